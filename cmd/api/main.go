@@ -5,14 +5,22 @@ package main
 import (
 	/* INTERNAL Packages */
 	"bookapi/internal/config"
+	"bookapi/internal/handlers"
+	"bookapi/internal/logger"
 	"bookapi/internal/router"
+	"bookapi/internal/tracing"
 	"os"
 
 	/* EXTERNAL Packages */
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	_ "net/http/pprof" /* 												>>>>>> PROFILER <<<<<<< */
-	"runtime"          /* 												>>>>>> PROFILER <<<<<<< */
+	"os/signal"
+	"runtime" /* 												>>>>>> PROFILER <<<<<<< */
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -58,6 +66,9 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	/* Set the level of the leveled logger (already validated by config.Load()) */
+	level, _ := logger.ParseLevel(cfg.LogLevel)
+	logger.SetLevel(level)
 
 	// 3. ALLOCATE PROFILER on a SEPARATE PORT 							>>>>>> PROFILER <<<<<<< */
 	go func() {
@@ -66,7 +77,7 @@ func main() {
 		/* Activate Tracking of waits for locks (mutexes) */
 		runtime.SetMutexProfileFraction(1)
 		/* Print Info Message in the Console Window */
-		log.Println("Starting pprof server on %s", cfg.ProfilerPort)
+		log.Printf("Starting pprof server on %s", cfg.ProfilerPort)
 		/* Allocate Server on Port + Error Handling */
 		err := http.ListenAndServe(cfg.ProfilerPort, nil)
 		if err != nil {
@@ -74,16 +85,47 @@ func main() {
 		}
 	}()
 
-	// 4. CREATE NEW HTTP ROUTER
+	// 4. SET UP TRACING (no-op without OTEL_EXPORTER_OTLP_ENDPOINT)				>>>>>> OPENTELEMETRY <<<<<<< */
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.OTLPEndpoint)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 5. CREATE NEW HTTP ROUTER
 	/* The method router.NewRouter(..) is defined in the router/ package and uses the value of cfg.DBURL to
 	   set up the connection to the PostgreSQL Database. */
-	r := router.NewRouter(cfg)
+	readiness := handlers.NewReadiness()
+	r, cleanup := router.NewRouter(cfg, readiness)
 	log.Printf("Starting server on %s", cfg.ServerPort)
 
-	// 5. ALLOCATE SERVER ON PORT + ERROR HANDLING
-	err = http.ListenAndServe(cfg.ServerPort, r)
-	if err != nil {
-		log.Fatal(err)
-	}
+	// 6. ALLOCATE SERVER ON PORT + ERROR HANDLING
+	/* The server runs in its own goroutine so that main can wait for SIGINT/SIGTERM and shut it down gracefully:
+	   first GET /health/ready answers 503 for the drain period (the load balancers stop sending new requests while
+	   the server keeps serving), then the in-flight requests get up to 10s to complete, THEN the prepared
+	   statements and DB pools get closed. */
+	server := &http.Server{Addr: cfg.ServerPort, Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
 
+	// 7. GRACEFUL SHUTDOWN
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop() /* A second signal kills the process right away (e.g. Ctrl+C twice) */
+	readiness.ShutdownStarted()
+	log.Printf("Shutting down server: draining for %v...", cfg.ShutdownDrainPeriod)
+	time.Sleep(cfg.ShutdownDrainPeriod)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Graceful shutdown failed: %v", err)
+	}
+	cleanup()
+	/* Flush the spans still buffered by the exporter */
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Tracing shutdown failed: %v", err)
+	}
 }
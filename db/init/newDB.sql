@@ -5,6 +5,10 @@ CREATE TABLE IF NOT EXISTS users (
     email TEXT UNIQUE NOT NULL,
     password TEXT NOT NULL
 );
+-- Suspension flag, false = suspended (no login, tokens rejected) - idempotent so that it also upgrades existing databases
+ALTER TABLE users ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT true;
+-- Emails are matched case-insensitively on login (accounts created before the normalization may have upper case)
+CREATE INDEX IF NOT EXISTS users_lower_email_idx ON users (lower(email));
 
 CREATE TABLE IF NOT EXISTS books (
     id SERIAL PRIMARY KEY,
@@ -13,3 +17,61 @@ CREATE TABLE IF NOT EXISTS books (
     pages INTEGER,
     owner_id INTEGER REFERENCES users(id)
 );
+
+-- Timestamps (idempotent so that it also upgrades existing databases)
+ALTER TABLE books ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE books ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+-- Delta sync (GET /books/changes) reads the books by updated_at
+CREATE INDEX IF NOT EXISTS books_updated_at_idx ON books (updated_at, id);
+
+-- Publication year (optional, NULL = unknown)
+ALTER TABLE books ADD COLUMN IF NOT EXISTS year INTEGER;
+
+-- Reviews (one per user per book, rating from 1 to 5)
+CREATE TABLE IF NOT EXISTS reviews (
+    id SERIAL PRIMARY KEY,
+    book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (book_id, user_id)
+);
+
+-- Favorites (the primary key prevents duplicates)
+CREATE TABLE IF NOT EXISTS favorites (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, book_id)
+);
+
+-- Audit log of the book changes and impersonations (no foreign keys: entries outlive the users and books they mention)
+CREATE TABLE IF NOT EXISTS audit_log (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    resource TEXT NOT NULL,
+    resource_id INTEGER,
+    impersonated_by INTEGER, -- admin acting as user_id via an impersonation token (NULL = the user themselves)
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS audit_log_user_id_created_at_idx ON audit_log (user_id, created_at DESC);
+
+-- Single-use invite codes required by POST /register (used_at IS NULL = still available)
+CREATE TABLE IF NOT EXISTS invites (
+    code TEXT PRIMARY KEY,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    used_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    used_at TIMESTAMPTZ
+);
+
+-- Versions of the schema applied to this database (GET /health/migrations compares the highest one with the latest
+-- known by the code: bump both whenever the schema changes)
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+INSERT INTO schema_migrations (version) VALUES (1) ON CONFLICT DO NOTHING;
@@ -0,0 +1,274 @@
+package breaker
+
+// breaker/ PACKAGE ***********************************************************************************************
+/* The breaker/ package provides a CIRCUIT BREAKER around the Database operations: when PostgreSQL is down, instead
+   of letting every request wait for its own query or connection attempt to time out (compounding the outage), the
+   breaker "opens" after a few consecutive failures and the requests get a fast 503 for a cooldown period. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. States
+	- CLOSED: normal operation, the consecutive failures are counted.
+	- OPEN: reached after DB_BREAKER_FAILURES consecutive failures. Every connection attempt fails immediately
+	  (and the DBCircuitBreaker middleware answers 503) for DB_BREAKER_COOLDOWN.
+	- HALF-OPEN: after the cooldown, at most DB_BREAKER_HALF_OPEN_REQUESTS operations get through to test the
+	  recovery: on success the breaker closes again, on failure it opens for another cooldown.
+	- Every state transition gets logged as WARN.
+   2. Where the Breaker Sits
+	- The breaker wraps the driver.Connector of the connection pool, so it covers ALL the repositories at once:
+	  both the connection attempts and the operations run on the pooled connections (queries, statements,
+	  transactions, pings) go through it.
+	- Only the failures telling that the Database is unreachable or unavailable get counted (see databaseDown):
+	  network errors, broken connections, timeouts and the PostgreSQL errors of the connection (08), resources
+	  (53), shutdown (57P01-57P03) and system (58) classes. Any other PostgreSQL error (e.g. a unique violation)
+	  means the Database has answered: it counts as a success.
+	- Operations aborted by the client (context.Canceled) are not the Database's fault: not counted.
+   3. Disabled Breaker
+	- A nil *Breaker (DB_BREAKER_FAILURES=0) is valid: it's never open and leaves the connector untouched.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"bookapi/internal/logger"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sony/gobreaker/v2"
+)
+
+// 2. GO STRUCTS and UTILITY METHODS ******************************************************************************
+
+/* Breaker - Go Struct */
+type Breaker struct {
+	cb       *gobreaker.CircuitBreaker[any]
+	cooldown time.Duration
+}
+
+/* Constructor - name in the logs, failures opening it (0 -> nil), cooldown while open, halfOpenRequests let through */
+func New(name string, failures int, cooldown time.Duration, halfOpenRequests int) *Breaker {
+	/* 1. No threshold -> no breaker (see IMPORTANT NOTES 3.) */
+	if failures <= 0 {
+		return nil
+	}
+	/* 2. Configure the underlying gobreaker (see IMPORTANT NOTES 1.) */
+	settings := gobreaker.Settings{
+		Name:        name,
+		MaxRequests: uint32(max(halfOpenRequests, 1)),
+		Timeout:     cooldown,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= uint32(failures)
+		},
+		IsSuccessful: func(err error) bool {
+			return !databaseDown(err)
+		},
+		IsExcluded: func(err error) bool {
+			return errors.Is(err, context.Canceled) || errors.Is(err, driver.ErrSkip)
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			logger.Warnf("DB circuit breaker %q: %s -> %s", name, from, to)
+		},
+	}
+	return &Breaker{cb: gobreaker.NewCircuitBreaker[any](settings), cooldown: cooldown}
+}
+
+/* Whether the breaker is open, i.e. the Database is considered down */
+func (b *Breaker) Open() bool {
+	return b != nil && b.cb.State() == gobreaker.StateOpen
+}
+
+/* How long the breaker stays open (suggested Retry-After of the 503 Responses) */
+func (b *Breaker) Cooldown() time.Duration {
+	if b == nil {
+		return 0
+	}
+	return b.cooldown
+}
+
+/* Run one Database operation through the breaker: fails immediately with gobreaker.ErrOpenState while open */
+func (b *Breaker) do(op func() error) error {
+	_, err := b.cb.Execute(func() (any, error) {
+		return nil, op()
+	})
+	return err
+}
+
+/* Whether the input error tells that the Database is unreachable or unavailable (see IMPORTANT NOTES 2.) */
+func databaseDown(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		code := string(pqErr.Code)
+		switch {
+		case strings.HasPrefix(code, "08"), strings.HasPrefix(code, "53"), strings.HasPrefix(code, "58"):
+			return true
+		case code == "57P01", code == "57P02", code == "57P03":
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// 3. DRIVER DECORATORS *******************************************************************************************
+
+/* Wrap the connector of a connection pool so that its connection attempts and operations go through the breaker */
+func (b *Breaker) Connector(c driver.Connector) driver.Connector {
+	if b == nil {
+		return c
+	}
+	return &connector{Connector: c, breaker: b}
+}
+
+/* driver.Connector decorator - Driver() comes from the embedded connector */
+type connector struct {
+	driver.Connector
+	breaker *Breaker
+}
+
+/* Open a new connection through the breaker */
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	var conn driver.Conn
+	err := c.breaker.do(func() (err error) {
+		conn, err = c.Connector.Connect(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &breakerConn{Conn: conn, breaker: c.breaker}, nil
+}
+
+/* driver.Conn decorator - every context-aware method goes through the breaker */
+type breakerConn struct {
+	driver.Conn
+	breaker *Breaker
+}
+
+func (c *breakerConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var prepared driver.Stmt
+	err := c.breaker.do(func() (err error) {
+		if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+			prepared, err = p.PrepareContext(ctx, query)
+		} else {
+			prepared, err = c.Conn.Prepare(query)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &breakerStmt{Stmt: prepared, breaker: c.breaker}, nil
+}
+
+func (c *breakerConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *breakerConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip /* database/sql falls back to PrepareContext */
+	}
+	var rows driver.Rows
+	err := c.breaker.do(func() (err error) {
+		rows, err = q.QueryContext(ctx, query, args)
+		return err
+	})
+	return rows, err
+}
+
+func (c *breakerConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip /* database/sql falls back to PrepareContext */
+	}
+	var result driver.Result
+	err := c.breaker.do(func() (err error) {
+		result, err = e.ExecContext(ctx, query, args)
+		return err
+	})
+	return result, err
+}
+
+func (c *breakerConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var tx driver.Tx
+	err := c.breaker.do(func() (err error) {
+		if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+			tx, err = b.BeginTx(ctx, opts)
+		} else {
+			tx, err = c.Conn.Begin()
+		}
+		return err
+	})
+	return tx, err
+}
+
+func (c *breakerConn) Ping(ctx context.Context) error {
+	p, ok := c.Conn.(driver.Pinger)
+	if !ok {
+		return nil
+	}
+	return c.breaker.do(func() error {
+		return p.Ping(ctx)
+	})
+}
+
+func (c *breakerConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *breakerConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+/* driver.Stmt decorator - the prepared statements go through the breaker too */
+type breakerStmt struct {
+	driver.Stmt
+	breaker *Breaker
+}
+
+func (s *breakerStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	var rows driver.Rows
+	err := s.breaker.do(func() (err error) {
+		if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+			rows, err = q.QueryContext(ctx, args)
+		} else {
+			rows, err = s.Stmt.Query(values(args))
+		}
+		return err
+	})
+	return rows, err
+}
+
+func (s *breakerStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var result driver.Result
+	err := s.breaker.do(func() (err error) {
+		if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+			result, err = e.ExecContext(ctx, args)
+		} else {
+			result, err = s.Stmt.Exec(values(args))
+		}
+		return err
+	})
+	return result, err
+}
+
+/* Positional values of the input arguments (for the drivers without the context-aware methods) */
+func values(args []driver.NamedValue) []driver.Value {
+	result := make([]driver.Value, len(args))
+	for i, arg := range args {
+		result[i] = arg.Value
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package breaker
+
+// breaker/ PACKAGE TESTS *****************************************************************************************
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sony/gobreaker/v2"
+)
+
+// 2. TEST DOUBLES ************************************************************************************************
+
+/* Fake connector answering every query with err (nil -> success), counting the queries reaching the "Database" */
+type fakeConnector struct {
+	err     error
+	queries int
+}
+type fakeConn struct{ connector *fakeConnector }
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{c}, nil }
+func (c *fakeConnector) Driver() driver.Driver                        { return nil }
+func (fakeConn) Prepare(string) (driver.Stmt, error)                  { return nil, driver.ErrSkip }
+func (fakeConn) Close() error                                         { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                            { return nil, driver.ErrSkip }
+
+func (c fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	c.connector.queries++
+	return nil, c.connector.err
+}
+
+// 3. TESTS *******************************************************************************************************
+
+/* TESTER for the states of the breaker: closed -> open -> half-open -> open/closed, driven by query failures ------*/
+func TestBreakerStates(t *testing.T) {
+	cooldown := 20 * time.Millisecond
+	b := New("test", 2, cooldown, 1)
+	fake := &fakeConnector{}
+	conn, err := b.Connector(fake).Connect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := func() error {
+		_, err := conn.(driver.QueryerContext).QueryContext(context.Background(), "SELECT 1", nil)
+		return err
+	}
+
+	/* 1. CLOSED: the errors of a Database that answers (e.g. unique violations) never open the breaker */
+	fake.err = &pq.Error{Code: "23505"}
+	for range 5 {
+		query()
+	}
+	if b.cb.State() != gobreaker.StateClosed {
+		t.Fatalf("Expected closed after PostgreSQL errors, got %s", b.cb.State())
+	}
+
+	/* 2. OPEN: two consecutive broken connections open it, then the queries fail without reaching the Database */
+	fake.err = io.ErrUnexpectedEOF
+	query()
+	query()
+	if !b.Open() {
+		t.Fatalf("Expected open after 2 failures, got %s", b.cb.State())
+	}
+	reached := fake.queries
+	if err := query(); !errors.Is(err, gobreaker.ErrOpenState) || fake.queries != reached {
+		t.Fatalf("Expected a fast failure while open, got %v", err)
+	}
+
+	/* 3. HALF-OPEN -> OPEN: after the cooldown, a failing test query opens it again */
+	time.Sleep(cooldown + 5*time.Millisecond)
+	if b.cb.State() != gobreaker.StateHalfOpen {
+		t.Fatalf("Expected half-open after the cooldown, got %s", b.cb.State())
+	}
+	query()
+	if !b.Open() {
+		t.Fatalf("Expected open after a failed test query, got %s", b.cb.State())
+	}
+
+	/* 4. HALF-OPEN -> CLOSED: after another cooldown, a successful test query closes it */
+	time.Sleep(cooldown + 5*time.Millisecond)
+	fake.err = nil
+	if err := query(); err != nil {
+		t.Fatalf("Expected the test query to go through, got %v", err)
+	}
+	if b.cb.State() != gobreaker.StateClosed {
+		t.Errorf("Expected closed after a successful test query, got %s", b.cb.State())
+	}
+}
+
+/* TESTER for the failures that count: only the ones telling that the Database is down --------------------------*/
+func TestDatabaseDown(t *testing.T) {
+	cases := map[error]bool{
+		nil:                            false,
+		driver.ErrBadConn:              true,
+		context.DeadlineExceeded:       true,
+		&pq.Error{Code: "08006"}:       true,  /* connection_failure */
+		&pq.Error{Code: "53300"}:       true,  /* too_many_connections */
+		&pq.Error{Code: "57P01"}:       true,  /* admin_shutdown */
+		&pq.Error{Code: "57014"}:       false, /* query_canceled */
+		&pq.Error{Code: "23503"}:       false, /* foreign_key_violation */
+		errors.New("connection reset"): true,
+	}
+	for err, expected := range cases {
+		if got := databaseDown(err); got != expected {
+			t.Errorf("%v: Expected %v, got %v", err, expected, got)
+		}
+	}
+}
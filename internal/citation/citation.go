@@ -0,0 +1,114 @@
+package citation
+
+// citation/ PACKAGE **********************************************************************************************
+/* The citation/ package is used to build formatted bibliographic citations (APA, MLA, Chicago) of a book starting
+   from its author, title and publication year. It doesn't know anything about HTTP or the Database. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Author Names
+- Authors are stored as one single string (e.g. "Gaius Julius Caesar"): the LAST word is used as family name and
+  all the previous words as given names (-> "Caesar, Gaius Julius"). One-word names are kept as they are.
+   2. Unknown Year
+- A year equal to 0 means unknown: APA and Chicago print "n.d." (no date), MLA simply omits it.
+   3. Plain Text
+- Citations are plain text: titles are NOT italicized since the output has no formatting.
+*/
+
+// 1. IMPORT PACKAGES *******************************************************************************************
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 2. UTILITY VARIABLES and METHODS *****************************************************************************
+
+/* Supported citation styles */
+const (
+	StyleAPA     = "apa"
+	StyleMLA     = "mla"
+	StyleChicago = "chicago"
+)
+
+/* Returned by Format when the requested style is not supported */
+var ErrUnknownStyle = errors.New("Unknown citation style. Supported styles: apa, mla, chicago.")
+
+/* Split the author into family name and given names (see IMPORTANT NOTES 1.) */
+func splitName(author string) (family string, given []string) {
+	parts := strings.Fields(author)
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return parts[len(parts)-1], parts[:len(parts)-1]
+}
+
+/* Format a year, returning "n.d." when unknown */
+func yearOrNoDate(year int) string {
+	if year == 0 {
+		return "n.d."
+	}
+	return fmt.Sprint(year)
+}
+
+/* "Caesar, Gaius Julius" - inverted full name used by MLA and Chicago */
+func invertedName(author string) string {
+	family, given := splitName(author)
+	if len(given) == 0 {
+		return family
+	}
+	return family + ", " + strings.Join(given, " ")
+}
+
+/* Append a final period unless the text already ends with a punctuation mark */
+func withPeriod(text string) string {
+	if strings.HasSuffix(text, ".") || strings.HasSuffix(text, "?") || strings.HasSuffix(text, "!") {
+		return text
+	}
+	return text + "."
+}
+
+// 3. CITATION STYLES *******************************************************************************************
+
+/* APA (7th ed.) - Caesar, G. J. (1869). De Bello Gallico. */
+func APA(author, title string, year int) string {
+	/* 1. Build the family name followed by the initials of the given names */
+	family, given := splitName(author)
+	name := family
+	if len(given) > 0 {
+		initials := make([]string, len(given))
+		for i, g := range given {
+			initials[i] = string([]rune(g)[0]) + "."
+		}
+		name += ", " + strings.Join(initials, " ")
+	}
+	/* 2. Put name, year and title together */
+	return fmt.Sprintf("%s (%s). %s", name, yearOrNoDate(year), withPeriod(title))
+}
+
+/* MLA (9th ed.) - Caesar, Gaius Julius. De Bello Gallico. 1869. */
+func MLA(author, title string, year int) string {
+	citation := fmt.Sprintf("%s %s", withPeriod(invertedName(author)), withPeriod(title))
+	if year != 0 {
+		citation += fmt.Sprintf(" %d.", year)
+	}
+	return citation
+}
+
+/* Chicago (17th ed., bibliography) - Caesar, Gaius Julius. De Bello Gallico. 1869. */
+func Chicago(author, title string, year int) string {
+	return fmt.Sprintf("%s %s %s", withPeriod(invertedName(author)), withPeriod(title), withPeriod(yearOrNoDate(year)))
+}
+
+/* Format the citation in the input style (case insensitive, empty -> APA) or return ErrUnknownStyle */
+func Format(style, author, title string, year int) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(style)) {
+	case "", StyleAPA:
+		return APA(author, title, year), nil
+	case StyleMLA:
+		return MLA(author, title, year), nil
+	case StyleChicago:
+		return Chicago(author, title, year), nil
+	default:
+		return "", ErrUnknownStyle
+	}
+}
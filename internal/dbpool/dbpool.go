@@ -0,0 +1,313 @@
+package dbpool
+
+// dbpool/ PACKAGE ************************************************************************************************
+/* The dbpool/ package bounds how long an HTTP Request can wait for a pooled Database connection (DB_ACQUIRE_TIMEOUT):
+   when the pool is exhausted, the request gets a fast 503 instead of hanging until REQUEST_TIMEOUT. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Why the Driver
+	- database/sql has no acquisition timeout: a query waits for a free connection until its context is done, and
+	  a deadline on that context would also cut the query itself once it got its connection.
+	- So the connector of the pool gets wrapped (see Connector): every connection reports to the Guard of the
+	  request (carried by the context of the query) when it starts being used and when it's given back.
+   2. The Guard
+	- It watches how long its request goes WITHOUT holding any connection. When that exceeds the timeout while the
+	  pool is full (i.e. the request is waiting for a connection, or would be as soon as it asks for one), it
+	  cancels the context of the request with ErrAcquireTimeout: the pending acquisition fails straight away.
+	- A connection is held while a statement runs, while its rows are open and for the whole of a transaction:
+	  slow queries are never cut by the Guard, only by the request deadline.
+	- Queries without a Guard in their context (e.g. the startup checks) are not bounded.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"time"
+)
+
+// 2. GUARD *******************************************************************************************************
+
+/* Cause of the cancellation of the requests that waited too long for a connection */
+var ErrAcquireTimeout = errors.New("timed out waiting for a pooled DB connection")
+
+/* Key of the Guard in the context of the request */
+type guardKey struct{}
+
+/* Guard - Go Struct */
+/* Acquisition timeout of one request (see IMPORTANT NOTES 2.) */
+type Guard struct {
+	pools    []*sql.DB
+	timeout  time.Duration
+	cancel   context.CancelCauseFunc
+	lock     sync.Mutex
+	timer    *time.Timer
+	holding  int  /* Connections currently held by the request */
+	stopped  bool /* The request is over */
+	timedOut bool /* The request has been cancelled with ErrAcquireTimeout */
+}
+
+/*
+Constructor - returns a child of the input context carrying a new Guard, which starts counting straight away.
+The pools are the ones the request may wait for (the primary and, if any, the read replica). Stop() must be called
+when the request is over.
+*/
+func WithGuard(ctx context.Context, timeout time.Duration, pools ...*sql.DB) (context.Context, *Guard) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	g := &Guard{pools: pools, timeout: timeout, cancel: cancel}
+	g.timer = time.AfterFunc(timeout, g.expire)
+	return context.WithValue(ctx, guardKey{}, g), g
+}
+
+/* Stop counting and release the context of the request */
+func (g *Guard) Stop() {
+	g.lock.Lock()
+	g.stopped = true
+	g.timer.Stop()
+	g.lock.Unlock()
+	g.cancel(nil)
+}
+
+/* Whether the request has been cancelled for waiting too long for a connection */
+func (g *Guard) TimedOut() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.timedOut
+}
+
+/* A connection has been handed to the request: nothing to count while it holds one */
+func (g *Guard) acquire() {
+	if g == nil {
+		return
+	}
+	g.lock.Lock()
+	g.holding++
+	g.timer.Stop()
+	g.lock.Unlock()
+}
+
+/* A connection has been given back: start counting again once the request holds none */
+func (g *Guard) release() {
+	if g == nil {
+		return
+	}
+	g.lock.Lock()
+	g.holding--
+	if g.holding == 0 && !g.stopped {
+		g.timer.Reset(g.timeout)
+	}
+	g.lock.Unlock()
+}
+
+/*
+The request went without a connection for the whole timeout: cancel it if a pool is full, otherwise it isn't waiting
+for one, so count again.
+*/
+func (g *Guard) expire() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.holding > 0 || g.stopped {
+		return
+	}
+	for _, pool := range g.pools {
+		if stats := pool.Stats(); stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+			g.timedOut = true
+			g.cancel(ErrAcquireTimeout)
+			return
+		}
+	}
+	g.timer.Reset(g.timeout)
+}
+
+/* Guard of the request the input context belongs to (nil if none) */
+func guardFrom(ctx context.Context) *Guard {
+	g, _ := ctx.Value(guardKey{}).(*Guard)
+	return g
+}
+
+// 3. DRIVER DECORATORS *******************************************************************************************
+
+/* Wrap the connector of a connection pool so that its connections report to the Guards (see IMPORTANT NOTES 1.) */
+func Connector(c driver.Connector) driver.Connector {
+	return &connector{Connector: c}
+}
+
+/* driver.Connector decorator - Driver() comes from the embedded connector */
+type connector struct {
+	driver.Connector
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledConn{Conn: conn}, nil
+}
+
+/* driver.Conn decorator - every context-aware method holds the connection for the Guard of its context */
+type pooledConn struct {
+	driver.Conn
+}
+
+func (c *pooledConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	g := guardFrom(ctx)
+	g.acquire()
+	defer g.release()
+	var prepared driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		prepared, err = p.PrepareContext(ctx, query)
+	} else {
+		prepared, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pooledStmt{Stmt: prepared}, nil
+}
+
+func (c *pooledConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *pooledConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip /* database/sql falls back to PrepareContext */
+	}
+	g := guardFrom(ctx)
+	g.acquire()
+	result, err := q.QueryContext(ctx, query, args)
+	if err != nil {
+		g.release()
+		return nil, err
+	}
+	return &pooledRows{Rows: result, guard: g}, nil
+}
+
+func (c *pooledConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip /* database/sql falls back to PrepareContext */
+	}
+	g := guardFrom(ctx)
+	g.acquire()
+	defer g.release()
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *pooledConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	g := guardFrom(ctx)
+	g.acquire()
+	var t driver.Tx
+	var err error
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		t, err = b.BeginTx(ctx, opts)
+	} else {
+		t, err = c.Conn.Begin()
+	}
+	if err != nil {
+		g.release()
+		return nil, err
+	}
+	return &pooledTx{Tx: t, guard: g}, nil
+}
+
+func (c *pooledConn) Ping(ctx context.Context) error {
+	p, ok := c.Conn.(driver.Pinger)
+	if !ok {
+		return nil
+	}
+	g := guardFrom(ctx)
+	g.acquire()
+	defer g.release()
+	return p.Ping(ctx)
+}
+
+func (c *pooledConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *pooledConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+/* driver.Stmt decorator - the prepared statements run on a connection too */
+type pooledStmt struct {
+	driver.Stmt
+}
+
+func (s *pooledStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	g := guardFrom(ctx)
+	g.acquire()
+	var result driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		result, err = q.QueryContext(ctx, args)
+	} else {
+		result, err = s.Stmt.Query(values(args))
+	}
+	if err != nil {
+		g.release()
+		return nil, err
+	}
+	return &pooledRows{Rows: result, guard: g}, nil
+}
+
+func (s *pooledStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	g := guardFrom(ctx)
+	g.acquire()
+	defer g.release()
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+	return s.Stmt.Exec(values(args))
+}
+
+/* driver.Rows decorator - the connection is held until the rows get closed */
+type pooledRows struct {
+	driver.Rows
+	guard *Guard
+	once  sync.Once
+}
+
+func (r *pooledRows) Close() error {
+	r.once.Do(r.guard.release)
+	return r.Rows.Close()
+}
+
+/* driver.Tx decorator - the connection is held until the end of the transaction */
+type pooledTx struct {
+	driver.Tx
+	guard *Guard
+	once  sync.Once
+}
+
+func (t *pooledTx) Commit() error {
+	defer t.once.Do(t.guard.release)
+	return t.Tx.Commit()
+}
+
+func (t *pooledTx) Rollback() error {
+	defer t.once.Do(t.guard.release)
+	return t.Tx.Rollback()
+}
+
+/* Positional values of the input arguments (for the drivers without the context-aware methods) */
+func values(args []driver.NamedValue) []driver.Value {
+	result := make([]driver.Value, len(args))
+	for i, arg := range args {
+		result[i] = arg.Value
+	}
+	return result
+}
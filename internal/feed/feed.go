@@ -0,0 +1,184 @@
+package feed
+
+// feed/ PACKAGE **************************************************************************************************
+/* The feed/ package renders a list of books as an Atom (RFC 4287) or RSS 2.0 syndication feed, so that new books
+   can be followed from any feed reader. It doesn't know anything about HTTP or the Database. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Entries
+	- Every book is one entry/item: title, author, link to GET /books/{id} and dates. Atom uses updated_at as the
+	  <updated> date (and created_at as <published>), RSS uses created_at as <pubDate> (when it was published).
+   2. Feed Date
+	- The date of the whole feed is the latest updated_at among its books (now if there are none), since Atom
+	  requires it and feed readers use it to skip unchanged feeds.
+   3. Identifiers
+	- The link of a book (baseURL + "/books/{id}") doubles as its permanent identifier (Atom <id>, RSS <guid>).
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"bookapi/internal/models"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// 2. GO STRUCTS and UTILITY VARIABLES ****************************************************************************
+
+/* Title and description shared by both formats */
+const (
+	feedTitle       = "Book API - Latest books"
+	feedDescription = "The most recently added books"
+)
+
+/* Content-Type of the two formats */
+const (
+	AtomContentType = "application/atom+xml; charset=utf-8"
+	RSSContentType  = "application/rss+xml; charset=utf-8"
+)
+
+/* Atom (RFC 4287) - Go Structs */
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Link      atomLink   `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Author    atomPerson `xml:"author"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+/* RSS 2.0 - Go Structs (the author goes into <dc:creator>: the RSS <author> must be an e-mail address) */
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	Creator     string  `xml:"dc:creator"`
+	Description string  `xml:"description"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// 3. UTILITY METHODS *********************************************************************************************
+
+/* Date of the whole feed (see IMPORTANT NOTES 2.) */
+func feedUpdated(books []models.Book) time.Time {
+	if len(books) == 0 {
+		return time.Now().UTC()
+	}
+	latest := books[0].UpdatedAt.Time
+	for _, b := range books[1:] {
+		if b.UpdatedAt.After(latest) {
+			latest = b.UpdatedAt.Time
+		}
+	}
+	return latest.UTC()
+}
+
+/* Link (and identifier) of a book (see IMPORTANT NOTES 3.) */
+func bookURL(baseURL string, id int) string {
+	return fmt.Sprintf("%s/books/%d", baseURL, id)
+}
+
+/* Encode the input feed as indented XML preceded by the XML declaration */
+func encode(feed any) ([]byte, error) {
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// 4. FEED FORMATS ************************************************************************************************
+
+/* Atom feed of the input books - baseURL is scheme://host of the API, selfURL the URL of the feed itself */
+func Atom(baseURL, selfURL string, books []models.Book) ([]byte, error) {
+	/* 1. Build the feed metadata */
+	feed := atomFeed{
+		Title:   feedTitle,
+		ID:      selfURL,
+		Updated: feedUpdated(books).Format(time.RFC3339),
+		Links:   []atomLink{{Rel: "self", Href: selfURL}, {Rel: "alternate", Href: baseURL + "/books"}},
+		Entries: make([]atomEntry, 0, len(books)),
+	}
+	/* 2. Add one entry per book */
+	for _, b := range books {
+		link := bookURL(baseURL, b.ID)
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:     b.Title,
+			ID:        link,
+			Link:      atomLink{Rel: "alternate", Href: link},
+			Published: b.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   b.UpdatedAt.UTC().Format(time.RFC3339),
+			Author:    atomPerson{Name: b.Author},
+		})
+	}
+	/* 3. Encode it */
+	return encode(feed)
+}
+
+/* RSS 2.0 feed of the input books - baseURL is scheme://host of the API */
+func RSS(baseURL string, books []models.Book) ([]byte, error) {
+	/* 1. Build the channel metadata */
+	feed := rssFeed{
+		Version: "2.0",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         feedTitle,
+			Link:          baseURL + "/books",
+			Description:   feedDescription,
+			LastBuildDate: feedUpdated(books).Format(time.RFC1123Z),
+			Items:         make([]rssItem, 0, len(books)),
+		},
+	}
+	/* 2. Add one item per book */
+	for _, b := range books {
+		link := bookURL(baseURL, b.ID)
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       b.Title,
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: true, Value: link},
+			Creator:     b.Author,
+			Description: fmt.Sprintf("%s by %s, %d pages.", b.Title, b.Author, b.Pages),
+			PubDate:     b.CreatedAt.UTC().Format(time.RFC1123Z),
+		})
+	}
+	/* 3. Encode it */
+	return encode(feed)
+}
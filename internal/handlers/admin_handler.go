@@ -8,6 +8,21 @@ package handlers
 /* 1. Scope of admin_handler.go
 - This go file contain the method GetUsers() that wraps around the services/ method FindAll() that wraps
 around the repositories/ method FindAll() talking directly to the Database.
+   2. Streamed Audit Export
+- GET /admin/audit/export writes the CSV rows to the ResponseWriter as they are read from the Database (flushing
+  every auditFlushRows rows), so that large logs are never buffered in memory. The route is exempted from the
+  buffering of the ResponseTimeout middleware, while REQUEST_TIMEOUT still bounds the whole export.
+- The status and the CSV header only get sent with the first entry: until then a failure can still be answered
+  with a JSON error. A failure after that can only cut the CSV short (and gets logged).
+   3. Impersonation
+- POST /admin/users/{id}/impersonate gives the admin a short-lived token (IMPERSONATION_TTL) for a non-admin user,
+  carrying the admin's ID as "impersonated_by". The issuing is recorded in the audit log (and logged) BEFORE the
+  token is returned - if the entry can't be recorded, the token is NOT returned (500) - and every change made with
+  the token gets audited with the admin's ID too.
+   4. Trusted Origins
+- When TRUSTED_ORIGINS is set, every /admin request must come from one of those origins (Origin header, or the
+  Referer as a fallback): the others get a 403 from middleware.OriginGuard, on top of the role check. Unlike CORS,
+  the request never reaches the handler. Requests without Origin/Referer (e.g. curl) then need to send one.
 */
 
 // 1. IMPORT PACKAGES *********************************************************************************************
@@ -15,14 +30,27 @@ around the repositories/ method FindAll() talking directly to the Database.
 /* Besides the external packages, we also need to import the necessary internal packages defined in the project */
 import (
 	/* INTERNAL Packages */
+	"bookapi/internal/config"
+	"bookapi/internal/logger"
 	"bookapi/internal/middleware"
+	"bookapi/internal/models"
+	"bookapi/internal/security"
 	"bookapi/internal/services"
 	"bookapi/internal/utils"
 	"fmt"
 
 	/* EXTERNAL Packages */
+	"database/sql"
 
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -32,20 +60,56 @@ import (
 /* STRUCT */
 /* Holds a reference to UserService, which contains the logic for registering users. */
 type AdminHandler struct {
-	Service *services.UserService
+	Service     *services.UserService
+	Audit       *services.AuditService      /* Audit log exported by GET /admin/audit/export */
+	Maintenance *middleware.MaintenanceMode /* Runtime maintenance flag shared with the maintenance middleware */
+	Transfers   *middleware.Semaphore       /* Cap on the concurrent transfers (read by GET /admin/db-stats) */
+	DB          *sql.DB                     /* Connection Pool (only read by GET /admin/db-stats) */
+	Config      config.Config               /* Effective configuration (exposed redacted by GET /admin/config) */
+	Routes      chi.Routes                  /* Root router, set once all the routes are registered (GET /admin/routes) */
 }
 
 /* STRUCT BUILDER */
 /* Creates and returns a new UserHandler instance */
-func NewAdminHandler(service *services.UserService) *AdminHandler {
-	return &AdminHandler{Service: service}
+func NewAdminHandler(service *services.UserService, audit *services.AuditService,
+	maintenance *middleware.MaintenanceMode, transfers *middleware.Semaphore, db *sql.DB,
+	cfg config.Config) *AdminHandler {
+	return &AdminHandler{Service: service, Audit: audit, Maintenance: maintenance, Transfers: transfers, DB: db,
+		Config: cfg}
 }
 
+/* Columns of the CSV written by GET /admin/audit/export */
+var auditCSVHeader = []string{"timestamp", "user_id", "action", "resource", "resource_id", "impersonated_by"}
+
+/* Number of CSV rows written between two flushes to the client */
+const auditFlushRows = 500
+
 /* Register All Routes */
 func (h *AdminHandler) RegisterRoutes(r chi.Router) {
+	/* One semaphore shared by all the (CPU/DB heavy) stats endpoints */
+	statsLimit := middleware.ConcurrencyLimit(h.Config.StatsConcurrency)
+	/* Same role list as GET /me/permissions (can_admin) */
+	adminOnly := middleware.AllowRoles(models.AdminRoles...)
 	r.Route("/admin", func(r chi.Router) {
-		r.With(middleware.AllowRoles("admin")).Get("/users", h.GetUsers)     /*		>>>>>> ROLE-BASED AUTH <<<<<<*/
-		r.With(middleware.AllowRoles("admin")).Get("/profile", h.GetProfile) /*		>>>>>> ROLE-BASED AUTH <<<<<<*/
+		/* Server-side check of the origin of the requests, if configured (see IMPORTANT NOTES 4.) */
+		if h.Config.TrustedOrigins != "" {
+			r.Use(middleware.OriginGuard(strings.Split(h.Config.TrustedOrigins, ",")...))
+		}
+		r.With(adminOnly).Get("/users", h.GetUsers)                                   /*		>>>>>> ROLE-BASED AUTH <<<<<<*/
+		r.With(adminOnly).Post("/users/import", h.ImportUsers)                        /* >> ROLE-BASED AUTH <<*/
+		r.With(adminOnly).Post("/users/roles", h.AssignRole)                          /* >> ROLE-BASED AUTH <<*/
+		r.With(adminOnly).Post("/users/{id}/suspend", h.SuspendUser)                  /* >> ROLE-BASED AUTH <<*/
+		r.With(adminOnly).Post("/users/{id}/unsuspend", h.UnsuspendUser)              /* >> ROLE-BASED AUTH <<*/
+		r.With(adminOnly).Post("/users/{id}/impersonate", h.ImpersonateUser)          /* >> ROLE-BASED AUTH <<*/
+		r.With(adminOnly).Post("/invites", h.CreateInvites)                           /* >> ROLE-BASED AUTH <<*/
+		r.With(adminOnly).Get("/profile", h.GetProfile)                               /*		>>>>>> ROLE-BASED AUTH <<<<<<*/
+		r.With(adminOnly, statsLimit).Get("/stats/books-per-user", h.GetBooksPerUser) /* >> ROLE-BASED AUTH <<*/
+		r.With(adminOnly).Get("/maintenance", h.GetMaintenance)                       /* >> ROLE-BASED AUTH <<*/
+		r.With(adminOnly).Post("/maintenance", h.SetMaintenance)                      /* >> ROLE-BASED AUTH <<*/
+		r.With(adminOnly).Get("/config", h.GetConfig)                                 /* >> ROLE-BASED AUTH <<*/
+		r.With(adminOnly).Get("/db-stats", h.GetDBStats)                              /* >> ROLE-BASED AUTH <<*/
+		r.With(adminOnly).Get("/routes", h.GetRoutes)                                 /* >> ROLE-BASED AUTH <<*/
+		r.With(adminOnly).Get("/audit/export", h.ExportAudit)                         /* >> ROLE-BASED AUTH <<*/
 	})
 
 }
@@ -56,7 +120,7 @@ func (h *AdminHandler) RegisterRoutes(r chi.Router) {
 
 /* GET /users Handler */
 func (h *AdminHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.Service.FindAll()
+	users, err := h.Service.FindAll(r.Context())
 	if err != nil {
 		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Books.")
 		return
@@ -64,9 +128,362 @@ func (h *AdminHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, users, nil)
 }
 
+/* POST /users/import Handler */
+/* Body: CSV file (text/csv, or multipart/form-data with a "file" field) with one "email,password" row per user.
+   A first row starting with "email" is treated as header and skipped. */
+func (h *AdminHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	/* 1. Limit the size of the upload and get the CSV reader from the Body (or from the uploaded file) */
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			utils.WriteSafeError(w, http.StatusBadRequest, `Missing CSV file in the "file" form field`)
+			return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+		}
+		defer file.Close()
+		body = file
+	}
+	/* 2. Parse the CSV rows + Error Handling */
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, "Invalid CSV: "+err.Error())
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if len(records) > 0 && len(records[0]) > 0 && strings.EqualFold(strings.TrimSpace(records[0][0]), "email") {
+		records = records[1:]
+	}
+	if len(records) == 0 {
+		utils.WriteSafeError(w, http.StatusBadRequest, "The CSV file contains no users")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if rejectLargeBatch(w, r, len(records), h.Config.MaxBatchItems) {
+		return
+	}
+	/* 3. Convert each row into a Register Request (missing fields are reported as failed rows) */
+	reqs := make([]models.RegisterRequest, len(records))
+	for i, record := range records {
+		if len(record) > 0 {
+			reqs[i].Email = record[0]
+		}
+		if len(record) > 1 {
+			reqs[i].Password = record[1]
+		}
+	}
+	/* 4. Register all the users in one transaction + Error Handling */
+	results, err := h.Service.ImportUsers(r.Context(), reqs)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Import Users.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 5. Return the per-row results */
+	utils.WriteJSON(w, http.StatusOK, results, nil)
+}
+
+/* POST /users/roles Handler */
+/* Body: {"ids": [2, 3], "role": "admin"} - sets the role of all the listed users at once */
+func (h *AdminHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	/* 1. Decode the Body + Error Handling */
+	var req models.RoleAssignmentRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, `Invalid JSON: expected {"ids": [...], "role": "..."}`)
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Update the roles via services/ method + Error Handling */
+	updated, err := h.Service.AssignRole(r.Context(), req.IDs, req.Role)
+	var invalid services.ValidationError
+	if errors.As(err, &invalid) {
+		utils.WriteValidationError(w, http.StatusBadRequest, "Missing/Invalid JSON Field values.", invalid)
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Update Roles.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Return how many users have been updated */
+	utils.WriteJSON(w, http.StatusOK, models.RoleAssignmentResult{Updated: updated}, nil)
+}
+
+/* POST /users/{id}/suspend Handler */
+/* Suspends the user: no more logins, and their existing tokens get rejected (see services/ IMPORTANT NOTES 5.) */
+func (h *AdminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, false)
+}
+
+/* POST /users/{id}/unsuspend Handler */
+func (h *AdminHandler) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, true)
+}
+
+/* Shared body of SuspendUser and UnsuspendUser */
+func (h *AdminHandler) setActive(w http.ResponseWriter, r *http.Request, active bool) {
+	/* 1. Convert the id from the URL + Error Handling */
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, idErrorMessage(err))
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. An admin can't lock themselves out */
+	if callerID, _ := r.Context().Value(middleware.UserIDKey).(int); !active && callerID == id {
+		utils.WriteSafeError(w, http.StatusBadRequest, "You cannot suspend your own account.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Update the flag via services/ method + Error Handling */
+	err = h.Service.SetActive(r.Context(), id, active)
+	if errors.Is(err, services.ErrUserNotFound) {
+		utils.WriteSafeError(w, http.StatusNotFound, "User Not Found.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Update the User.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 4. Return the new state of the user */
+	utils.WriteJSON(w, http.StatusOK, models.UserActiveState{ID: id, Active: active}, nil)
+}
+
+/* POST /users/{id}/impersonate Handler */
+/* A short-lived token letting the calling admin act as the user (see IMPORTANT NOTES 3.) */
+func (h *AdminHandler) ImpersonateUser(w http.ResponseWriter, r *http.Request) {
+	/* 1. Convert the id from the URL + Error Handling */
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, idErrorMessage(err))
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. No chains: a token that is already impersonating somebody can't impersonate anybody else */
+	adminID, _ := r.Context().Value(middleware.UserIDKey).(int)
+	if _, impersonating := r.Context().Value(middleware.ImpersonatorKey).(int); impersonating {
+		utils.WriteSafeError(w, http.StatusForbidden, "Impersonation tokens cannot impersonate.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Get the user to act as via services/ method + Error Handling */
+	user, err := h.Service.ImpersonationTarget(r.Context(), id)
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		utils.WriteSafeError(w, http.StatusNotFound, "User Not Found.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	case errors.Is(err, services.ErrImpersonateAdmin), errors.Is(err, services.ErrAccountSuspended):
+		utils.WriteSafeError(w, http.StatusForbidden, err.Error())
+		return
+	case err != nil:
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch the User.")
+		return
+	}
+	/* 4. Generate the short-lived Token carrying the admin's ID + Error Handling */
+	token, expiresAt, err := security.GenerateImpersonationToken(user.ID, user.Role, adminID, h.Config.ImpersonationTTL,
+		h.Config.JWTSecret, h.Config.JWTIssuer, h.Config.JWTAudience)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Failed to generate token.")
+		return
+	}
+	/* 5. Audit (and log) the impersonation BEFORE handing the Token out: no audit entry, no Token */
+	if err := h.Audit.Record(r.Context(), models.AuditEntry{UserID: adminID, Action: models.AuditImpersonate,
+		Resource: "user", ResourceID: user.ID}); err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Audit the Impersonation.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	logger.Infof("admin %d impersonating user %d until %s", adminID, user.ID, expiresAt.UTC().Format(time.RFC3339))
+	utils.WriteJSON(w, http.StatusOK, models.ImpersonationToken{Token: token, UserID: user.ID, ImpersonatedBy: adminID,
+		ExpiresAt: models.NewAPITime(expiresAt)}, nil)
+}
+
+/* POST /invites Handler */
+/* Body (optional): {"count": n} - generates n single-use invite codes for POST /register (1 by default) */
+func (h *AdminHandler) CreateInvites(w http.ResponseWriter, r *http.Request) {
+	/* 1. Decode the optional Body + Error Handling */
+	req := models.InviteRequest{Count: 1}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.WriteSafeError(w, http.StatusBadRequest, `Invalid JSON: expected {"count": n}`)
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Generate and store the codes + Error Handling */
+	userID, _ := r.Context().Value(middleware.UserIDKey).(int)
+	invites, err := h.Service.CreateInvites(r.Context(), userID, req.Count)
+	var invalid services.ValidationError
+	if errors.As(err, &invalid) {
+		utils.WriteValidationError(w, http.StatusBadRequest, "Missing/Invalid JSON Field values.", invalid)
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Create Invites.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Return HTTP Response with 201 Status Code and the new codes */
+	utils.WriteJSON(w, http.StatusCreated, invites, nil)
+}
+
 /* GET /profile Handler */
 func (h *AdminHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(middleware.UserIDKey).(int)
 	w.Header().Set("Content-Type", "text/plain")
 	fmt.Fprintf(w, "Welcome user %d", userID)
 }
+
+/* GET /stats/books-per-user Handler */
+/* Optional query parameter: limit (number of users to return, 0 or missing -> all) */
+func (h *AdminHandler) GetBooksPerUser(w http.ResponseWriter, r *http.Request) {
+	/* 1. Parse the optional limit query parameter + Error Handling */
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			utils.WriteSafeError(w, http.StatusBadRequest, "limit must be a non-negative integer")
+			return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+		}
+		limit = parsed
+	}
+	/* 2. Get the book counts sorted by count (descending) + Error Handling */
+	counts, err := h.Service.BooksPerUser(r.Context(), limit)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Stats.")
+		return
+	}
+	/* 3. Return the counts */
+	utils.WriteJSON(w, http.StatusOK, counts, nil)
+}
+
+/* GET /config Handler */
+/* Effective configuration, with the secrets redacted (NEVER return h.Config as it is!) */
+func (h *AdminHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, h.Config.Redacted(), nil)
+}
+
+/* GET /db-stats Handler */
+/* Snapshot of the Connection Pool usage, to right-size it (see initPostgres in router.go), together with the
+   transfers holding (or waiting for) a connection */
+func (h *AdminHandler) GetDBStats(w http.ResponseWriter, r *http.Request) {
+	stats := h.DB.Stats()
+	utils.WriteJSON(w, http.StatusOK, models.DBStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		TransfersInFlight:  h.Transfers.InFlight(),
+		TransfersQueued:    h.Transfers.Queued(),
+	}, nil)
+}
+
+/* GET /routes Handler */
+/* Every registered method+pattern with the roles allowed to call it, for building a permissions matrix.
+   NOTE: roles only reflect the ROLE-BASED AUTH middleware: routes with no roles may still require a Token. */
+func (h *AdminHandler) GetRoutes(w http.ResponseWriter, r *http.Request) {
+	/* 1. Walk the registered routes (the router is the source of truth, nothing is hardcoded) + Error Handling */
+	routes := []models.RouteInfo{}
+	err := chi.Walk(h.Routes, func(method, pattern string, _ http.Handler,
+		middlewares ...func(http.Handler) http.Handler) error {
+		roles := middleware.RouteRoles(middlewares...)
+		if roles == nil {
+			roles = []string{}
+		}
+		routes = append(routes, models.RouteInfo{Method: method, Pattern: pattern, Roles: roles})
+		return nil
+	})
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not List the Routes.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Sort them by pattern and method (the walk order isn't stable) and return them */
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	utils.WriteJSON(w, http.StatusOK, routes, nil)
+}
+
+/* GET /maintenance Handler */
+func (h *AdminHandler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	enabled := h.Maintenance.Enabled()
+	utils.WriteJSON(w, http.StatusOK, models.MaintenanceState{Enabled: &enabled}, nil)
+}
+
+/* POST /maintenance Handler */
+/* Body: {"enabled": true|false} - switches maintenance mode on/off at runtime */
+func (h *AdminHandler) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	/* 1. Decode the Body + Error Handling (the enabled field is required) */
+	var state models.MaintenanceState
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&state); err != nil || state.Enabled == nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, `Invalid JSON: expected {"enabled": true|false}`)
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Flip the flag and return the new state */
+	h.Maintenance.Set(*state.Enabled)
+	utils.WriteJSON(w, http.StatusOK, state, nil)
+}
+
+/* GET /audit/export Handler */
+/* Every audit entry (oldest first), optionally within the ?from= and ?to= dates, streamed as CSV with the columns
+   of auditCSVHeader (see IMPORTANT NOTES 2.) */
+func (h *AdminHandler) ExportAudit(w http.ResponseWriter, r *http.Request) {
+	/* 1. Parse the optional date range + Error Handling via Helper Function */
+	from, err := parseDateParam(r, "from")
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	to, err := parseDateParam(r, "to")
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if from != nil && to != nil && from.After(*to) {
+		utils.WriteSafeError(w, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+
+	/* 2. Send the status and the CSV header (only once, with the first entry or at the end if there's none) */
+	out := csv.NewWriter(w)
+	started := false
+	start := func() {
+		started = true
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit.csv"`)
+		w.WriteHeader(http.StatusOK)
+		out.Write(auditCSVHeader)
+	}
+
+	/* 3. Stream the entries row by row via services/ method, flushing them to the client every auditFlushRows */
+	rows := 0
+	err = h.Audit.Export(r.Context(), from, to, func(e models.AuditEntry) error {
+		if !started {
+			start()
+		}
+		impersonatedBy := "" /* empty = the user themselves */
+		if e.ImpersonatedBy != 0 {
+			impersonatedBy = strconv.Itoa(e.ImpersonatedBy)
+		}
+		out.Write([]string{e.CreatedAt.UTC().Format(time.RFC3339), strconv.Itoa(e.UserID), e.Action, e.Resource,
+			strconv.Itoa(e.ResourceID), impersonatedBy})
+		if rows++; rows%auditFlushRows == 0 {
+			out.Flush()
+			http.NewResponseController(w).Flush() /* Not every ResponseWriter can flush: the rows go out anyway */
+		}
+		return out.Error()
+	})
+
+	/* 4. Error Handling: a JSON error if nothing has been sent yet, otherwise the CSV just stops short */
+	if err != nil {
+		if !started {
+			utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Export the Audit Log.")
+			return
+		}
+		logger.Errorf("audit export interrupted after %d rows: %v", rows, err)
+		out.Flush()
+		return
+	}
+	if !started {
+		start()
+	}
+	out.Flush()
+}
@@ -0,0 +1,232 @@
+package handlers
+
+// handlers/ PACKAGE TESTS ****************************************************************************************
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Fake Database
+- The AdminHandler and the UserHandler are built on the concrete services (no interface to mock), hence their
+  tests plug a fake Database under the real services and repositories: fakeConnector answers every query
+  with the function of the test, which sees the SQL text and its arguments.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	/* INTERNAL Packages */
+	"bookapi/internal/middleware"
+	"bookapi/internal/models"
+	"bookapi/internal/repositories"
+	"bookapi/internal/security"
+	"bookapi/internal/services"
+
+	/* EXTERNAL Packages */
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// 2. TEST DOUBLES ************************************************************************************************
+
+/* Answer of the fake Database to one query: the columns and rows of a SELECT (none for the other statements) */
+type fakeAnswer func(query string, args []driver.NamedValue) (columns []string, rows [][]driver.Value, err error)
+
+/* Connector of a fake Database (see IMPORTANT NOTES 1.) */
+type fakeConnector struct {
+	answer fakeAnswer
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+/* Connection of the fake Database: only the context-aware queries are supported */
+type fakeConn struct {
+	answer fakeAnswer
+}
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("fake DB: no prepare") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("fake DB: no transactions") }
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	columns, rows, err := c.answer(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+func (c fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if _, _, err := c.answer(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+/* Rows of the fake Database */
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+/* Connection pool of a fake Database answering with the input function */
+func fakeDB(t *testing.T, answer fakeAnswer) *sql.DB {
+	db := sql.OpenDB(fakeConnector{answer: answer})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// 3. TESTS *******************************************************************************************************
+
+/* TESTER for POST /admin/users/{id}/impersonate ----------------------------------------------------------------*/
+func TestImpersonateUserEndPoint(t *testing.T) {
+	/* 1. Users 5 (user) and 6 (admin) + an audit log that can be made to fail */
+	var lock sync.Mutex
+	var audited []models.AuditEntry
+	auditDown := false
+	db := fakeDB(t, func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case strings.HasPrefix(query, "SELECT id, role, email, active FROM users"):
+			roles := map[int64]string{5: models.RoleUser, 6: models.RoleAdmin}
+			id := args[0].Value.(int64)
+			if roles[id] == "" {
+				return []string{"id", "role", "email", "active"}, nil, nil
+			}
+			return []string{"id", "role", "email", "active"},
+				[][]driver.Value{{id, roles[id], "user@example.com", true}}, nil
+		case strings.HasPrefix(query, "INSERT INTO audit_log"):
+			if auditDown {
+				return nil, nil, errors.New("pq: connection refused")
+			}
+			audited = append(audited, models.AuditEntry{UserID: int(args[0].Value.(int64)),
+				Action: args[1].Value.(string), ResourceID: int(args[3].Value.(int64))})
+			return nil, nil, nil
+		}
+		return nil, nil, errors.New("fake DB: unexpected query " + query)
+	})
+	userRepo, err := repositories.NewUserRepository(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditRepo, err := repositories.NewAuditRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	handler := NewAdminHandler(services.NewUserService(userRepo, "", 4, 0, ""), services.NewAuditService(auditRepo),
+		nil, nil, db, cfg)
+	r := chi.NewRouter()
+	r.Use(middleware.JWTAuth(cfg.JWTSecret, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTLeeway))
+	handler.RegisterRoutes(r)
+	adminToken, err := testToken(1, models.RoleAdmin)
+	if err != nil {
+		t.Fatalf("Error in Generating the Authorization Token")
+	}
+	send := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	/* 2. Success: a token acting as user 5, issued only once the impersonation got audited */
+	rec := send("/admin/users/5/impersonate", adminToken)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected Status 200, got %d (%s)", rec.Code, rec.Body.String())
+	}
+	issued := decodeNestedJSON[models.ImpersonationToken](t, rec.Body)
+	if issued.Token == "" || issued.UserID != 5 || issued.ImpersonatedBy != 1 {
+		t.Errorf("Expected a token for user 5 impersonated by 1, got %+v", issued)
+	}
+	if len(audited) != 1 || audited[0].UserID != 1 || audited[0].Action != models.AuditImpersonate ||
+		audited[0].ResourceID != 5 {
+		t.Errorf("Expected the impersonation of user 5 by admin 1 in the audit log, got %+v", audited)
+	}
+
+	/* 3. Admins can't be impersonated */
+	if rec := send("/admin/users/6/impersonate", adminToken); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected Status 403 for an admin target, got %d", rec.Code)
+	}
+
+	/* 4. No chains: an impersonation token can't impersonate anybody else, even with an admin role */
+	chained, _, err := security.GenerateImpersonationToken(2, models.RoleAdmin, 1, cfg.ImpersonationTTL,
+		cfg.JWTSecret, cfg.JWTIssuer, cfg.JWTAudience)
+	if err != nil {
+		t.Fatalf("Error in Generating the Impersonation Token")
+	}
+	if rec := send("/admin/users/5/impersonate", chained); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected Status 403 for a chained impersonation, got %d", rec.Code)
+	}
+	if len(audited) != 1 {
+		t.Errorf("Expected no audit entry for the refused impersonations, got %+v", audited[1:])
+	}
+
+	/* 5. No audit entry, no token */
+	lock.Lock()
+	auditDown = true
+	lock.Unlock()
+	rec = send("/admin/users/5/impersonate", adminToken)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected Status 500 when the audit log is down, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "token") || strings.Contains(body, "pq:") {
+		t.Errorf("Expected neither the token nor the DB error in the response, got %s", body)
+	}
+}
+
+/* TESTER for the TRUSTED_ORIGINS check of the /admin routes ---------------------------------------------------*/
+func TestAdminRoutes_TrustedOrigins(t *testing.T) {
+	token, err := testToken(1, models.RoleAdmin)
+	if err != nil {
+		t.Fatalf("Error in Generating the Authorization Token")
+	}
+	send := func(trustedOrigins, origin string) int {
+		cfg := testConfig()
+		cfg.TrustedOrigins = trustedOrigins
+		r := chi.NewRouter()
+		r.Use(middleware.JWTAuth(cfg.JWTSecret, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTLeeway))
+		(&AdminHandler{Config: cfg}).RegisterRoutes(r)
+		req := httptest.NewRequest(http.MethodGet, "/admin/profile", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for _, tc := range []struct {
+		name, trustedOrigins, origin string
+		expected                     int
+	}{
+		{"not configured", "", "", http.StatusOK},
+		{"allowed origin", "https://admin.example.com,https://ops.example.com", "https://ops.example.com", http.StatusOK},
+		{"untrusted origin", "https://admin.example.com", "https://evil.example.com", http.StatusForbidden},
+		{"no origin", "https://admin.example.com", "", http.StatusForbidden},
+	} {
+		if code := send(tc.trustedOrigins, tc.origin); code != tc.expected {
+			t.Errorf("%s: Expected Status %d, got %d", tc.name, tc.expected, code)
+		}
+	}
+}
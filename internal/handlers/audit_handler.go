@@ -0,0 +1,88 @@
+package handlers
+
+// handlers/ PACKAGE **********************************************************************************************
+/* The handlers/ package stores all the HTTP Method Handlers keeping the HTTP logic separate from
+   the other packages. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Scope of audit_handler.go
+- Read access to the audit log recorded by the BookHandler (see models/audit.go).
+   2. Own Activity Only
+- GET /me/activity takes the user ID ONLY from the JWT Token (never from the URL or the Body), so that nobody can
+  read the activity of somebody else.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+
+/* Besides the external packages, we also need to import the necessary internal packages defined in the project */
+import (
+	/* INTERNAL Packages */
+	"bookapi/internal/middleware"
+	"bookapi/internal/models"
+	"bookapi/internal/services"
+	"bookapi/internal/utils"
+
+	/* EXTERNAL Packages */
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// 2. GO STRUCTS and UTILITY METHODS  *****************************************************************************
+
+/* STRUCT */
+type AuditHandler struct {
+	Service *services.AuditService
+}
+
+/* STRUCT BUILDER */
+func NewAuditHandler(service *services.AuditService) *AuditHandler {
+	return &AuditHandler{Service: service}
+}
+
+/* Register All Routes */
+func (h *AuditHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/me/activity", h.GetActivity)
+}
+
+// 3. HTTP REQUEST HANDLERS  ***************************************************************************************
+
+/* GET /me/activity Handler -------------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Get my recent activity
+// @Description Returns the book changes made by the authenticated user (create, update, delete, transfer,
+// @Description merge), newest first and paginated. The Link header (RFC 5988) points to the next/previous pages.
+// @Tags activity
+// @Produce json
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param offset query int false "Number of entries to skip (default 0)"
+// @Success 200 {object} models.SuccessResponse{data=[]models.AuditEntry,meta=models.Pagination}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /me/activity [get]
+func (h *AuditHandler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the user ID from the JWT token + Error Handling via Helper Function 	>>>>>> JWT <<<<<<< */
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Unauthorized")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Parse the pagination parameters (always paginated) + Error Handling */
+	limit, offset, err := utils.ParsePagination(r, utils.DefaultPageLimit, utils.MaxPageLimit)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	/* 3. Get one page of the caller's own activity via services/ method + Error Handling */
+	entries, total, err := h.Service.ListActivity(r.Context(), userID, limit, offset)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Activity.")
+		return
+	}
+	/* 4. Set the Link header and return the entries together with the pagination meta */
+	page := models.Pagination{Total: total, Limit: limit, Offset: offset}
+	utils.SetPaginationLinks(w, r, page)
+	utils.WriteJSON(w, http.StatusOK, entries, page)
+}
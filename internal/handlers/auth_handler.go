@@ -10,12 +10,19 @@ package handlers
    	 repositories/ method FindByEmail talking directly to the Database.
      In addition to that it also carries out the creation of the Token than can be used by the client to keep getting
      access to the API endpoints during the entire user's session.
+   2. Sliding Sessions
+   - POST /auth/extend swaps a still-valid token for a fresh one expiring 24h from now ("keep me logged in"), so that
+     an active client never needs to log in again and no separate refresh token is needed. Expired tokens are NOT
+     extended (not even within JWT_LEEWAY): once a session has expired the user has to log in again.
+   - Impersonation tokens (impersonated_by claim) are NOT extended either: they are short-lived on purpose, and every
+     new one has to be asked for (and gets audited) via POST /admin/users/{id}/impersonate.
 */
 
 // 1. IMPORT PACKAGES *********************************************************************************************
 import (
 	/* INTERNAL Packages */
 
+	"bookapi/internal/logger"
 	"bookapi/internal/security"
 	"bookapi/internal/services"
 	"bookapi/internal/utils"
@@ -23,6 +30,8 @@ import (
 	/* EXTERNAL Packages */
 	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -35,22 +44,44 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+/* STRUCT for Token Verification */
+type VerifyRequest struct {
+	Token string `json:"token"`
+}
+
+/* STRUCT for the decoded claims of a verified Token */
+type TokenClaims struct {
+	UserID    int    `json:"user_id"`
+	Role      string `json:"role"`
+	ExpiresAt int64  `json:"exp"` /* Unix time */
+	/* Admin acting as the user (impersonation tokens only) */
+	ImpersonatedBy int `json:"impersonated_by,omitempty"`
+}
+
 /* STRUCT for Authentication via Token */
 type AuthHandler struct {
 	UserService *services.UserService
 	JWTSecret   string
+	JWTIssuer   string
+	JWTAudience string
+	JWTLeeway   time.Duration                   /* Clock skew tolerated when checking the Tokens' expiration */
+	AuthLimit   func(http.Handler) http.Handler /* Stricter rate limit of POST /login (middleware.AuthRateLimit) */
 }
 
 /* STRUCT BUILDER */
 /* Creates and returns a new UserHandler instance */
-func NewAuthHandler(service *services.UserService, secret string) *AuthHandler {
-	return &AuthHandler{UserService: service, JWTSecret: secret}
+func NewAuthHandler(service *services.UserService, secret, issuer, audience string, leeway time.Duration,
+	authLimit func(http.Handler) http.Handler) *AuthHandler {
+	return &AuthHandler{UserService: service, JWTSecret: secret, JWTIssuer: issuer, JWTAudience: audience,
+		JWTLeeway: leeway, AuthLimit: authLimit}
 }
 
 /* Register All Routes */
 func (h *AuthHandler) RegisterRoutes(r chi.Router) {
 	/* STATIC Routes */
-	r.Post("/login", h.Login)
+	r.With(h.AuthLimit).Post("/login", h.Login) /* 	>>>> AUTH RATE LIMIT Middleware <<<<< */
+	r.Post("/auth/verify", h.VerifyToken)
+	r.Post("/auth/extend", h.ExtendToken)
 }
 
 // 3. HTTP REQUEST HANDLERS  ***************************************************************************************
@@ -67,23 +98,104 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		utils.WriteSafeError(w, http.StatusBadRequest, "Invalid input")
 		return
 	}
-	/* 3. Look into Database for User object matching input email + Error Handling via Helper Function */
-	user, err := h.UserService.FindByEmail(req.Email)
+	/* 3. Look into Database for User object matching input email (normalized like on registration, e.g. pasted
+	   with blanks around it; the password is used as it is) + Error Handling via Helper Function */
+	req.Email = services.NormalizeEmail(req.Email)
+	user, err := h.UserService.FindByEmail(r.Context(), req.Email)
 	if err != nil || user == nil {
 		utils.WriteSafeError(w, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
 	/* 4. If User exists..compare input textual Password with stored Hash. + Error Handling via Helper Function */
-	if !security.CheckPasswordHash(req.Password, user.Password) {
+	if !security.CheckPasswordHash(req.Password, user.Password, h.UserService.Pepper) {
 		utils.WriteSafeError(w, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
-	/* 5. If user exists and password is correct....generate Token via JWT + Error Handling via Helper Function */
-	token, err := security.GenerateToken(user.ID, user.Role, h.JWTSecret)
+	/* 4b. Suspended users get no token (told only AFTER the password check, so that the suspension of an account
+	   isn't revealed to whoever doesn't know its password) */
+	if !user.Active {
+		utils.WriteSafeError(w, http.StatusForbidden, services.ErrAccountSuspended.Error())
+		return
+	}
+	/* 5. Upgrade the password hash if it has a lower cost than the configured one. Not fatal: the user is
+	   authenticated anyway and the upgrade gets retried on the next login */
+	if err := h.UserService.UpgradePasswordHash(r.Context(), user, req.Password); err != nil {
+		logger.Warnf("upgrading the password hash of user %d: %v", user.ID, err)
+	}
+	/* 6. If user exists and password is correct....generate Token via JWT + Error Handling via Helper Function */
+	token, err := security.GenerateToken(user.ID, user.Role, h.JWTSecret, h.JWTIssuer, h.JWTAudience)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Failed to generate token.")
+		return
+	}
+	/* 7. Return HTTP Response with 200 Status Code + Token as JSON in the Body via Helper Function */
+	utils.WriteJSON(w, http.StatusOK, token, nil)
+}
+
+/* POST /auth/verify Handler */
+/* Lets a gateway validate a token on behalf of its clients: the token comes in the Body (NOT in the Authorization
+   Header) and is only checked, never consumed or refreshed. */
+func (h *AuthHandler) VerifyToken(w http.ResponseWriter, r *http.Request) {
+	/* 1. Convert JSON from Body of HTTP Request into VerifyRequest Struct + Error Handling via Helper Function */
+	var req VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		utils.WriteSafeError(w, http.StatusBadRequest, "Invalid input")
+		return
+	}
+	/* 2. Check signature, expiration, issuer and audience of the Token + Error Handling via Helper Function */
+	claims, err := security.ParseToken(req.Token, h.JWTSecret, h.JWTIssuer, h.JWTAudience, h.JWTLeeway)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Invalid or expired token.")
+		return
+	}
+	/* 3. Extract user id, role and expiration date from the claims + Error Handling via Helper Function */
+	userID, okID := claims["user_id"].(float64)
+	role, okRole := claims["user_role"].(string)
+	exp, err := claims.GetExpirationTime()
+	if !okID || !okRole || err != nil || exp == nil {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Invalid or expired token.")
+		return
+	}
+	/* 4. Return HTTP Response with 200 Status Code + decoded claims as JSON in the Body via Helper Function */
+	impersonatedBy, _ := claims["impersonated_by"].(float64)
+	utils.WriteJSON(w, http.StatusOK, TokenClaims{UserID: int(userID), Role: role, ExpiresAt: exp.Unix(),
+		ImpersonatedBy: int(impersonatedBy)}, nil)
+}
+
+/* POST /auth/extend Handler */
+/* Issues a fresh token with the same claims as the (still valid) one in the Authorization Header
+   (see IMPORTANT NOTES 2.) */
+func (h *AuthHandler) ExtendToken(w http.ResponseWriter, r *http.Request) {
+	/* 1. Get the Token from the Authorization Header + Error Handling via Helper Function */
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer") {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	/* 2. Check the Token with NO leeway, so that already expired tokens get rejected + Error Handling */
+	claims, err := security.ParseToken(strings.TrimPrefix(auth, "Bearer"), h.JWTSecret, h.JWTIssuer, h.JWTAudience, 0)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Invalid or expired token.")
+		return
+	}
+	/* 3. Extract user id and role from the claims + Error Handling via Helper Function */
+	userID, okID := claims["user_id"].(float64)
+	role, okRole := claims["user_role"].(string)
+	if !okID || !okRole {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Invalid or expired token.")
+		return
+	}
+	/* 3b. Impersonation tokens expire as planned (see IMPORTANT NOTES 2.) */
+	if _, impersonated := claims["impersonated_by"]; impersonated {
+		utils.WriteSafeError(w, http.StatusForbidden, "Impersonation tokens cannot be extended.")
+		return
+	}
+	/* 4. Generate a new Token with the same claims and a reset expiration + Error Handling via Helper Function */
+	token, err := security.GenerateToken(int(userID), role, h.JWTSecret, h.JWTIssuer, h.JWTAudience)
 	if err != nil {
 		utils.WriteSafeError(w, http.StatusInternalServerError, "Failed to generate token.")
 		return
 	}
-	/* 6. Return HTTP Response with 200 Status Code + Token as JSON in the Body via Helper Function */
+	/* 5. Return HTTP Response with 200 Status Code + new Token as JSON in the Body via Helper Function */
 	utils.WriteJSON(w, http.StatusOK, token, nil)
 }
@@ -24,6 +24,17 @@ package handlers
 	- If we want to allow the Response Helper Functions to get used in whatever package of our project (i.e. not
 	  only in the handlers/ package where they are defined), we need to name them with the first letter to be a
 	  CAPITAL letter: i.e. - writeJSON(..) -> WriteJSON(..)
+   5. Admin View of GET /books (?all=true)
+	- models.Book never exposes owner_id (json:"-"). With ?all=true, admins get every book as a models.OwnedBook,
+	  a separate response struct adding owner_id, while anyone else gets 403 (all=false is the normal list).
+   6. Empty List of GET /books
+	- By default no book gives 200 with "data": [] (and the meta when paginated). Clients that prefer 204 No Content
+	  send "X-Empty-List: no-content", or EMPTY_BOOKS_NO_CONTENT=true makes it the default ("X-Empty-List: array"
+	  switches back). With pagination, only a filter matching NO book at all gives 204: a page past the end of a
+	  non-empty list stays 200 [] with its meta.
+   7. Recently Viewed Books
+	- GetBookByID records the books that authenticated users fetch (anonymous requests are never tracked), and
+	  GET /me/recent lists them back (see services/recent_views.go).
 */
 
 /* 1. IMPORT PACKAGES *********************************************************************************************
@@ -31,15 +42,26 @@ package handlers
 import (
 	/* INTERNAL Packages */
 
+	"bookapi/internal/citation"
+	"bookapi/internal/config"
+	"bookapi/internal/feed"
+	"bookapi/internal/logger"
 	"bookapi/internal/middleware"
 	"bookapi/internal/models"
+	"bookapi/internal/security"
 	"bookapi/internal/services"
 	"bookapi/internal/utils"
 
 	/* EXTERNAL Packages */
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5" /*													>>>>>>>>> CHI Router <<<<<<<<*/
 )
@@ -50,33 +72,96 @@ import (
 /* Main Struct */
 type BookHandler struct {
 	Service services.BookService
+	Audit   *services.AuditService /* Records the book changes (nil -> nothing gets recorded) */
+	Config  config.Config          /* Configuration values driving optional behaviors (zero value -> defaults) */
+	/* Cap on the concurrent transfer transactions (nil -> no cap) */
+	Transfers *middleware.Semaphore
+	/* Books recently fetched by every user [GET /me/recent] (nil -> not tracked) */
+	Recent *services.RecentViews
 }
 
 /* Constructor */
-func NewBookHandler(service services.BookService) *BookHandler {
-	return &BookHandler{Service: service}
+func NewBookHandler(service services.BookService, audit *services.AuditService, transfers *middleware.Semaphore,
+	cfg config.Config) *BookHandler {
+	return &BookHandler{Service: service, Audit: audit, Transfers: transfers, Config: cfg,
+		Recent: services.NewRecentViews(cfg.RecentViewsLimit)}
+}
+
+/* Record the action of the caller (JWT Token, with the impersonating admin if any) on the book in the audit log */
+func (h *BookHandler) audit(r *http.Request, action string, bookID int) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(int)
+	impersonatedBy, _ := r.Context().Value(middleware.ImpersonatorKey).(int)
+	h.Audit.Record(r.Context(), models.AuditEntry{UserID: userID, Action: action, Resource: "book", ResourceID: bookID,
+		ImpersonatedBy: impersonatedBy})
 }
 
 /* Register All Routes */
 func (h *BookHandler) RegisterRoutes(r chi.Router) {
+	/* One daily quota shared by both transfer routes (admins exempt) */
+	transferQuota := middleware.DailyQuota(h.Config.TransferDailyLimit, models.RoleAdmin)
+	/* Same role list as GET /me/permissions (can_transfer) */
+	canTransfer := middleware.AllowRoles(models.TransferRoles...)
+	r.Get("/me/favorites", h.GetFavorites)
+	r.Get("/me/pages/total", h.GetTotalPages)
+	r.Get("/me/recent", h.GetRecentBooks)
 	r.Route("/books", func(r chi.Router) {
 		/* STATIC Routes */
 		r.Get("/", h.GetBooks)
 		r.Post("/", h.PostBook)
-		r.With(middleware.AllowRoles("admin")).Post("/transfer", h.TransferPages) /*>>>>>> ROLE-BASED AUTH <<<<<<*/
+		r.Post("/validate", h.ValidateBook)
+		r.Get("/authors", h.GetAuthors)
+		r.Get("/popular", h.GetPopularBooks)
+		r.Get("/compare", h.CompareBooks)
+		r.Get("/changes", h.GetBookChanges)
+		r.Post("/query", h.QueryBooks)
+		r.Post("/ownership", h.CheckOwnership)
+		r.Get("/feed.atom", h.GetAtomFeed)
+		r.Get("/feed.rss", h.GetRSSFeed)
+		r.Put("/pages", h.UpdatePages)
+		r.Get("/schema", h.GetBookSchema)
+		r.With(canTransfer, transferQuota, h.Transfers.Middleware).Post("/transfer", h.TransferPages) /* ROLE-BASED AUTH */
+		r.With(canTransfer, transferQuota, h.Transfers.Middleware).Post("/transfer/batch", h.TransferPagesBatch)
 		/* DYNAMIC Routes */
 		r.Route("/{id}", func(r chi.Router) {
 			r.Get("/", h.GetBookByID)
+			r.Get("/cite", h.GetBookCitation)
+			r.Get("/reviews", h.GetReviews)
+			r.Post("/reviews", h.PostReview)
+			r.Post("/favorite", h.PostFavorite)
+			r.Delete("/favorite", h.DeleteFavorite)
+			r.Post("/merge", h.MergeBook) /* 		>> OWNERSHIP of BOTH books checked in the transaction <<*/
 			r.Group(func(r chi.Router) {
 				r.Use(middleware.EnforceOwnership("id", /*					   >>>>>> OWNERSHIP-BASED AUTH <<<<<<*/
-					func(r *http.Request, id int) (int, error) { return h.Service.GetOwnerID(id) }))
+					h.loadOwner))
 				r.Put("/", h.PutBook)
-				r.With(middleware.AllowRoles("admin")).Delete("/", h.DeleteBook) /*>> ROLE+OWNERSHIP-BASED AUTH <<*/
+				r.Post("/share", h.ShareBook)
+				r.Patch("/", h.PatchBook)
+				r.With(middleware.AllowRoles(models.DeleteBookRoles...)).Delete("/", h.DeleteBook) /*>> ROLE+OWNERSHIP-BASED AUTH <<*/
 			})
 		})
 	})
 }
 
+/* Register the PUBLIC Routes (no authentication required) - see the router for GET /books/example */
+func (h *BookHandler) RegisterPublicRoutes(r chi.Router) {
+	r.Get("/shared/{token}", h.GetSharedBook) /* 				>> AUTHORIZED BY THE SHARE TOKEN ITSELF << */
+}
+
+/* OwnerLoader used by the OWNERSHIP-BASED AUTH Middleware */
+/* A missing book gives 404, except in Create-or-Replace mode, where a PUT on a missing book creates it for the
+   caller, who is therefore its owner. */
+func (h *BookHandler) loadOwner(r *http.Request, id int) (int, error) {
+	ownerID, err := h.Service.GetOwnerID(r.Context(), id)
+	if errors.Is(err, services.ErrBookNotFound) {
+		if userID, ok := r.Context().Value(middleware.UserIDKey).(int); ok && h.Config.PutUpsert &&
+			r.Method == http.MethodPut {
+			return userID, nil
+		}
+		return 0, middleware.ErrResourceNotFound
+	}
+	return ownerID, err
+}
+
 /* 3. HTTP REQUEST HANDLERS  ***************************************************************************************
 *******************************************************************************************************************/
 
@@ -86,21 +171,439 @@ func (h *BookHandler) RegisterRoutes(r chi.Router) {
 /* GET /books Handler --------------------------------------------------------------------------------------------*/
 /* >>>>>> SWAGGER <<<<<<< */
 // @Summary Get all books
-// @Description Returns all books stored in the database
+// @Description Returns all books stored in the database, optionally restricted to a creation date range.
+// @Description Dates are RFC 3339 timestamps (2024-01-01T00:00:00Z) or full dates (2024-01-01, i.e. midnight UTC).
+// @Description When limit or offset is set, the list is paginated: meta holds total/limit/offset and the Link
+// @Description header (RFC 5988) points to the next and previous pages.
+// @Description No book gives 200 with [] by default, or 204 with "X-Empty-List: no-content" (EMPTY_BOOKS_NO_CONTENT).
 // @Tags books
 // @Produce json
+// @Param X-Empty-List header string false "Answer to an empty list: array (200 []) or no-content (204)"
+// @Param created_from query string false "Only books created at or after this date"
+// @Param created_to query string false "Only books created at or before this date"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param offset query int false "Number of books to skip (default 0)"
+// @Param ids query string false "Comma-separated book IDs (e.g. 1,2,3): only these books, other filters ignored"
+// @Param q query string false "Search: only the books whose title or author contains q (case insensitive), other filters ignored"
+// @Param all query bool false "Admins only: every book with its owner_id (models.OwnedBook)"
+// @Param include query string false "reviews_summary: add the review_count of every book (average_rating is always there)"
 // @Success 200 {array} models.Book
+// @Success 204 "No book (X-Empty-List: no-content)"
+// @Header 200 {string} Link "Links to the next/previous pages (paginated requests only)"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /books [get]
 func (h *BookHandler) GetBooks(w http.ResponseWriter, r *http.Request) {
-	books, err := h.Service.ListBooks()
+	/* 0. Batch request (?ids=1,2,3): return just those books */
+	if r.URL.Query().Has("ids") {
+		h.getBooksByIDs(w, r)
+		return
+	}
+	/* 0b. Search (?q=go): return the books matching it (an empty q lists the books as usual) */
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		h.searchBooks(w, r, q)
+		return
+	}
+	/* 1. Parse the optional creation date range + Error Handling via Helper Function */
+	var filter models.BookFilter
+	var err error
+	if filter.CreatedFrom, err = parseDateParam(r, "created_from"); err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if filter.CreatedTo, err = parseDateParam(r, "created_to"); err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && filter.CreatedFrom.After(*filter.CreatedTo) {
+		utils.WriteSafeError(w, http.StatusBadRequest, "created_from must not be after created_to")
+		return
+	}
+	/* 1b. Parse the optional admin view flag (see IMPORTANT NOTES 5.) + Error Handling */
+	all := false
+	if raw := r.URL.Query().Get("all"); raw != "" {
+		if all, err = strconv.ParseBool(raw); err != nil {
+			utils.WriteSafeError(w, http.StatusBadRequest, "all must be true or false")
+			return
+		}
+	}
+	if role, _ := r.Context().Value(middleware.UserRoleKey).(string); all && !slices.Contains(models.AdminRoles, role) {
+		utils.WriteSafeError(w, http.StatusForbidden, "Forbidden: all=true is reserved to admins")
+		return
+	}
+	/* 1c. Parse the optional extra data to include (comma-separated) + Error Handling */
+	for _, include := range strings.Split(r.URL.Query().Get("include"), ",") {
+		switch strings.TrimSpace(include) {
+		case "":
+		case "reviews_summary":
+			filter.IncludeReviewsSummary = true
+		default:
+			utils.WriteSafeError(w, http.StatusBadRequest, "include must be reviews_summary")
+			return
+		}
+	}
+	/* 2. Parse the optional pagination parameters (paginated only if limit or offset is given) + Error Handling */
+	paginated := r.URL.Query().Get("limit") != "" || r.URL.Query().Get("offset") != ""
+	filter.Limit, filter.Offset, err = utils.ParsePagination(r, utils.DefaultPageLimit, utils.MaxPageLimit)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	/* 3. Get the (filtered) list of books via services/ method + Error Handling */
+	books, err := h.Service.ListBooks(r.Context(), filter)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Books.")
+		return
+	}
+	/* 4. Not paginated: return the list of books (with their owners in the admin view) with HTTP Status 200 */
+	var data any = books
+	if all {
+		data = ownedBooks(books)
+	}
+	noContent := len(books) == 0 && utils.EmptyListAsNoContent(r, h.Config.EmptyBooksNoContent)
+	if !paginated {
+		if noContent { /* (see IMPORTANT NOTES 6.) */
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, data, nil)
+		return
+	}
+	/* 5. Paginated: count all the matching books, then set the Link header and the meta of the HTTP Response */
+	total, err := h.Service.CountBooks(r.Context(), filter)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Books.")
+		return
+	}
+	if noContent && total == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	page := models.Pagination{Total: total, Limit: filter.Limit, Offset: filter.Offset}
+	utils.SetPaginationLinks(w, r, page)
+	utils.WriteJSON(w, http.StatusOK, data, page)
+}
+
+/* Admin view of a list of books: the same books with their owner_id (encoded as [] and not null) */
+func ownedBooks(books []models.Book) []models.OwnedBook {
+	owned := make([]models.OwnedBook, 0, len(books))
+	for _, b := range books {
+		owned = append(owned, models.OwnedBook{Book: b, OwnerID: b.OwnerID})
+	}
+	return owned
+}
+
+/* POST /books/query Handler -----------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Query books with a JSON filter
+// @Description Advanced alternative to the GET /books query string: author, title substring, pages and year
+// @Description ranges, sort (id, title, author, pages, year, created_at; "-" prefix = descending) and pagination.
+// @Description Every filter is optional. Invalid values are reported per field.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param query body models.BookQuery true "Filters, sort and pagination"
+// @Success 200 {object} models.SuccessResponse{data=[]models.Book,meta=models.Pagination}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/query [post]
+func (h *BookHandler) QueryBooks(w http.ResponseWriter, r *http.Request) {
+	/* 1. Decode the JSON object from the HTTP Request (unknown fields rejected) + Error Handling */
+	var q models.BookQuery
+	if err := utils.ExpectJSONShape(r, false); err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if err := utils.DecodeJSON(r.Body, &q, true); err != nil {
+		utils.WriteDecodeError(w, err, "Invalid JSON query.")
+		return
+	}
+	/* 2. Get one page of the matching books via services/ method (which validates the query) + Error Handling */
+	books, page, err := h.Service.QueryBooks(r.Context(), q)
+	var invalid services.ValidationError
+	if errors.As(err, &invalid) {
+		utils.WriteValidationError(w, http.StatusBadRequest, "Missing/Invalid JSON Field values.", invalid)
+		return
+	}
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Books.")
+		return
+	}
+	/* 3. Return the books with the pagination meta, same envelope as the paginated GET /books (no Link header:
+	   the pagination lives in the Body, not in the URL) */
+	utils.WriteJSON(w, http.StatusOK, books, page)
+}
+
+/* GET /books/feed.atom and /books/feed.rss Handlers ----------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Atom feed of the latest books
+// @Description The most recently created books (newest first) as an Atom feed, one entry per book. Paginated via
+// @Description limit (default 20, max 100) and offset: the Link header points to the next and previous pages.
+// @Tags books
+// @Produce xml
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param offset query int false "Number of books to skip (default 0)"
+// @Success 200 {string} string "Atom feed"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/feed.atom [get]
+func (h *BookHandler) GetAtomFeed(w http.ResponseWriter, r *http.Request) {
+	h.writeFeed(w, r, feed.AtomContentType, func(baseURL string, books []models.Book) ([]byte, error) {
+		return feed.Atom(baseURL, baseURL+r.URL.RequestURI(), books)
+	})
+}
+
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary RSS feed of the latest books
+// @Description Same as GET /books/feed.atom, in the RSS 2.0 format (one item per book)
+// @Tags books
+// @Produce xml
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param offset query int false "Number of books to skip (default 0)"
+// @Success 200 {string} string "RSS feed"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/feed.rss [get]
+func (h *BookHandler) GetRSSFeed(w http.ResponseWriter, r *http.Request) {
+	h.writeFeed(w, r, feed.RSSContentType, feed.RSS)
+}
+
+/* Shared by the two feed handlers: get one page of the latest books and write it in the format built by render */
+func (h *BookHandler) writeFeed(w http.ResponseWriter, r *http.Request, contentType string,
+	render func(baseURL string, books []models.Book) ([]byte, error)) {
+	/* 1. Parse the optional pagination parameters + Error Handling */
+	limit, offset, err := utils.ParsePagination(r, utils.DefaultPageLimit, utils.MaxPageLimit)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Get one page of the books, newest first, via services/ method + Error Handling */
+	books, page, err := h.Service.QueryBooks(r.Context(), models.BookQuery{Sort: "-created_at", Limit: limit, Offset: offset})
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Books.")
+		return
+	}
+	/* 3. Render the feed, with links pointing back to this API */
+	body, err := render(requestBaseURL(r), books)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Render the Feed.")
+		return
+	}
+	/* 4. Return the feed with its own Content-Type and the Link header of the adjacent pages */
+	utils.SetPaginationLinks(w, r, page)
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+/* GET /books?ids=1,2,3 - books matching the comma-separated IDs (IDs not found are simply absent) */
+func (h *BookHandler) getBooksByIDs(w http.ResponseWriter, r *http.Request) {
+	/* 1. Parse the comma-separated IDs + Error Handling */
+	ids, invalid := parseIDList(r.URL.Query().Get("ids"))
+	if len(invalid) > 0 {
+		utils.WriteSafeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid book IDs in ids: %s", strings.Join(invalid, ", ")))
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if len(ids) == 0 {
+		utils.WriteSafeError(w, http.StatusBadRequest, "ids must be a non-empty comma-separated list of book IDs")
+		return
+	}
+	/* 2. Get the books via services/ method + Error Handling */
+	books, err := h.Service.GetBooksByIDs(r.Context(), ids)
 	if err != nil {
 		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Books.")
 		return
 	}
+	/* 3. Return the found books with HTTP Status 200 via Helper Function */
+	utils.WriteJSON(w, http.StatusOK, books, nil)
+}
+
+/* GET /books?q=go - books whose title or author contains q (case insensitive), sorted by id */
+func (h *BookHandler) searchBooks(w http.ResponseWriter, r *http.Request, q string) {
+	/* 1. Get the matching books via services/ method + Error Handling */
+	books, err := h.Service.SearchBooks(r.Context(), q)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Search Books.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Return the matching books with HTTP Status 200 (204 if none and asked so, see IMPORTANT NOTES 6.) */
+	if len(books) == 0 && utils.EmptyListAsNoContent(r, h.Config.EmptyBooksNoContent) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 	utils.WriteJSON(w, http.StatusOK, books, nil)
 }
 
+/* Parse a comma-separated list of IDs (e.g. "1,2,3"), collecting ALL the invalid tokens, quoted (e.g. "" in "1,,3") */
+func parseIDList(raw string) (ids []int, invalid []string) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	for _, token := range strings.Split(raw, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(token))
+		if err != nil {
+			invalid = append(invalid, strconv.Quote(token))
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, invalid
+}
+
+/* 400 message for an {id} strconv.Atoi couldn't parse: "id out of range" if too big/small, else "Invalid id input." */
+func idErrorMessage(err error) string {
+	if errors.Is(err, strconv.ErrRange) {
+		return "id out of range"
+	}
+	return "Invalid id input."
+}
+
+/* Max number of items of a batch Body when the Config doesn't set one (e.g. zero-value Config in tests) */
+const defaultMaxBatchItems = 1000
+
+/*
+Reject a batch Body with more than maxItems items (MAX_BATCH_ITEMS) before any processing, logging its size and
+sending back 400 "too many items (max N)". Returns true if the batch got rejected (HTTP Response already sent).
+*/
+func rejectLargeBatch(w http.ResponseWriter, r *http.Request, items, maxItems int) bool {
+	if maxItems <= 0 {
+		maxItems = defaultMaxBatchItems
+	}
+	if items <= maxItems {
+		return false
+	}
+	logger.Warnf("batch of %d items (max %d) rejected on %s %s", items, maxItems, r.Method, r.URL.Path)
+	utils.WriteSafeError(w, http.StatusBadRequest, fmt.Sprintf("too many items (max %d)", maxItems))
+	return true
+}
+
+/* Parse an optional date query parameter given as RFC 3339 timestamp or full date (nil if missing) */
+func parseDateParam(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("Invalid %s: expected an RFC 3339 date (e.g. 2024-01-01 or 2024-01-01T00:00:00Z)", name)
+}
+
+/* GET /books/authors Handler -----------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Get distinct authors
+// @Description Returns the sorted list of unique authors, optionally filtered by name prefix (q).
+// @Description Non-admin users only get the authors of their own books.
+// @Tags books
+// @Produce json
+// @Param q query string false "Author name prefix"
+// @Success 200 {array} string
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/authors [get]
+func (h *BookHandler) GetAuthors(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the user ID and ROLE from the JWT token + Error Handling via Helper Function */
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int) /*						>>>>>> JWT <<<<<<< */
+	if !ok {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	role, _ := r.Context().Value(middleware.UserRoleKey).(string)
+	/* 2. Admins see the authors of all books, everybody else only the ones of their own books (ownerID=0 -> all) */
+	ownerID := userID
+	if role == "admin" {
+		ownerID = 0
+	}
+	/* 3. Get the list of authors matching the optional prefix via services/ method + Error Handling */
+	authors, err := h.Service.ListAuthors(r.Context(), r.URL.Query().Get("q"), ownerID)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Authors.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 4. Return the list of authors with HTTP Status 200 via Helper Function */
+	utils.WriteJSON(w, http.StatusOK, authors, nil)
+}
+
+/* GET /books/schema Handler ------------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Get the Book JSON Schema
+// @Description Returns the JSON Schema document describing the Book input (required fields, types, constraints)
+// @Tags books
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /books/schema [get]
+func (h *BookHandler) GetBookSchema(w http.ResponseWriter, r *http.Request) {
+	/* 1. Set the Content-Type of the Body of the HTTP Response to the JSON Schema media type */
+	w.Header().Set("Content-Type", "application/schema+json")
+	/* 2. Write the raw schema document (NOT wrapped in data/meta) so that validators can consume it directly */
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(services.BookSchema())
+}
+
+/* Base URL of this API as seen by the client (scheme://host of the HTTP Request), used to build absolute links */
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+/* GET /shared/{token} Handler ----------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Read a shared book
+// @Description Returns the book of a read-only link created by POST /books/{id}/share. No authentication required:
+// @Description the token is the authorization, until it expires.
+// @Tags books
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} models.SuccessResponse{data=models.Book}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /shared/{token} [get]
+func (h *BookHandler) GetSharedBook(w http.ResponseWriter, r *http.Request) {
+	/* 1. Check the share token and read the book ID out of it + Error Handling (invalid and expired alike) */
+	id, err := security.ParseShareToken(chi.URLParam(r, "token"), h.Config.JWTSecret, h.Config.JWTIssuer,
+		h.Config.JWTAudience, h.Config.JWTLeeway)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusNotFound, "Share link not found or expired.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Get the book using the services/ method + Error Handling (it may have been deleted since) */
+	book, err := h.Service.GetBookByID(r.Context(), id)
+	if err != nil || book == nil {
+		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Return the book */
+	utils.WriteJSON(w, http.StatusOK, book, nil)
+}
+
+/* GET /books/example Handler ----------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Get an example Book payload
+// @Description Returns a valid POST /books Body (built from the Swagger examples of the Book model) that can be
+// @Description copied as a starting point. No authentication required.
+// @Tags books
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /books/example [get]
+func (h *BookHandler) GetBookExample(w http.ResponseWriter, r *http.Request) {
+	/* 1. Set the Content-Type of the Body of the HTTP Response */
+	w.Header().Set("Content-Type", "application/json")
+	/* 2. Write the raw example (NOT wrapped in data/meta) so that it can be copied and sent as it is */
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(services.BookExample())
+}
+
 /* POST /books Handler ------------------------------------------------------------------------------------------*/
 /* >>>>>> SWAGGER <<<<<<< */
 // @Summary Create a new book
@@ -111,6 +614,7 @@ func (h *BookHandler) GetBooks(w http.ResponseWriter, r *http.Request) {
 // @Param book body models.Book true "Book to create"
 // @Success 200 {object} models.SuccessResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /books [post]
 func (h *BookHandler) PostBook(w http.ResponseWriter, r *http.Request) {
@@ -124,15 +628,16 @@ func (h *BookHandler) PostBook(w http.ResponseWriter, r *http.Request) {
 	/* 2. Declare Go Struct to convert JSON from HTTP Request into. */
 	var book models.Book
 
-	/* 3. Create Decoder Object */
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-
-	/* 4. Handle Error in Decoding the JSON from the HTTP Request into corresponding Go Struct */
-	err := decoder.Decode(&book)
+	/* 3. Decode the JSON object (a clear error if it's something else, e.g. an array) from the HTTP Request into
+	   corresponding Go Struct (unknown fields and out of range pages rejected) and Handle Error using the Error
+	   Response Helper Function */
+	if err := utils.ExpectJSONShape(r, false); err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	err := utils.DecodeJSON(r.Body, &book, true)
 	if err != nil {
-		/* Error handled using the Error Response Helper Function */
-		utils.WriteError(w, http.StatusBadRequest, err, "Invalid Inputs.")
+		utils.WriteDecodeError(w, err, "Invalid Inputs.")
 		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
 	}
 
@@ -141,22 +646,69 @@ func (h *BookHandler) PostBook(w http.ResponseWriter, r *http.Request) {
 	   is carried out by the VALIDATEBOOK Method in the services/ package and that gets executed
 	   inside all the methods of the BookService object !! */
 
-	/* 5. Assign the user_id to the book's owner_id field */
+	/* 4. Assign the user_id to the book's owner_id field */
 	book.OwnerID = userID
 
-	/* 4. Add new Book record in the Database via services/ method. */
-	newBook, err := h.Service.CreateBook(book)
-	if err != nil {
-		/* 5. If an error is returned by the service method,
+	/* 5. Add new Book record in the Database via services/ method. */
+	newBook, err := h.Service.CreateBook(r.Context(), book)
+	var conflict *services.AlreadyExistsError
+	if errors.As(err, &conflict) {
+		/* A unique constraint has been violated: tell the client which field collides */
+		utils.WriteSafeError(w, http.StatusConflict, conflict.Error())
+	} else if err != nil {
+		/* 6. If an error is returned by the service method,
 		warn the client about an Internal Server Error via Helper Function. */
 		utils.WriteError(w, http.StatusInternalServerError, err, "Server Error.")
 	} else {
-		/* 6. Convert Go Struct back to JSON, write it to the Body of the HTTP Response
+		/* 7. Convert Go Struct back to JSON, write it to the Body of the HTTP Response
 		and send it to Client. */
+		h.audit(r, models.AuditCreate, newBook.ID)
 		utils.WriteJSON(w, http.StatusCreated, newBook, nil)
 	}
 }
 
+/* POST /books/validate Handler ---------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Validate a book without saving it
+// @Description Dry run of POST /books: runs the very same service validation and returns {"valid": true}, or 422
+// @Description with every failed field. Nothing gets stored.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param book body models.Book true "Book to validate"
+// @Success 200 {object} models.SuccessResponse{data=models.BookValidation}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/validate [post]
+func (h *BookHandler) ValidateBook(w http.ResponseWriter, r *http.Request) {
+	/* 1. Decode the JSON object exactly as POST /books does + Error Handling */
+	if err := utils.ExpectJSONShape(r, false); err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var book models.Book
+	if err := utils.DecodeJSON(r.Body, &book, true); err != nil {
+		utils.WriteDecodeError(w, err, "Invalid Inputs.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+
+	/* 2. Run the service validation (nothing gets stored) and report every failed field with 422 */
+	err := h.Service.ValidateBook(r.Context(), book)
+	var invalid services.ValidationError
+	if errors.As(err, &invalid) {
+		utils.WriteValidationError(w, http.StatusUnprocessableEntity, "Missing/Invalid JSON Field values.", invalid)
+		return
+	}
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Validate the Book.")
+		return
+	}
+
+	/* 3. Return the HTTP Response with HTTP Status Code 200 */
+	utils.WriteJSON(w, http.StatusOK, models.BookValidation{Valid: true}, nil)
+}
+
 /* POST /transfer Handler ---------------------------------------------------------------------------------------*/
 /* >>>>>> SWAGGER <<<<<<< */
 // @Summary Transfer pages between two books
@@ -180,120 +732,924 @@ func (h *BookHandler) TransferPages(w http.ResponseWriter, r *http.Request) {
 
 	/* 2. Convert the JSON Body of the HTTP Request into a TransferRequest Go Struct + Error Handling */
 	var req models.TransferRequest
-	err := json.NewDecoder(r.Body).Decode(&req)
+	err := utils.DecodeJSON(r.Body, &req, false)
 	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, err, "Invalid Inputs.")
+		utils.WriteDecodeError(w, err, "Invalid Inputs.")
 		return
 	}
 
-	/* 3. Check Values of JSON fields from the Body of the HTTP Request + Error Handling */
-	if req.FromID <= 0 || req.ToID <= 0 || req.Pages <= 0 {
-		utils.WriteSafeError(w, http.StatusBadRequest, "Missing/Invalid JSON Field values.")
+	/* 3. EXECUTE the TRANSACTION  - Executes multiple SQL Queries in one single unit of work/function  */
+	err = h.Service.TransferPages(r.Context(), req)
+
+	/* 4. Check any error due to invalid JSON field values (all of them listed per field) or to failure of
+	   Transaction and handle it with helper function */
+	var invalid services.ValidationError
+	if errors.As(err, &invalid) {
+		utils.WriteValidationError(w, http.StatusBadRequest, "Missing/Invalid JSON Field values.", invalid)
 		return
 	}
-
-	/* 4. EXECUTE the TRANSACTION  - Executes multiple SQL Queries in one single unit of work/function  */
-	err = h.Service.TransferPages(req)
-
-	/* 5. Check any error due to failure of Transaction and handle it with helper function */
 	if err != nil {
 		utils.WriteSafeError(w, http.StatusInternalServerError, "Transfer failed: "+err.Error())
 		return
 	}
 
-	/* 6. Return the HTTP Response with HTTP Status Code 200 and
+	/* 5. Return the HTTP Response with HTTP Status Code 200 and
 	the Transfer Request object via helper function*/
+	h.audit(r, models.AuditTransfer, req.FromID)
+	h.audit(r, models.AuditTransfer, req.ToID)
 	utils.WriteJSON(w, http.StatusOK, req, nil)
 }
 
-/* DYNAMIC HTTP Request Handlers -----------------------------------------------------------------------------------
-------------------------------------------------------------------------------------------------------------------*/
-
-/* GET /books/{id} Handler ---------------------------------------------------------------------------------------*/
+/* POST /books/transfer/batch Handler ---------------------------------------------------------------------------*/
 /* >>>>>> SWAGGER <<<<<<< */
-// @Summary Get book by ID
-// @Description Retrieves a book by its ID
+// @Summary Transfer pages across many pairs of books
+// @Description Applies all the transfers (in order) within one transaction: either all of them succeed or none
+// @Description does. The failed transfer is identified by its index within the list.
 // @Tags books
+// @Accept json
 // @Produce json
-// @Param id path int true "Book ID"
+// @Param transfers body []models.TransferRequest true "Pages transfers"
 // @Success 200 {object} models.SuccessResponse
 // @Failure 400 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Router /books/{id} [get]
-func (h *BookHandler) GetBookByID(w http.ResponseWriter, r *http.Request) {
-	/* 1. Extract the id using the CHI Router directly from the HTTP Request r 		>>>>>>>>> CHI Router <<<<<<<<*/
-	idStr := chi.URLParam(r, "id")
-	/* 2. Convert id from string to int + Error Handling */
-	id, err := strconv.Atoi(idStr)
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/transfer/batch [post]
+func (h *BookHandler) TransferPagesBatch(w http.ResponseWriter, r *http.Request) {
+	/* 1. Convert the JSON Body (an array, a clear error otherwise) of the HTTP Request into a list of
+	   TransferRequest Go Structs + Error Handling */
+	if err := utils.ExpectJSONShape(r, true); err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var transfers []models.TransferRequest
+	err := utils.DecodeJSON(r.Body, &transfers, true)
 	if err != nil {
-		utils.WriteSafeError(w, http.StatusBadRequest, "Invalid id input.")
+		utils.WriteDecodeError(w, err, "Invalid Inputs.")
 		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
 	}
-	/* 3. Get Book Go Struct and corresponding Error Object based on input ID using the services/ method */
-	book, err := h.Service.GetBookByID(id)
-	/* 4. Handle possible returned error using the Error Response Helper Function */
+
+	/* 2. Reject the batches longer than MAX_BATCH_ITEMS before touching the Database */
+	if rejectLargeBatch(w, r, len(transfers), h.Config.MaxBatchItems) {
+		return
+	}
+
+	/* 3. EXECUTE the TRANSACTION via services/ method */
+	err = h.Service.TransferPagesBatch(r.Context(), transfers)
+
+	/* 4. Check any error due to invalid JSON field values (keyed by "[index].field"), to a transfer that could
+	   not be applied (named by its index) or to the failure of the Transaction and handle it with helper function */
+	var invalid services.ValidationError
+	if errors.As(err, &invalid) {
+		utils.WriteValidationError(w, http.StatusBadRequest, "Missing/Invalid JSON Field values.", invalid)
+		return
+	}
+	var failed *services.TransferBatchError
+	if errors.As(err, &failed) {
+		utils.WriteValidationError(w, http.StatusConflict, failed.Error()+". No transfer was applied.",
+			map[string]string{fmt.Sprintf("[%d]", failed.Index): failed.Reason})
+		return
+	}
 	if err != nil {
-		utils.WriteError(w, http.StatusNotFound, err, "Book Not Found.")
-		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Transfer failed. No transfer was applied.")
+		return
 	}
-	if book == nil {
-		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
-		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+
+	/* 5. Return the HTTP Response with HTTP Status Code 200 and the applied transfers via helper function */
+	for _, t := range transfers {
+		h.audit(r, models.AuditTransfer, t.FromID)
+		h.audit(r, models.AuditTransfer, t.ToID)
 	}
-	/* 5. Convert the found Book Go Struct into JSON, write it to the Body of the HTTP Response and send it to
-	Client. */
-	utils.WriteJSON(w, http.StatusOK, book, nil)
+	utils.WriteJSON(w, http.StatusOK, transfers, nil)
 }
 
-/* PUT /books/{id} Handler ---------------------------------------------------------------------------------------*/
+/* PUT /books/pages Handler -------------------------------------------------------------------------------------*/
 /* >>>>>> SWAGGER <<<<<<< */
-// @Summary Update a book
-// @Description Replace an existing book with a new instance
+// @Summary Bulk update pages
+// @Description Sets the pages of many books within one transaction. Missing books are reported as failed, while
+// @Description a book not owned by the caller (non-admins) rolls back the whole update.
 // @Tags books
 // @Accept json
 // @Produce json
-// @Param book body models.Book true "Updated Book"
-// @Success 200 {object} models.SuccessResponse
+// @Param updates body []models.PagesUpdate true "Pages updates"
+// @Success 200 {object} models.BulkUpdateResult
 // @Failure 400 {object} models.ErrorResponse
-// @Router /books/{id} [put]
-func (h *BookHandler) PutBook(w http.ResponseWriter, r *http.Request) {
-	/* 1. Extract the id using the CHI Router directly from the HTTP Request r 		>>>>>>>>> CHI Router <<<<<<<<*/
-	idStr := chi.URLParam(r, "id")
-	/* 2. Convert id from string to int + Error Handling */
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		utils.WriteSafeError(w, http.StatusBadRequest, "Invalid id input.")
-	}
-	/* 3. Declare Go Struct to store the JSON passed in the Body of the HTTP Request */
-	var book models.Book
-	/* 4. Create the decoder object to convert the JSON into the corresponding Go Struct */
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-	/* 5. Convert JSON to Go Struct and handle possible errors via Error Response Helper Function */
-	err = decoder.Decode(&book)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, err, "Invalid inputs.")
-		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/pages [put]
+func (h *BookHandler) UpdatePages(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the user ID and ROLE from the JWT token + Error Handling via Helper Function */
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int) /*						>>>>>> JWT <<<<<<< */
+	if !ok {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
 	}
+	role, _ := r.Context().Value(middleware.UserRoleKey).(string)
 
-	/* 6. Check values of JSON Fields and handle possible errors via Error Safe Response Helper Function
-	   Carried out inside the services/ method UpdateBook(..) via the private method validateBook(..) */
-
-	/* 7. Look for the book having id matching the input one and, if found, replace it with input book
-	   and return the updated book object via the services/ method UpdateBook() . */
-	updatedBook, err := h.Service.UpdateBook(id, book)
-	/* 8. If error is returned, handle it using the Error Safe Response Helper Function */
+	/* 2. Convert the JSON Body (an array, a clear error otherwise) of the HTTP Request into a list of PagesUpdate
+	   Go Structs + Error Handling */
+	if err := utils.ExpectJSONShape(r, true); err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var updates []models.PagesUpdate
+	err := utils.DecodeJSON(r.Body, &updates, true)
 	if err != nil {
-		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
+		utils.WriteDecodeError(w, err, "Invalid Inputs.")
 		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
 	}
 
-	/* 9. If everything has gone well, return an HTTP Response with HTTP Status 200 and a Body containing the
-	   JSON of the updated object using the Success Response Helper Function */
-	utils.WriteJSON(w, http.StatusOK, updatedBook, nil)
-
-}
-
+	/* 3. Check Values of JSON fields from the Body of the HTTP Request + Error Handling */
+	if len(updates) == 0 {
+		utils.WriteSafeError(w, http.StatusBadRequest, "At least one update is required.")
+		return
+	}
+	if rejectLargeBatch(w, r, len(updates), h.Config.MaxBatchItems) {
+		return
+	}
+	for _, u := range updates {
+		if u.ID <= 0 || u.Pages <= 0 {
+			utils.WriteSafeError(w, http.StatusBadRequest, "Missing/Invalid JSON Field values.")
+			return
+		}
+	}
+
+	/* 4. Admins can update any book, everybody else only their own books (ownerID=0 -> no ownership check) */
+	ownerID := userID
+	if role == "admin" {
+		ownerID = 0
+	}
+
+	/* 5. EXECUTE the TRANSACTION via services/ method + Error Handling */
+	result, err := h.Service.UpdatePages(r.Context(), updates, ownerID)
+	if errors.Is(err, services.ErrNotOwner) {
+		utils.WriteSafeError(w, http.StatusForbidden, "Forbidden: not owner of all the books. No book was updated.")
+		return
+	}
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Bulk update failed.")
+		return
+	}
+
+	/* 6. Return the HTTP Response with HTTP Status Code 200 and the succeeded/failed IDs via helper function */
+	for _, id := range result.Succeeded {
+		h.audit(r, models.AuditUpdate, id)
+	}
+	utils.WriteJSON(w, http.StatusOK, result, nil)
+}
+
+/* GET /books/changes Handler -----------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Get the books changed since a timestamp (delta sync)
+// @Description Returns the books created or updated at or after since (oldest change first). meta.synced_at is the
+// @Description since to send on the next call: it lags a little behind (at least 1 minute, more while a write is in
+// @Description progress) so that no change is ever missed, and a book may then come back twice: treat them as
+// @Description upserts by ID.
+// @Description NOTE: deleted books are not reported (books are hard-deleted, there are no tombstones).
+// @Tags books
+// @Produce json
+// @Param since query string true "RFC 3339 timestamp (e.g. 2024-01-01T00:00:00Z)"
+// @Success 200 {object} models.SuccessResponse{data=[]models.Book,meta=models.SyncMeta}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/changes [get]
+func (h *BookHandler) GetBookChanges(w http.ResponseWriter, r *http.Request) {
+	/* 1. Parse the mandatory since parameter (full RFC 3339 timestamp only) + Error Handling via Helper Function */
+	since, err := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest,
+			"Invalid since: expected an RFC 3339 timestamp (e.g. 2024-01-01T00:00:00Z)")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Get the changed books via services/ method + Error Handling */
+	books, meta, err := h.Service.ListChangedSince(r.Context(), since)
+	if err != nil {
+		logger.Errorf("listing the books changed since %s: %v", since.Format(time.RFC3339), err)
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Book Changes.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Return the changed books with the sync meta */
+	utils.WriteJSON(w, http.StatusOK, books, meta)
+}
+
+/* GET /me/favorites Handler ------------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary List my favorite books
+// @Description Returns the books favorited by the authenticated user (latest first)
+// @Tags books
+// @Produce json
+// @Success 200 {object} models.SuccessResponse
+// @Router /me/favorites [get]
+func (h *BookHandler) GetFavorites(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the user ID from the JWT token + Error Handling via Helper Function 	>>>>>> JWT <<<<<<< */
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Unauthorized")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Get the favorite books via services/ method + Error Handling */
+	books, err := h.Service.ListFavorites(r.Context(), userID)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Favorites.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Return the list of books */
+	utils.WriteJSON(w, http.StatusOK, books, nil)
+}
+
+/* GET /me/recent Handler --------------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary List my recently viewed books
+// @Description Returns the last books fetched by the authenticated user via GET /books/{id} (latest first, at most
+// @Description RECENT_VIEWS_LIMIT). Deleted books are left out. Kept in memory: a restart empties the list.
+// @Tags books
+// @Produce json
+// @Success 200 {object} models.SuccessResponse{data=[]models.Book}
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /me/recent [get]
+func (h *BookHandler) GetRecentBooks(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the user ID from the JWT token + Error Handling via Helper Function 	>>>>>> JWT <<<<<<< */
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Unauthorized")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Get the recently viewed books via services/ method + Error Handling */
+	books := []models.Book{}
+	ids := h.Recent.List(userID)
+	if len(ids) > 0 {
+		found, err := h.Service.GetBooksByIDs(r.Context(), ids)
+		if err != nil {
+			utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Recent Books.")
+			return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+		}
+		/* 3. Put them back in viewing order (they come sorted by ID), skipping the deleted ones */
+		byID := make(map[int]models.Book, len(found))
+		for _, book := range found {
+			byID[book.ID] = book
+		}
+		for _, id := range ids {
+			if book, ok := byID[id]; ok {
+				books = append(books, book)
+			}
+		}
+	}
+	/* 4. Return the list of books */
+	utils.WriteJSON(w, http.StatusOK, books, nil)
+}
+
+/* GET /me/pages/total Handler ---------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Get the total pages of my books
+// @Description Returns the sum of the pages of the books owned by the authenticated user (0 if none)
+// @Tags books
+// @Produce json
+// @Success 200 {object} models.SuccessResponse{data=models.PagesTotal}
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /me/pages/total [get]
+func (h *BookHandler) GetTotalPages(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the user ID from the JWT token + Error Handling via Helper Function 	>>>>>> JWT <<<<<<< */
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Unauthorized")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Sum the pages of the caller's books via services/ method + Error Handling */
+	total, err := h.Service.TotalPages(r.Context(), userID)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Compute Total Pages.")
+		return
+	}
+	/* 3. Return the total */
+	utils.WriteJSON(w, http.StatusOK, models.PagesTotal{TotalPages: total}, nil)
+}
+
+/* POST /books/ownership Handler -------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Check which books I own
+// @Description For every input book ID tells whether the authenticated user owns it (false for missing books),
+// @Description e.g. to render the edit buttons of a list with one request instead of one per book.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param ownership body models.OwnershipRequest true "Book IDs (1-100)"
+// @Success 200 {object} models.SuccessResponse{data=map[string]bool}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/ownership [post]
+func (h *BookHandler) CheckOwnership(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the user ID from the JWT token + Error Handling via Helper Function 	>>>>>> JWT <<<<<<< */
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Unauthorized")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Decode the JSON object from the HTTP Request (unknown fields rejected) + Error Handling */
+	var req models.OwnershipRequest
+	if err := utils.ExpectJSONShape(r, false); err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := utils.DecodeJSON(r.Body, &req, true); err != nil {
+		utils.WriteDecodeError(w, err, "Invalid Inputs.")
+		return
+	}
+	/* 3. Check the ownership of all the books via services/ method + Error Handling */
+	owned, err := h.Service.CheckOwnership(r.Context(), userID, req.IDs)
+	var invalid services.ValidationError
+	if errors.As(err, &invalid) {
+		utils.WriteValidationError(w, http.StatusBadRequest, "Missing/Invalid JSON Field values.", invalid)
+		return
+	}
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Check Ownership.")
+		return
+	}
+	/* 4. Return the {id: owned} object */
+	utils.WriteJSON(w, http.StatusOK, owned, nil)
+}
+
+/* DYNAMIC HTTP Request Handlers -----------------------------------------------------------------------------------
+------------------------------------------------------------------------------------------------------------------*/
+
+/* GET /books/{id} Handler ---------------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Get book by ID
+// @Description Retrieves a book by its ID
+// @Tags books
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param If-Modified-Since header string false "Return 304 if not modified since this date (RFC 1123)"
+// @Success 200 {object} models.SuccessResponse
+// @Header 200 {string} Last-Modified "Date of the last update of the book (RFC 1123)"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /books/{id} [get]
+func (h *BookHandler) GetBookByID(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the id using the CHI Router directly from the HTTP Request r 		>>>>>>>>> CHI Router <<<<<<<<*/
+	idStr := chi.URLParam(r, "id")
+	/* 2. Convert id from string to int + Error Handling */
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, idErrorMessage(err))
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Get Book Go Struct and corresponding Error Object based on input ID using the services/ method */
+	book, err := h.Service.GetBookByID(r.Context(), id)
+	/* 4. Handle possible returned error using the Error Response Helper Function */
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err, "Book Not Found.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if book == nil {
+		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 4b. Record the view for GET /me/recent (authenticated users only, see IMPORTANT NOTES 7.) */
+	if userID, ok := r.Context().Value(middleware.UserIDKey).(int); ok {
+		h.Recent.Add(userID, book.ID)
+	}
+	/* 5. Set the Last-Modified Header from the book's updated_at (RFC 1123, GMT) so that clients and caching
+	proxies can revalidate via If-Modified-Since: if the book hasn't changed since, return 304 with no Body. */
+	lastModified := book.UpdatedAt.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !lastModified.After(since) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	/* 6. Convert the found Book Go Struct into JSON, write it to the Body of the HTTP Response and send it to
+	Client. */
+	utils.WriteJSON(w, http.StatusOK, book, nil)
+}
+
+/* GET /books/{id}/cite Handler ----------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Cite a book
+// @Description Returns the formatted citation of a book (APA, MLA or Chicago) built from author, title and year
+// @Tags books
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param style query string false "Citation style: apa (default), mla, chicago"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /books/{id}/cite [get]
+func (h *BookHandler) GetBookCitation(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the id from the URL and convert it to int + Error Handling 		>>>>>>>>> CHI Router <<<<<<<<*/
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, idErrorMessage(err))
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Read the optional style query parameter (APA by default) */
+	style := strings.ToLower(r.URL.Query().Get("style"))
+	if style == "" {
+		style = citation.StyleAPA
+	}
+	/* 3. Get the book using the services/ method + Error Handling */
+	book, err := h.Service.GetBookByID(r.Context(), id)
+	if err != nil || book == nil {
+		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 4. Format the citation in the requested style + Error Handling (unknown style -> 400) */
+	text, err := citation.Format(style, book.Author, book.Title, book.Year)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 5. Return the citation */
+	utils.WriteJSON(w, http.StatusOK, models.Citation{Style: style, Citation: text}, nil)
+}
+
+/* POST /books/{id}/share Handler ------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Share a book
+// @Description Owner only: creates a read-only link to the book (GET /shared/{token}) that anyone can open without
+// @Description an account. The link can't be revoked: it stops working after SHARE_LINK_TTL (7 days by default).
+// @Tags books
+// @Produce json
+// @Param id path int true "Book ID"
+// @Success 201 {object} models.SuccessResponse{data=models.ShareLink}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/{id}/share [post]
+func (h *BookHandler) ShareBook(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the id from the URL and convert it to int + Error Handling 		>>>>>>>>> CHI Router <<<<<<<<*/
+	/* NOTE: the ownership (hence the existence) of the book has been checked by the OWNERSHIP-BASED AUTH Middleware */
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, idErrorMessage(err))
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Sign the share token of the book 												>>>>>> JWT <<<<<<< */
+	token, expiresAt, err := security.GenerateShareToken(id, h.Config.ShareLinkTTL, h.Config.JWTSecret,
+		h.Config.JWTIssuer, h.Config.JWTAudience)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Create the Share Link.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Return the link with HTTP Status Code 201, auditing it (the token itself is never logged) */
+	h.audit(r, models.AuditShare, id)
+	utils.WriteJSON(w, http.StatusCreated, models.ShareLink{BookID: id, Token: token,
+		URL: requestBaseURL(r) + "/shared/" + token, ExpiresAt: models.NewAPITime(expiresAt)}, nil)
+}
+
+/* POST /books/{id}/reviews Handler -----------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Review a book
+// @Description Rates a book from 1 to 5. One review per user per book: a new review replaces the previous one.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param review body models.ReviewRequest true "Review"
+// @Success 200 {object} models.SuccessResponse
+// @Success 201 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /books/{id}/reviews [post]
+func (h *BookHandler) PostReview(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the user ID from the JWT token + Error Handling via Helper Function 	>>>>>> JWT <<<<<<< */
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Unauthorized")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Extract the book id from the URL and convert it to int + Error Handling 	>>>>>>>>> CHI Router <<<<<<<<*/
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, idErrorMessage(err))
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Convert the JSON Body of the HTTP Request into the ReviewRequest Go Struct + Error Handling */
+	var req models.ReviewRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err, "Invalid Inputs.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 4. Store the review (book from the URL, reviewer from the token) via services/ method + Error Handling */
+	review := models.Review{BookID: id, UserID: userID, Rating: req.Rating, Comment: req.Comment}
+	stored, created, err := h.Service.ReviewBook(r.Context(), review)
+	switch {
+	case errors.Is(err, services.ErrBookNotFound):
+		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	case errors.Is(err, services.ErrInvalidRating), errors.Is(err, services.ErrCommentTooLong):
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	case err != nil:
+		logger.Errorf("storing the review of book %d by user %d: %v", id, userID, err)
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could not store the review.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 5. Return 201 for a new review, 200 if it has replaced the previous one */
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	utils.WriteJSON(w, status, stored, nil)
+}
+
+/* GET /books/{id}/reviews Handler ------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary List the reviews of a book
+// @Description Returns one page of the reviews of a book (newest first): meta holds total/limit/offset and the
+// @Description Link header (RFC 5988) points to the next and previous pages.
+// @Tags books
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param offset query int false "Number of reviews to skip (default 0)"
+// @Success 200 {object} models.SuccessResponse{data=[]models.Review,meta=models.Pagination}
+// @Header 200 {string} Link "Links to the next/previous pages"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /books/{id}/reviews [get]
+func (h *BookHandler) GetReviews(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the book id from the URL and convert it to int + Error Handling 	>>>>>>>>> CHI Router <<<<<<<<*/
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, idErrorMessage(err))
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Parse the optional pagination parameters (always paginated, 20 per page by default) + Error Handling */
+	limit, offset, err := utils.ParsePagination(r, utils.DefaultPageLimit, utils.MaxPageLimit)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	/* 3. Get one page of the reviews via services/ method + Error Handling */
+	reviews, page, err := h.Service.ListReviews(r.Context(), id, limit, offset)
+	if errors.Is(err, services.ErrBookNotFound) {
+		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Reviews.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 4. Return the page of reviews with the pagination meta and the Link header */
+	utils.SetPaginationLinks(w, r, page)
+	utils.WriteJSON(w, http.StatusOK, reviews, page)
+}
+
+/* GET /books/popular Handler -----------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary List the most popular books
+// @Description Returns one page of the favorited books, most favorited first, each with its favorite_count:
+// @Description meta holds total/limit/offset and the Link header (RFC 5988) points to the next and previous pages.
+// @Tags books
+// @Produce json
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param offset query int false "Number of books to skip (default 0)"
+// @Success 200 {object} models.SuccessResponse{data=[]models.PopularBook,meta=models.Pagination}
+// @Header 200 {string} Link "Links to the next/previous pages"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/popular [get]
+func (h *BookHandler) GetPopularBooks(w http.ResponseWriter, r *http.Request) {
+	/* 1. Parse the optional pagination parameters (always paginated, 20 per page by default) + Error Handling */
+	limit, offset, err := utils.ParsePagination(r, utils.DefaultPageLimit, utils.MaxPageLimit)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Get one page of the most favorited books via services/ method + Error Handling */
+	books, page, err := h.Service.ListPopular(r.Context(), limit, offset)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Fetch Books.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Return the page of books with the pagination meta and the Link header */
+	utils.SetPaginationLinks(w, r, page)
+	utils.WriteJSON(w, http.StatusOK, books, page)
+}
+
+/* GET /books/compare Handler -----------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Compare two books
+// @Description Returns both books side by side with the differences of b compared to a: page and year deltas,
+// @Description whether the author is the same and the JSON names of the fields with different values.
+// @Tags books
+// @Produce json
+// @Param a query int true "ID of the first book"
+// @Param b query int true "ID of the second book"
+// @Success 200 {object} models.SuccessResponse{data=models.BookComparison}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/compare [get]
+func (h *BookHandler) CompareBooks(w http.ResponseWriter, r *http.Request) {
+	/* 1. Parse the IDs of the two books + Error Handling */
+	aID, errA := strconv.Atoi(r.URL.Query().Get("a"))
+	bID, errB := strconv.Atoi(r.URL.Query().Get("b"))
+	if errA != nil || errB != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, "a and b must be book IDs (e.g. ?a=1&b=2)")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Compare the books via services/ method + Error Handling (404 if either is missing) */
+	comparison, err := h.Service.CompareBooks(r.Context(), aID, bID)
+	if errors.Is(err, services.ErrBookNotFound) {
+		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Compare Books.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Return the comparison */
+	utils.WriteJSON(w, http.StatusOK, comparison, nil)
+}
+
+/* POST /books/{id}/favorite Handler ----------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Favorite a book
+// @Description Bookmarks a book for the authenticated user (idempotent)
+// @Tags books
+// @Param id path int true "Book ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /books/{id}/favorite [post]
+func (h *BookHandler) PostFavorite(w http.ResponseWriter, r *http.Request) {
+	h.toggleFavorite(w, r, h.Service.AddFavorite)
+}
+
+/* DELETE /books/{id}/favorite Handler --------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Unfavorite a book
+// @Description Removes a book from the favorites of the authenticated user (idempotent)
+// @Tags books
+// @Param id path int true "Book ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /books/{id}/favorite [delete]
+func (h *BookHandler) DeleteFavorite(w http.ResponseWriter, r *http.Request) {
+	h.toggleFavorite(w, r, h.Service.RemoveFavorite)
+}
+
+/* Shared logic of POST and DELETE /books/{id}/favorite - toggle is the services/ method to call */
+func (h *BookHandler) toggleFavorite(w http.ResponseWriter, r *http.Request,
+	toggle func(ctx context.Context, userID, bookID int) error) {
+	/* 1. Extract the user ID from the JWT token + Error Handling via Helper Function 	>>>>>> JWT <<<<<<< */
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Unauthorized")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Extract the book id from the URL and convert it to int + Error Handling 	>>>>>>>>> CHI Router <<<<<<<<*/
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, idErrorMessage(err))
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Add/Remove the favorite + Error Handling */
+	err = toggle(r.Context(), userID, id)
+	if errors.Is(err, services.ErrBookNotFound) {
+		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Update Favorites.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 4. Return 204 (No Content) */
+	utils.WriteJSON(w, http.StatusNoContent, nil, nil)
+}
+
+/* POST /books/{id}/merge Handler ------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Merge a duplicate book
+// @Description Adds the pages of book merge_from_id to book {id} and deletes book merge_from_id, in one
+// @Description transaction. Non-admin users must own both books.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param id path int true "ID of the book that is kept"
+// @Param merge body models.MergeRequest true "ID of the duplicate book"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/{id}/merge [post]
+func (h *BookHandler) MergeBook(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the user ID and ROLE from the JWT token + Error Handling via Helper Function */
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int) /*						>>>>>> JWT <<<<<<< */
+	if !ok {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Unauthorized")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	role, _ := r.Context().Value(middleware.UserRoleKey).(string)
+	/* 2. Extract the book id from the URL and convert it to int + Error Handling 	>>>>>>>>> CHI Router <<<<<<<<*/
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, idErrorMessage(err))
+		return
+	}
+	/* 3. Convert the JSON Body of the HTTP Request into the MergeRequest Go Struct + Error Handling */
+	var req models.MergeRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err, "Invalid Inputs.")
+		return
+	}
+	/* 4. Admins can merge any books, everybody else only their own books (ownerID=0 -> no ownership check) */
+	ownerID := userID
+	if role == "admin" {
+		ownerID = 0
+	}
+	/* 5. EXECUTE the TRANSACTION via services/ method + Error Handling */
+	merged, err := h.Service.MergeBooks(r.Context(), id, req.MergeFromID, ownerID)
+	var invalid services.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		utils.WriteValidationError(w, http.StatusBadRequest, "Missing/Invalid JSON Field values.", invalid)
+		return
+	case errors.Is(err, services.ErrBookNotFound):
+		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
+		return
+	case errors.Is(err, services.ErrNotOwner):
+		utils.WriteSafeError(w, http.StatusForbidden, "Forbidden: not owner of both books.")
+		return
+	case err != nil:
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Merge failed.")
+		return
+	}
+	/* 6. Return the merged book with HTTP Status 200 via Helper Function */
+	h.audit(r, models.AuditMerge, id)
+	h.audit(r, models.AuditDelete, req.MergeFromID)
+	utils.WriteJSON(w, http.StatusOK, merged, nil)
+}
+
+/* PUT /books/{id} Handler ---------------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Update a book
+// @Description Replace an existing book with a new instance. If PUT_UPSERT is enabled, a missing book gets created.
+// @Description In that mode the id must be between 1 and 1073741824 (models.MaxUpsertBookID).
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param book body models.Book true "Updated Book"
+// @Param If-Unmodified-Since header string false "Replace only if not modified since this date (RFC 1123)"
+// @Success 200 {object} models.SuccessResponse
+// @Success 201 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /books/{id} [put]
+func (h *BookHandler) PutBook(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the id using the CHI Router directly from the HTTP Request r 		>>>>>>>>> CHI Router <<<<<<<<*/
+	idStr := chi.URLParam(r, "id")
+	/* 2. Convert id from string to int + Error Handling */
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, idErrorMessage(err))
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Declare Go Struct to store the JSON passed in the Body of the HTTP Request */
+	var book models.Book
+	/* 4. Convert JSON to Go Struct (unknown fields and out of range pages rejected) and handle possible errors
+	   via Error Response Helper Function */
+	err = utils.DecodeJSON(r.Body, &book, true)
+	if err != nil {
+		utils.WriteDecodeError(w, err, "Invalid inputs.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+
+	/* 5. Check values of JSON Fields and handle possible errors via Error Safe Response Helper Function
+	   Carried out inside the services/ method UpdateBook(..) via the private method validateBook(..) */
+
+	/* 6. Look for the book having id matching the input one and, if found, replace it with input book
+	   and return the updated book object via the services/ method UpdateBook() .
+	   If the client sent the If-Unmodified-Since Header, the book gets replaced ONLY IF it hasn't been
+	   modified after that date (optimistic concurrency - prevents lost updates).
+	   In Create-or-Replace mode (PUT_UPSERT=true), a missing book gets created with the input id instead. */
+	var updatedBook *models.Book
+	var created bool
+	if header := r.Header.Get("If-Unmodified-Since"); header != "" {
+		since, parseErr := http.ParseTime(header)
+		if parseErr != nil {
+			utils.WriteSafeError(w, http.StatusBadRequest, "Invalid If-Unmodified-Since header.")
+			return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+		}
+		updatedBook, err = h.Service.UpdateBookIfUnmodifiedSince(r.Context(), id, book, since)
+	} else if h.Config.PutUpsert {
+		book.OwnerID, _ = r.Context().Value(middleware.UserIDKey).(int)
+		updatedBook, created, err = h.Service.ReplaceBook(r.Context(), id, book)
+	} else {
+		updatedBook, err = h.Service.UpdateBook(r.Context(), id, book)
+	}
+	/* 7. If error is returned, handle it using the Error Safe Response Helper Function */
+	if errors.Is(err, services.ErrPreconditionFailed) {
+		utils.WriteSafeError(w, http.StatusPreconditionFailed, "Book has been modified since the given date.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if errors.Is(err, services.ErrNotOwner) { /* Create-or-Replace: the id got taken meanwhile by another owner */
+		utils.WriteSafeError(w, http.StatusForbidden, "Forbidden: not owner")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	var invalid services.ValidationError
+	if errors.As(err, &invalid) { /* Invalid fields, or (Create-or-Replace) an id clients may not create */
+		utils.WriteValidationError(w, http.StatusBadRequest, "Missing/Invalid JSON Field values.", invalid)
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if errors.Is(err, services.ErrBookNotFound) {
+		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if err != nil {
+		logger.Errorf("replacing book %d: %v", id, err)
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Update the Book.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+
+	/* 8. If everything has gone well, return an HTTP Response with HTTP Status 200 (201 if the book has been
+	   created) and a Body containing the JSON of the updated object using the Success Response Helper Function */
+	status, action := http.StatusOK, models.AuditUpdate
+	if created {
+		status, action = http.StatusCreated, models.AuditCreate
+	}
+	h.audit(r, action, updatedBook.ID)
+	utils.WriteJSON(w, status, updatedBook, nil)
+
+}
+
+/* PATCH /books/{id} Handler -------------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Partially update book by ID
+// @Description Updates only the fields present in the Body. The Meta of the Response lists the fields whose value
+// @Description has actually changed.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param book body models.BookPatch true "Fields to update"
+// @Success 200 {object} models.SuccessResponse{data=models.Book,meta=models.PatchMeta}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /books/{id} [patch]
+func (h *BookHandler) PatchBook(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the id using the CHI Router directly from the HTTP Request r 		>>>>>>>>> CHI Router <<<<<<<<*/
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, idErrorMessage(err))
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Convert the JSON object of the Body into the BookPatch Go Struct + Error Handling */
+	if err := utils.ExpectJSONShape(r, false); err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var patch models.BookPatch
+	if err := utils.DecodeJSON(r.Body, &patch, true); err != nil {
+		utils.WriteDecodeError(w, err, "Invalid inputs.")
+		return
+	}
+	/* 3. Apply the patch via services/ method + Error Handling */
+	updatedBook, changed, err := h.Service.PatchBook(r.Context(), id, patch)
+	if errors.Is(err, services.ErrBookNotFound) {
+		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
+		return
+	}
+	if err != nil {
+		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	/* 4. Return the updated book together with the list of changed fields */
+	if len(changed) > 0 {
+		h.audit(r, models.AuditUpdate, id)
+	}
+	utils.WriteJSON(w, http.StatusOK, updatedBook, models.PatchMeta{Changed: changed})
+}
+
 /* DELETE /books/{id} Handler ---------------------------------------------------------------------------------------*/
 /* >>>>>> SWAGGER <<<<<<< */
 // @Summary Delete book by ID
@@ -311,17 +1667,19 @@ func (h *BookHandler) DeleteBook(w http.ResponseWriter, r *http.Request) {
 	/* 2. Convert id from string to int + Error Handling */
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		utils.WriteSafeError(w, http.StatusBadRequest, "Invalid id input.")
+		utils.WriteSafeError(w, http.StatusBadRequest, idErrorMessage(err))
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
 	}
 	/* 3. Delete book by id directly in the database via the services/ method DeleteBook() */
-	err = h.Service.DeleteBook(id)
+	err = h.Service.DeleteBook(r.Context(), id)
 	/* 4. If an error gets returned by the services/ method, that means that the provided id doesn't
 	exist in the database. The error gets handled using a Error Safe Response Helper Function */
 	if err != nil {
 		utils.WriteSafeError(w, http.StatusNotFound, "Book Not Found.")
 		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
 	}
-	/* 5. If no error has been returned, return an HTTP Status Code 204 (No Content) within an HTTP Response
-	having null/empty Body */
+	/* 5. If no error has been returned, record the deletion and return an HTTP Status Code 204 (No Content)
+	within an HTTP Response having null/empty Body */
+	h.audit(r, models.AuditDelete, id)
 	utils.WriteJSON(w, http.StatusNoContent, nil, nil)
 }
@@ -0,0 +1,39 @@
+package handlers
+
+// handlers/ PACKAGE **********************************************************************************************
+/* The handlers/ package stores all the HTTP Method Handlers keeping the HTTP logic separate from
+   the other packages. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Scope of docs_handler.go
+- GET /openapi.json returns the raw OpenAPI (Swagger 2.0) spec generated by swag into the docs/ package, the same
+  document rendered by the Swagger UI at /swagger/, so that tools like openapi-generator can consume it directly.
+- The spec is regenerated with `swag init` (see the >>>>>> SWAGGER <<<<<<< annotations of the handlers): this
+  handler only serves what has been generated.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	/* INTERNAL Packages */
+	"bookapi/docs"
+	"bookapi/internal/utils"
+
+	/* EXTERNAL Packages */
+	"net/http"
+)
+
+// 2. HTTP REQUEST HANDLERS  ***************************************************************************************
+
+/* GET /openapi.json Handler */
+func GetOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	/* 1. Render the generated spec (host, base path, etc. filled in from docs.SwaggerInfo) */
+	spec := docs.SwaggerInfo.ReadDoc()
+	if spec == "" {
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Load the OpenAPI spec.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Return it as it is (NOT wrapped in the data/meta envelope, so that tools can read it directly) */
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(spec))
+}
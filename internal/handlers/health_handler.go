@@ -0,0 +1,228 @@
+package handlers
+
+// handlers/ PACKAGE **********************************************************************************************
+/* The handlers/ package stores all the HTTP Method Handlers keeping the HTTP logic separate from
+   the other packages. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Scope of health_handler.go
+- GET /health is the LIGHTWEIGHT check meant for load balancers and container probes: public, no Database
+  round-trip, it only tells that the process is up and serving requests.
+- GET /health/live (alias of GET /health) answers 200 until the process exits, while GET /health/ready answers
+  503 as soon as the graceful shutdown begins: load balancers stop sending new requests to the instance while
+  the in-flight ones are still being served (see the drain period in main.go).
+- GET /health/detailed is the RICHER check meant for dashboards (admins only): dependencies status (PostgreSQL
+  and, when the rate limiter uses it, Redis), schema migrations, goroutine count and memory usage.
+- GET /health/migrations (public) answers 503 while the Database schema is behind the latest version known by the
+  code: used as a readiness probe, it keeps a new release from serving traffic against an un-migrated schema. It
+  reads the Database, unlike the other probes.
+   2. Dependency Checks
+- Every dependency gets pinged with its own short timeout, so that one hanging dependency can't hang the whole
+  report. A dependency that is down does NOT make the handler fail: it gets reported and the response status
+  becomes 503, so that dashboards can alert on it.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+
+/* Besides the external packages, we also need to import the necessary internal packages defined in the project */
+import (
+	/* INTERNAL Packages */
+	"bookapi/internal/middleware"
+	"bookapi/internal/models"
+	"bookapi/internal/utils"
+
+	/* EXTERNAL Packages */
+	"context"
+	"database/sql"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// 2. GO STRUCTS and UTILITY METHODS  *****************************************************************************
+
+/* Max time given to every single dependency to answer its ping */
+const healthPingTimeout = 2 * time.Second
+
+/* Readiness - Go Struct */
+/* Goroutine-safe readiness flag: set by main when the shutdown begins, read by GET /health/ready */
+type Readiness struct {
+	shuttingDown atomic.Bool
+}
+
+/* Constructor - ready until ShutdownStarted gets called */
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+/* Flip readiness off for good (the shutdown can't be undone) */
+func (r *Readiness) ShutdownStarted() {
+	r.shuttingDown.Store(true)
+}
+
+/* Whether the instance should still receive new requests */
+func (r *Readiness) Ready() bool {
+	return !r.shuttingDown.Load()
+}
+
+/* STRUCT */
+type HealthHandler struct {
+	DB        *sql.DB
+	PingRedis func(ctx context.Context) error /* nil when the rate limiter doesn't use Redis */
+	/* Schema version of the Database vs the latest known one (e.g. repositories.SchemaRepository.MigrationStatus) */
+	Migrations func(ctx context.Context) (models.MigrationStatus, error)
+	Readiness  *Readiness
+	StartedAt  time.Time
+}
+
+/* STRUCT BUILDER */
+func NewHealthHandler(db *sql.DB, pingRedis func(ctx context.Context) error,
+	migrations func(ctx context.Context) (models.MigrationStatus, error), readiness *Readiness) *HealthHandler {
+	return &HealthHandler{DB: db, PingRedis: pingRedis, Migrations: migrations, Readiness: readiness,
+		StartedAt: time.Now()}
+}
+
+/* Register the PUBLIC Routes (probes must not need a JWT Token) */
+func (h *HealthHandler) RegisterPublicRoutes(r chi.Router) {
+	r.Get("/health", h.GetHealth)
+	r.Get("/health/live", h.GetHealth)
+	r.Get("/health/ready", h.GetReadiness)
+	r.Get("/health/migrations", h.GetMigrations)
+}
+
+/* Register the PROTECTED Routes */
+func (h *HealthHandler) RegisterRoutes(r chi.Router) {
+	r.With(middleware.AllowRoles(models.AdminRoles...)).Get("/health/detailed", h.GetDetailedHealth) /* >> ROLE-BASED AUTH <<*/
+}
+
+/* Ping a dependency with its own timeout, returning "ok" or the error message */
+func pingStatus(ctx context.Context, ping func(ctx context.Context) error) string {
+	ctx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+	defer cancel()
+	if err := ping(ctx); err != nil {
+		return err.Error()
+	}
+	return models.HealthOK
+}
+
+/* Read the migration status with the same timeout as the pings, the error message becoming its status */
+func (h *HealthHandler) migrationStatus(ctx context.Context) models.MigrationStatus {
+	ctx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+	defer cancel()
+	status, err := h.Migrations(ctx)
+	if err != nil {
+		return models.MigrationStatus{Status: err.Error()}
+	}
+	return status
+}
+
+// 3. HTTP REQUEST HANDLERS  ***************************************************************************************
+
+/* GET /health Handler ------------------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Liveness check
+// @Description Lightweight check for probes: answers as long as the server is up, without touching the Database.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.SuccessResponse{data=map[string]string}
+// @Router /health [get]
+func (h *HealthHandler) GetHealth(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"status": models.HealthOK}, nil)
+}
+
+/* GET /health/ready Handler -----------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Readiness check
+// @Description 200 while the instance accepts new requests, 503 from the moment its graceful shutdown begins
+// @Description (the in-flight requests are still served). Meant for readiness probes and load balancers.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.SuccessResponse{data=map[string]string}
+// @Failure 503 {object} models.SuccessResponse{data=map[string]string}
+// @Router /health/ready [get]
+func (h *HealthHandler) GetReadiness(w http.ResponseWriter, r *http.Request) {
+	if !h.Readiness.Ready() {
+		utils.WriteJSON(w, http.StatusServiceUnavailable, map[string]string{"status": models.HealthShuttingDown}, nil)
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"status": models.HealthOK}, nil)
+}
+
+/* GET /health/migrations Handler -------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Schema migrations check
+// @Description 200 when the Database schema is at (or past) the latest version known by the code, 503 when it's
+// @Description behind or its version can't be read. Meant for readiness probes during deployments.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.SuccessResponse{data=models.MigrationStatus}
+// @Failure 503 {object} models.SuccessResponse{data=models.MigrationStatus}
+// @Router /health/migrations [get]
+func (h *HealthHandler) GetMigrations(w http.ResponseWriter, r *http.Request) {
+	status := h.migrationStatus(r.Context())
+	if status.Status != models.HealthOK {
+		utils.WriteJSON(w, http.StatusServiceUnavailable, status, nil)
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	utils.WriteJSON(w, http.StatusOK, status, nil)
+}
+
+/* GET /health/detailed Handler ---------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Detailed health report
+// @Description Dependencies status (PostgreSQL, Redis if used by the rate limiter), goroutine count and memory
+// @Description usage. Answers 503 with the same report when at least one dependency is not reachable or the
+// @Description Database schema is behind (migrations).
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.SuccessResponse{data=models.HealthReport}
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 503 {object} models.SuccessResponse{data=models.HealthReport}
+// @Security BearerAuth
+// @Router /health/detailed [get]
+func (h *HealthHandler) GetDetailedHealth(w http.ResponseWriter, r *http.Request) {
+	/* 1. Ping the dependencies (see IMPORTANT NOTES 2.) */
+	dependencies := map[string]string{
+		"postgres": pingStatus(r.Context(), h.DB.PingContext),
+		"redis":    models.HealthDisabled,
+	}
+	if h.PingRedis != nil {
+		dependencies["redis"] = pingStatus(r.Context(), h.PingRedis)
+	}
+	/* 2. Read the runtime statistics */
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	/* 3. Build the report, degraded if any dependency is not reachable or the schema is behind */
+	report := models.HealthReport{
+		Status:       models.HealthOK,
+		Ready:        h.Readiness.Ready(),
+		GoVersion:    runtime.Version(),
+		Uptime:       time.Since(h.StartedAt).Round(time.Second).String(),
+		Dependencies: dependencies,
+		Migrations:   h.migrationStatus(r.Context()),
+		Goroutines:   runtime.NumGoroutine(),
+		Memory: models.MemoryStats{
+			AllocBytes:     mem.Alloc,
+			HeapInuseBytes: mem.HeapInuse,
+			SysBytes:       mem.Sys,
+			NumGC:          mem.NumGC,
+		},
+	}
+	status := http.StatusOK
+	for _, dependencyStatus := range dependencies {
+		if dependencyStatus != models.HealthOK && dependencyStatus != models.HealthDisabled {
+			report.Status = models.HealthDegraded
+			status = http.StatusServiceUnavailable
+		}
+	}
+	if report.Migrations.Status != models.HealthOK {
+		report.Status = models.HealthDegraded
+		status = http.StatusServiceUnavailable
+	}
+	/* 4. Return the report */
+	utils.WriteJSON(w, status, report, nil)
+}
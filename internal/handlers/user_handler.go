@@ -8,6 +8,16 @@ package handlers
 /* 1. Scope of user_handler.go
 - This go file contain the method Register() that wraps around the services/ method Register() that wraps
 around the repositories/ method Create() talking directly to the Database.
+- A single-use invite code (invite_code) is required: missing, invalid or already used codes get 400.
+   2. Closed Registration
+- With REGISTRATION_ENABLED=false the POST /register route stays registered but always answers 403, so that clients
+  get a clear reason instead of a 404/405 and only admins can create users.
+   3. Repeated Registrations
+- Re-sending the same email+password right after a successful registration (e.g. a double-click) answers 200 with
+  the existing user instead of 409, see REGISTER_REPLAY_WINDOW (201 = created now, 200 = created just before).
+   4. Data Export
+- GET /me/export returns every piece of data of the caller in one JSON document (see services/export_service.go),
+  sent as an attachment so that browsers save it as a file.
 */
 
 // 1. IMPORT PACKAGES *********************************************************************************************
@@ -15,13 +25,15 @@ around the repositories/ method Create() talking directly to the Database.
 /* Besides the external packages, we also need to import the necessary internal packages defined in the project */
 import (
 	/* INTERNAL Packages */
-
+	"bookapi/internal/logger"
+	"bookapi/internal/middleware"
 	"bookapi/internal/models"
 	"bookapi/internal/services"
 	"bookapi/internal/utils"
 
 	/* EXTERNAL Packages */
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -32,49 +44,124 @@ import (
 /* STRUCT */
 /* Holds a reference to UserService, which contains the logic for registering users. */
 type UserHandler struct {
-	Service *services.UserService
+	Service             *services.UserService
+	AuthLimit           func(http.Handler) http.Handler /* Stricter rate limit of POST /register (middleware.AuthRateLimit) */
+	RegistrationEnabled bool                            /* Whether POST /register is open (see IMPORTANT NOTES 2.) */
+	Export              *services.ExportService         /* Data of GET /me/export (see IMPORTANT NOTES 4.) */
 }
 
 /* STRUCT BUILDER */
 /* Creates and returns a new UserHandler instance */
-func NewUserHandler(service *services.UserService) *UserHandler {
-	return &UserHandler{Service: service}
+func NewUserHandler(service *services.UserService, export *services.ExportService,
+	authLimit func(http.Handler) http.Handler, registrationEnabled bool) *UserHandler {
+	return &UserHandler{Service: service, Export: export, AuthLimit: authLimit, RegistrationEnabled: registrationEnabled}
 }
 
 /* Register All Routes */
 func (h *UserHandler) RegisterRoutes(r chi.Router) {
 	r.Route("/register", func(r chi.Router) {
 		/* STATIC Routes */
-		r.Post("/", h.Register)
+		r.With(h.AuthLimit).Post("/", h.Register) /*   >>>> AUTH RATE LIMIT Middleware <<<<< */
 	})
 }
 
+/* Register the PROTECTED Routes (JWT Token required) */
+func (h *UserHandler) RegisterProtectedRoutes(r chi.Router) {
+	r.Get("/me/permissions", h.GetPermissions)
+	r.Get("/me/export", h.GetExport)
+}
+
 // 3. HTTP REQUEST HANDLERS  ***************************************************************************************
 
 /* STATIC HTTP Request Handlers ---------------------------------------------------------------------------------*/
 
 /* POST /register Handler ---------------------------------------------------------------------------------------*/
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
-	/* 1. Decode JSON Body of HTTP Request + Error Handling */
+	/* 1. Short-circuit if registration is disabled (see IMPORTANT NOTES 2.) */
+	if !h.RegistrationEnabled {
+		utils.WriteSafeError(w, http.StatusForbidden, "Registration is disabled")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Decode JSON Body of HTTP Request + Error Handling */
 	var req models.RegisterRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		utils.WriteSafeError(w, http.StatusBadRequest, "Invalid Request")
 		return
 	}
-	/* 2. Add record in the Database via the service/ layer + Error Handling */
-	user, err := h.Service.Register(req)
+	/* 3. Add record in the Database via the service/ layer + Error Handling */
+	user, created, err := h.Service.Register(r.Context(), req)
+	var conflict *services.AlreadyExistsError
+	if errors.Is(err, services.ErrEmailTaken) || (errors.As(err, &conflict) && conflict.Field == "email") {
+		utils.WriteSafeError(w, http.StatusConflict, "A user with this email is already registered.")
+		return
+	}
+	if conflict != nil {
+		utils.WriteSafeError(w, http.StatusConflict, conflict.Error())
+		return
+	}
 	if err != nil {
 		utils.WriteSafeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	/* 3. Build Go Struct holding id and email of registered user */
+	/* 4. Build Go Struct holding id and email of registered user */
 	resp := struct {
 		ID    int    `json:"id"`
 		Email string `json:"email"`
 	}{user.ID, user.Email}
 
-	/* 4. Return HTTP Response with 201 Status Code, registered user object and no error */
-	utils.WriteJSON(w, http.StatusCreated, resp, nil)
+	/* 5. Return HTTP Response with 201 Status Code, registered user object and no error (200 if the request
+	   has been a replay of a registration that has just succeeded, e.g. a double-click) */
+	status := http.StatusCreated
+	if !created {
+		status = http.StatusOK
+	}
+	utils.WriteJSON(w, status, resp, nil)
 
 }
+
+/* GET /me/permissions Handler ----------------------------------------------------------------------------------*/
+/* Capabilities of the caller, read from the JWT Token claims in the context (see services.UserService.Permissions) */
+func (h *UserHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	/* 1. Get the User ID and Role injected in the context by the JWT middleware */
+	userID, _ := r.Context().Value(middleware.UserIDKey).(int)
+	role, _ := r.Context().Value(middleware.UserRoleKey).(string)
+	/* 2. Return the capabilities of the role */
+	utils.WriteJSON(w, http.StatusOK, h.Service.Permissions(userID, role), nil)
+}
+
+/* GET /me/export Handler ---------------------------------------------------------------------------------------*/
+/* >>>>>> SWAGGER <<<<<<< */
+// @Summary Export my data
+// @Description Returns all the data of the authenticated user in one JSON document (GDPR data portability):
+// @Description profile, owned books, favorites and reviews. Sent as an attachment (bookapi-export.json).
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.SuccessResponse{data=models.UserExport}
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /me/export [get]
+func (h *UserHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	/* 1. Extract the user ID from the JWT token + Error Handling via Helper Function 	>>>>>> JWT <<<<<<< */
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		utils.WriteSafeError(w, http.StatusUnauthorized, "Unauthorized")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 2. Assemble the export via services/ method + Error Handling */
+	export, err := h.Export.ExportUser(r.Context(), userID)
+	if errors.Is(err, services.ErrUserNotFound) {
+		utils.WriteSafeError(w, http.StatusNotFound, "User Not Found.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	if err != nil {
+		logger.Errorf("exporting the data of user %d: %v", userID, err)
+		utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Export the Data.")
+		return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+	}
+	/* 3. Return the export as a JSON attachment (see IMPORTANT NOTES 4.) */
+	w.Header().Set("Content-Disposition", `attachment; filename="bookapi-export.json"`)
+	utils.WriteJSON(w, http.StatusOK, export, nil)
+}
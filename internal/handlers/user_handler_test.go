@@ -0,0 +1,112 @@
+package handlers
+
+// handlers/ PACKAGE TESTS ****************************************************************************************
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	/* INTERNAL Packages */
+	"bookapi/internal/middleware"
+	"bookapi/internal/models"
+	"bookapi/internal/repositories"
+	"bookapi/internal/services"
+
+	/* EXTERNAL Packages */
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// 2. TEST DOUBLES ************************************************************************************************
+
+/* Book data of the exported user - embeds the interface so that only the methods read by the export are written */
+type exportBookRepository struct {
+	repositories.BookRepository
+}
+
+func (exportBookRepository) FindByOwner(ctx context.Context, ownerID int) ([]models.Book, error) {
+	return []models.Book{{ID: 1, Title: "Dune", Author: "Frank Herbert", Pages: 412, OwnerID: ownerID}}, nil
+}
+
+func (exportBookRepository) FindFavorites(ctx context.Context, userID int) ([]models.Book, error) {
+	return []models.Book{{ID: 2, Title: "Emma", Author: "Jane Austen", Pages: 474}}, nil
+}
+
+func (exportBookRepository) FindReviewsByUser(ctx context.Context, userID int) ([]models.Review, error) {
+	return []models.Review{{ID: 3, BookID: 2, UserID: userID, Rating: 5}}, nil
+}
+
+// 3. TESTS *******************************************************************************************************
+
+/* TESTER for GET /me/export: profile, books, favorites, reviews AND the whole audit trail ---------------------*/
+func TestGetExportEndPoint(t *testing.T) {
+	/* 1. User 7 and their audit trail on the fake Database (see admin_handler_test.go) */
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := fakeDB(t, func(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+		switch {
+		case strings.HasPrefix(query, "SELECT id, role, email, active FROM users"):
+			return []string{"id", "role", "email", "active"},
+				[][]driver.Value{{int64(7), models.RoleUser, "reader@example.com", true}}, nil
+		case strings.Contains(query, "FROM audit_log WHERE user_id = $1"):
+			if limit := args[1].Value.(int64); limit != 0 {
+				return nil, nil, errors.New("fake DB: expected every entry (limit 0), got a page")
+			}
+			columns := []string{"id", "user_id", "action", "resource", "resource_id", "impersonated_by", "created_at"}
+			return columns, [][]driver.Value{
+				{int64(12), int64(7), "update", "book", int64(1), int64(1), created.Add(time.Hour)},
+				{int64(11), int64(7), "create", "book", int64(1), int64(0), created},
+			}, nil
+		}
+		return nil, nil, errors.New("fake DB: unexpected query " + query)
+	})
+	userRepo, err := repositories.NewUserRepository(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditRepo, err := repositories.NewAuditRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewUserHandler(services.NewUserService(userRepo, "", 4, 0, ""),
+		services.NewExportService(userRepo, exportBookRepository{}, auditRepo), nil, true)
+	cfg := testConfig()
+	r := chi.NewRouter()
+	r.Use(middleware.JWTAuth(cfg.JWTSecret, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTLeeway))
+	handler.RegisterProtectedRoutes(r)
+	token, err := testToken(7, models.RoleUser)
+	if err != nil {
+		t.Fatalf("Error in Generating the Authorization Token")
+	}
+
+	/* 2. Send the request */
+	req := httptest.NewRequest(http.MethodGet, "/me/export", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected Status 200, got %d (%s)", rec.Code, rec.Body.String())
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, "attachment") {
+		t.Errorf("Expected the export as an attachment, got %q", disposition)
+	}
+
+	/* 3. Every part of the user's data is in the document, the audit trail newest first */
+	export := decodeNestedJSON[models.UserExport](t, rec.Body)
+	if export.Profile.ID != 7 || export.Profile.Email != "reader@example.com" {
+		t.Errorf("Expected the profile of user 7, got %+v", export.Profile)
+	}
+	if len(export.Books) != 1 || len(export.Favorites) != 1 || len(export.Reviews) != 1 {
+		t.Errorf("Expected 1 book, 1 favorite and 1 review, got %d, %d and %d", len(export.Books),
+			len(export.Favorites), len(export.Reviews))
+	}
+	if len(export.Activity) != 2 || export.Activity[0].ID != 12 || export.Activity[0].ImpersonatedBy != 1 ||
+		export.Activity[1].Action != "create" {
+		t.Errorf("Expected the 2 audit entries of user 7, newest first, got %+v", export.Activity)
+	}
+}
@@ -0,0 +1,78 @@
+package logger
+
+// logger/ PACKAGE ************************************************************************************************
+/* The logger/ package provides a tiny LEVELED LOGGER on top of the standard log package, so that the amount of
+   output can be tuned per environment via the LOG_LEVEL environment variable (e.g. INFO in production, DEBUG in
+   development) instead of printing everything all the time. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Levels
+	- DEBUG < INFO < WARN < ERROR: a message gets printed only if its level is >= the configured one.
+   2. Goroutine Safety
+	- The configured level is stored in an atomic.Int32 since it's read by every request (i.e. every goroutine)
+	  and may be set at startup while other goroutines are already running (e.g. the profiler).
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// 2. LEVELS ******************************************************************************************************
+
+/* Level of a log message */
+type Level int32
+
+const (
+	DEBUG Level = iota
+	INFO
+	WARN
+	ERROR
+)
+
+/* Names of the levels, used both as prefixes of the messages and as LOG_LEVEL values */
+var levelNames = map[Level]string{DEBUG: "DEBUG", INFO: "INFO", WARN: "WARN", ERROR: "ERROR"}
+
+/* Currently configured level (INFO by default) */
+var current atomic.Int32
+
+func init() {
+	current.Store(int32(INFO))
+}
+
+/* Convert a level name (case insensitive) into a Level + Error Handling */
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(strings.TrimSpace(name), levelName) {
+			return level, nil
+		}
+	}
+	return INFO, fmt.Errorf("invalid log level %q (expected DEBUG, INFO, WARN or ERROR)", name)
+}
+
+/* Set the minimum level of the messages that get printed */
+func SetLevel(level Level) {
+	current.Store(int32(level))
+}
+
+/* Whether messages of the input level get printed */
+func Enabled(level Level) bool {
+	return level >= Level(current.Load())
+}
+
+// 3. LOGGING METHODS *********************************************************************************************
+
+/* Print the message with its level as prefix, if enabled */
+func logf(level Level, format string, args ...any) {
+	if Enabled(level) {
+		log.Printf(levelNames[level]+" "+format, args...)
+	}
+}
+
+func Debugf(format string, args ...any) { logf(DEBUG, format, args...) }
+func Infof(format string, args ...any)  { logf(INFO, format, args...) }
+func Warnf(format string, args ...any)  { logf(WARN, format, args...) }
+func Errorf(format string, args ...any) { logf(ERROR, format, args...) }
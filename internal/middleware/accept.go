@@ -0,0 +1,89 @@
+package middleware
+
+// middleware/ PACKAGE ************************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Content Negotiation
+	- The API only produces the media types listed in SupportedMediaTypes: a client whose Accept header matches
+	  none of them (e.g. "Accept: text/html") gets 406 Not Acceptable instead of a JSON it didn't ask for.
+	- A missing/empty Accept header means "anything". Wildcards are honored: the full wildcard matches every type and
+	  "application/*" every application type. Media ranges with q=0 are explicitly NOT acceptable.
+   2. Exempted Routes
+	- The Swagger UI (/swagger/...) serves HTML pages and assets to the browsers.
+*/
+
+// 1. IMPORT PACKAGES *************************************************************************************************
+import (
+	"bookapi/internal/utils"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 2. GO STRUCTS and UTILITY VARIABLES  *******************************************************************************
+
+/* Media types the API can respond with - the ONLY place to update when a new response format gets added */
+var SupportedMediaTypes = []string{
+	"application/json",         /* every endpoint */
+	"application/problem+json", /* errors, see problem.go */
+	"application/schema+json",  /* GET /books/schema */
+	"application/atom+xml",     /* GET /books/feed.atom */
+	"application/rss+xml",      /* GET /books/feed.rss */
+	"text/csv",                 /* GET /admin/audit/export */
+}
+
+/* Whether the input media range (e.g. "application/*") matches at least one supported media type */
+func mediaRangeSupported(mediaRange string) bool {
+	for _, supported := range SupportedMediaTypes {
+		typ, _, _ := strings.Cut(supported, "/")
+		if mediaRange == "*/*" || mediaRange == typ+"/*" || mediaRange == supported {
+			return true
+		}
+	}
+	return false
+}
+
+/* Whether the input Accept header can be satisfied by the supported media types (see IMPORTANT NOTES 1.) */
+func acceptable(accept string) bool {
+	/* 1. No preference -> anything goes */
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+	/* 2. Look for one media range that is both wanted (q > 0) and supported. Unparsable ranges are skipped. */
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if weight, err := strconv.ParseFloat(q, 64); err != nil || weight <= 0 {
+				continue
+			}
+		}
+		if mediaRangeSupported(mediaRange) {
+			return true
+		}
+	}
+	return false
+}
+
+// 3. CUSTOM http.Handlers ********************************************************************************************
+
+/* ACCEPT HEADER Middleware -----------------------------------------------------------------------------------------*/
+/* Returns 406 Not Acceptable when the Accept header of the request matches none of the SupportedMediaTypes */
+func AcceptCheck(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		/* 1. Reject the request if none of the accepted media types can be produced (Swagger UI excluded) */
+		if !strings.HasPrefix(r.URL.Path, "/swagger/") && !acceptable(r.Header.Get("Accept")) {
+			utils.WriteSafeError(w, http.StatusNotAcceptable,
+				"Not Acceptable. Supported media types: "+strings.Join(SupportedMediaTypes, ", "))
+			return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+		}
+		/* 2. Continue handling the HTTP Requests with the next registered middleware */
+		next.ServeHTTP(w, r)
+	})
+}
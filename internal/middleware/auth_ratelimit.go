@@ -0,0 +1,85 @@
+package middleware
+
+// middleware/ PACKAGE ************************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Why a Separate Limiter
+	- POST /login and POST /register are the targets of brute-force and credential-stuffing attacks, so they get
+	  a much stricter limit (e.g. 10 requests/min/IP) than the rest of the API.
+	- Every AuthRateLimit keeps its OWN bucket map: normal API traffic never counts against the login attempts
+	  (and the other way round). The global rate limit still applies on top of it.
+   2. Fixed Window
+	- The window of a client starts with its first request and is NOT extended by the following ones: once the
+	  limit is hit, the client gets 429 (with Retry-After) until the window started by its first request ends.
+	- The Retry-After gets a random jitter on top (see setRetryAfter in ratelimit.go): clients limited at the
+	  same instant would otherwise all retry at the same instant.
+   3. Keyed by IP only
+	- These routes are anonymous: there's no User ID in the context yet.
+*/
+
+// 1. IMPORT PACKAGES *************************************************************************************************
+import (
+	"bookapi/internal/logger"
+	"bookapi/internal/utils"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 2. GO STRUCTS and UTILITY VARIABLES  *******************************************************************************
+
+/* Requests Tracker of one client within the current window - Go Struct */
+type authRateLimitEntry struct {
+	WindowStart time.Time
+	Count       int
+}
+
+// 3. CUSTOM http.Handlers ********************************************************************************************
+
+/* AUTH RATE-LIMIT Middleware --------------------------------------------------------------------------------------*/
+/*
+Higher-order function returning a middleware that allows at most limit requests per window per IP address to the
+routes it's registered on (see IMPORTANT NOTES), adding up to jitter to the Retry-After of its 429 Responses.
+A limit of 0 disables it.
+*/
+func AuthRateLimit(limit int, window, jitter time.Duration) func(http.Handler) http.Handler {
+	/* 1. Bucket map (and its lock) owned by this limiter only */
+	var (
+		buckets = make(map[string]*authRateLimitEntry)
+		lock    sync.Mutex
+	)
+	/* 2. Wrap the original handler (next) with the rate-limiting logic. */
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		/* 3. Actual Handler Function that runs for every registered HTTP request. */
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 1. Count the request within the window of the client, starting a new window if the last one ended */
+			key := clientIP(r)
+			now := time.Now()
+			lock.Lock()
+			entry, exists := buckets[key]
+			if !exists || now.Sub(entry.WindowStart) >= window {
+				entry = &authRateLimitEntry{WindowStart: now}
+				buckets[key] = entry
+			}
+			entry.Count++
+			count, retryAfter := entry.Count, entry.WindowStart.Add(window).Sub(now)
+			lock.Unlock()
+
+			/* 2. If the requests count exceeds the limit, log the hit and send back 429 via Helper Function */
+			if count > limit {
+				logger.Warnf("auth rate limit exceeded for ip:%s on %s %s", key, r.Method, r.URL.Path)
+				setRetryAfter(w, retryAfter, jitter)
+				utils.WriteSafeError(w, http.StatusTooManyRequests, "Too many attempts, please retry later.")
+				return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+			}
+			/* 3. If the request is within the limit, pass it to the next handler. */
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+// middleware/ PACKAGE **********************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Fast Failure
+	- While a Database circuit breaker is open (see the breaker/ package) the requests would only fail after their
+	  queries do, hence they get rejected straight away with 503 and a Retry-After equal to the cooldown.
+   2. Exempted Routes
+	- It's only registered on the routes using the Database (see the router): the static ones never get a 503.
+	- The /health probes keep going through: they must report the outage themselves (e.g. /health/ready -> 503),
+	  and /health/live must stay 200 since restarting the instance wouldn't fix the Database.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"bookapi/internal/breaker"
+	"bookapi/internal/utils"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 3. CUSTOM http.Handlers ****************************************************************************************
+
+/* DB CIRCUIT BREAKER Middleware ------------------------------------------------------------------------------- */
+/* Higher-order function returning 503 while any of the input breakers is open (nil breakers are disabled ones) */
+func DBCircuitBreaker(breakers ...*breaker.Breaker) func(http.Handler) http.Handler {
+	/* 1. Wrap the original handler (next) with the breaker-checking logic. */
+	return func(next http.Handler) http.Handler {
+		/* 2. Actual Handler Function that runs for every registered HTTP request. */
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 1. Reject the request if a breaker is open, unless it's a probe (see IMPORTANT NOTES 2.) */
+			if !strings.HasPrefix(r.URL.Path, "/health") {
+				for _, b := range breakers {
+					if b.Open() {
+						retryAfter := int(math.Ceil(b.Cooldown().Seconds()))
+						w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+						utils.WriteSafeError(w, http.StatusServiceUnavailable, "Database unavailable, please retry later.")
+						return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+					}
+				}
+			}
+			/* 2. Let the request continue */
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+// middleware/ PACKAGE TESTS **************************************************************************************
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"bookapi/internal/breaker"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// 2. TEST DOUBLES ************************************************************************************************
+
+/* Connector of an unreachable Database */
+type downConnector struct{}
+
+func (downConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("connection refused")
+}
+func (downConnector) Driver() driver.Driver { return nil }
+
+// 3. TESTS *******************************************************************************************************
+
+/* TESTER for the DB circuit breaker: fast 503 while open, but for the probes -----------------------------------*/
+func TestDBCircuitBreaker(t *testing.T) {
+	/* 1. Open the breaker with one failed connection attempt */
+	b := breaker.New("test", 1, time.Minute, 1)
+	handler := DBCircuitBreaker(b)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	if rec := send("/books"); rec.Code != http.StatusOK {
+		t.Fatalf("Expected Status 200 while closed, got %d", rec.Code)
+	}
+	if _, err := b.Connector(downConnector{}).Connect(context.Background()); err == nil || !b.Open() {
+		t.Fatalf("Expected the failed connection attempt to open the breaker")
+	}
+
+	/* 2. Open: 503 with the cooldown as Retry-After, the probes go through */
+	if rec := send("/books"); rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected Status 503 with Retry-After 60, got %d (%q)", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := send("/health/ready"); rec.Code != http.StatusOK {
+		t.Errorf("Expected the probe to go through, got %d", rec.Code)
+	}
+}
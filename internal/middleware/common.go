@@ -20,13 +20,18 @@ package middleware
 	 ...NOT if they are http.HandlerFuncs!!
 		> Register GLOBALLY -> r.Use(requestLogger)
 		> Register LOCALLY 	-> r.With(requestLogger).Get/Post/Put/Patch/Delete(...)
+   3. Wildcard CORS Origin
+   - CORS_ALLOWED_ORIGINS=* lets ANY website call the API from the browsers of its visitors: fine for a public API
+	 authenticated via the Authorization header, a risk otherwise. CorsMiddleware warns about it at startup.
+   - Browsers reject "Access-Control-Allow-Origin: *" on credentialed requests (cookies, HTTP auth): should
+	 credentials ever be allowed, the origins must be listed explicitly, and the wildcard turned into a startup error.
 */
 
 // 1. IMPORT PACKAGES *********************************************************************************************
 import (
 	"bookapi/internal/config"
+	"bookapi/internal/logger"
 	"bookapi/internal/utils"
-	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -61,12 +66,12 @@ func requestLoggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		/* 1. Get the current time and print HTTP Method infos in the Console */
 		startTime := time.Now()
-		log.Printf("Started HTTP Request %s %s", r.Method, r.URL.Path)
+		logger.Debugf("Started HTTP Request %s %s", r.Method, r.URL.Path)
 		/* 2. RUN THE CORE/BASE HTTP.HANDLERFUNC */
 		next(w, r)
 		/* 3. Get the duration time to handle the HTTP Response and print it in the Console */
 		durationTime := time.Since(startTime)
-		log.Printf("Completed %s %s in %v \n\n", r.Method, r.URL.Path, durationTime)
+		logger.Debugf("Completed %s %s in %v", r.Method, r.URL.Path, durationTime)
 	}
 }
 
@@ -77,7 +82,7 @@ func recoveryMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		/* 2. Recover from any panic */
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("Recovered from panic: %v", err)
+				logger.Errorf("Recovered from panic: %v", err)
 				utils.WriteSafeError(w, http.StatusInternalServerError, "Internal Server Error")
 			}
 		}()
@@ -92,8 +97,8 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		/* 1. Set Allowed Origins for HTTP Requests - Any of them in this case. */
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		/* 2. Set Allowed HTTP Methods for HTTP Requests - Any apart from PATCH in this case */
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		/* 2. Set Allowed HTTP Methods for HTTP Requests - Any in this case */
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		/* 3. Set Allowed Headers for HTTP Requests - Content-Type in this case */
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		/* 4. If HTTP Method OPTIONS is used, return empty HTTP Response */
@@ -111,7 +116,7 @@ func userAgentLogMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	/* 1. Return a new http.HandlerFunc object wrapping around the input one (next) */
 	return func(w http.ResponseWriter, r *http.Request) {
 		/* 2. Print the User Agent of the HTTP Request in the Console window */
-		log.Printf("User-Agent: %s", r.Header.Get("User-Agent"))
+		logger.Debugf("User-Agent: %s", r.Header.Get("User-Agent"))
 		/* 3. RUN THE CORE/BASE HTTP.HANDLERFUNC */
 		next(w, r)
 	}
@@ -150,6 +155,40 @@ func AuthMiddleware(next http.Handler) http.Handler { /*				 		  >>>>>>>>> CHI R
 	})
 }
 
+/* BLOCKED USER AGENTS Middleware -----------------------------------------------------------------------------*/
+/*
+Enforcement counterpart of userAgentLogMiddleware: returns 403 when the User-Agent of the request contains (case
+insensitive) any of the comma-separated substrings of the input list (BLOCKED_USER_AGENTS, e.g. "sqlmap,nikto").
+An empty list disables it. Requests with no User-Agent at all are let through.
+*/
+func BlockUserAgents(blocked string) func(http.Handler) http.Handler { /*			  >>>>>>>>> CHI Router <<<<<<<<*/
+	/* 1. Normalize the list once: lower case, no blanks, no empty entries */
+	var substrings []string
+	for _, s := range strings.Split(blocked, ",") {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			substrings = append(substrings, s)
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		if len(substrings) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 2. Reject the request if its User-Agent contains a blocked substring */
+			userAgent := strings.ToLower(r.Header.Get("User-Agent"))
+			for _, s := range substrings {
+				if userAgent != "" && strings.Contains(userAgent, s) {
+					logger.Debugf("blocked User-Agent %q on %s %s", r.Header.Get("User-Agent"), r.Method, r.URL.Path)
+					utils.WriteSafeError(w, http.StatusForbidden, "Forbidden")
+					return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+				}
+			}
+			/* Execute the next/inner http.Handler */
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 /* REQUEST LOGGER Middleware ---------------------------------------------------------------------------------- */
 /*
 http.Handler version of the http.HandlerFunc requestLoggingMiddleware.
@@ -157,11 +196,11 @@ http.Handler version of the http.HandlerFunc requestLoggingMiddleware.
 func RequestLogger(next http.Handler) http.Handler { /*				 		  	  >>>>>>>>> CHI Router <<<<<<<<*/
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		log.Printf("Started %s %s", r.Method, r.URL.Path)
+		logger.Debugf("Started %s %s", r.Method, r.URL.Path)
 		/* Execute the next/inner http.Handler */
 		next.ServeHTTP(w, r) /* Equivalent to next(w,r) with next http.HandlerFunc !! */
 		duration := time.Since(start)
-		log.Printf("Completed %s in %v", r.URL.Path, duration)
+		logger.Debugf("Completed %s in %v", r.URL.Path, duration)
 	})
 }
 
@@ -170,10 +209,21 @@ func RequestLogger(next http.Handler) http.Handler { /*				 		  	  >>>>>>>>> CHI
 http.Handler version of the http.HandlerFunc corsMiddleware.
 */
 func CorsMiddleware(cfg config.Config) func(http.Handler) http.Handler { /* >>>>  CONFIG-DRIVEN CORS SETUP <<<< */
+	/* Runs once, at startup: flag the wildcard origin (see IMPORTANT NOTES 3.) */
+	for _, origin := range strings.Split(cfg.CorsAllowedOrigins, ",") {
+		if strings.TrimSpace(origin) == "*" {
+			logger.Warnf("CORS_ALLOWED_ORIGINS contains \"*\": any website can call this API from its visitors' " +
+				"browsers, and browsers would reject credentialed requests (list the allowed origins explicitly instead)")
+			break
+		}
+	}
 	return func(next http.Handler) http.Handler {
 		return cors.New(cors.Options{
 			AllowedOrigins: strings.Split(cfg.CorsAllowedOrigins, ","),
 			AllowedMethods: strings.Split(cfg.CorsAllowedMethods, ","),
+			/* The rs/cors defaults plus the headers choosing the key case of the JSON Responses and the answer to
+			   the empty lists */
+			AllowedHeaders: []string{"Accept", "Content-Type", "X-Requested-With", JSONCaseHeader, utils.EmptyListHeader},
 		}).Handler(next)
 	}
 }
@@ -0,0 +1,58 @@
+package middleware
+
+// middleware/ PACKAGE **********************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Semaphore
+	- The limit is a buffered channel of capacity N: a request goes through only if it manages to put a token in
+	  the channel (without waiting) and takes it out when done. A full channel means N requests are already in
+	  flight, so the new one gets 503 straight away instead of queueing up and piling load on the Database.
+   2. Shared Limit
+	- Every route registered with the SAME returned middleware shares the same N slots, e.g.
+		> limit := middleware.ConcurrencyLimit(4)
+		> r.With(limit).Get("/stats/a", ...)
+		> r.With(limit).Get("/stats/b", ...)
+   3. Rate Limit vs Concurrency Limit
+	- The rate limiter (ratelimit.go) caps the requests per client per period, this one the requests being served
+	  at the same time by all clients together, however fast each of them is.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"bookapi/internal/logger"
+	"bookapi/internal/utils"
+	"net/http"
+)
+
+// 2. CUSTOM http.Handlers ****************************************************************************************
+
+/* CONCURRENCY-LIMIT Middleware -------------------------------------------------------------------------------- */
+/* Higher-order function that takes the max number of concurrent requests (0 or less disables the limit) and
+   returns a middleware function letting at most n requests through at the same time. */
+func ConcurrencyLimit(n int) func(http.Handler) http.Handler {
+	/* 1. No limit -> return the original handler as it is */
+	if n <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	/* 2. Create the semaphore shared by every request going through the returned middleware */
+	semaphore := make(chan struct{}, n)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 3. Try to acquire a slot without waiting: if they are all taken, tell the client to retry later */
+			select {
+			case semaphore <- struct{}{}:
+			default:
+				logger.Warnf("Concurrency limit (%d) reached: %s %s", n, r.Method, r.URL.Path)
+				w.Header().Set("Retry-After", "1")
+				utils.WriteSafeError(w, http.StatusServiceUnavailable, "Too many concurrent requests, please retry later.")
+				return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+			}
+			/* 4. Release the slot when the request has been served (even if the handler panics) */
+			defer func() { <-semaphore }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+// middleware/ PACKAGE ************************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Content-Length Mismatch
+- When a client declares a Content-Length bigger than the Body it actually sends, the JSON decoder of the
+  handlers fails with a confusing "unexpected EOF" error.
+- The middleware below counts the bytes of the Body AS the handler reads them (nothing gets buffered, so the size
+  of the Body is not limited here): a Body ending before the declared length, or going past it, makes the read
+  fail with utils.ErrMalformedBody, which utils.WriteDecodeError turns into a clear 400 "malformed request body".
+*/
+
+// 1. IMPORT PACKAGES *************************************************************************************************
+import (
+	"bookapi/internal/utils"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// 2. GO STRUCTS ******************************************************************************************************
+
+/* Body Wrapper - Go Struct */
+/* Checks the size of the Body against the declared Content-Length while it gets read */
+type lengthCheckedBody struct {
+	io.ReadCloser
+	remaining int64 /* Bytes still expected according to the Content-Length */
+}
+
+func (b *lengthCheckedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	switch {
+	case b.remaining < 0:
+		return n, utils.ErrMalformedBody /* More bytes than declared */
+	case errors.Is(err, io.ErrUnexpectedEOF), err == io.EOF && b.remaining > 0:
+		return n, utils.ErrMalformedBody /* Fewer bytes than declared */
+	}
+	return n, err
+}
+
+// 3. CUSTOM http.Handlers ********************************************************************************************
+
+/* CONTENT-LENGTH CHECK Middleware ----------------------------------------------------------------------------------*/
+/* Makes the reads of the Body fail with utils.ErrMalformedBody when it doesn't match the declared Content-Length */
+func ContentLengthCheck(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		/* 1. Nothing to check if no length has been declared (-1 = unknown, e.g. chunked) or the Body is empty */
+		if r.ContentLength <= 0 || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		/* 2. Hand the Body over to the next handler wrapped, so that it gets checked while being read */
+		r.Body = &lengthCheckedBody{ReadCloser: r.Body, remaining: r.ContentLength}
+		next.ServeHTTP(w, r)
+	})
+}
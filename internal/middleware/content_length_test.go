@@ -0,0 +1,97 @@
+package middleware
+
+// middleware/ PACKAGE TESTS **************************************************************************************
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"bookapi/internal/utils"
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// 2. TESTS *******************************************************************************************************
+
+/* TESTER for the Content-Length check: 400 on mismatching bodies, no size limit on the matching ones ----------*/
+func TestContentLengthCheck(t *testing.T) {
+	/* 1. Handler decoding the Body as the JSON handlers do */
+	handler := ContentLengthCheck(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := utils.DecodeJSON(r.Body, &body, false); err != nil {
+			utils.WriteDecodeError(w, err, "Invalid Inputs.")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(body string, declared int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/books", strings.NewReader(body))
+		req.ContentLength = declared
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	body := `{"title": "Dune", "pages": 412}`
+
+	/* 2. Matching, undeclared (-1) and mismatching lengths */
+	for _, tc := range []struct {
+		name     string
+		declared int64
+		expected int
+	}{
+		{"matching", int64(len(body)), http.StatusOK},
+		{"unknown", -1, http.StatusOK},
+		{"shorter body", int64(len(body)) + 10, http.StatusBadRequest},
+		{"longer body", int64(len(body)) - 5, http.StatusBadRequest},
+	} {
+		rec := send(body, tc.declared)
+		if rec.Code != tc.expected {
+			t.Errorf("%s: Expected Status %d, got %d", tc.name, tc.expected, rec.Code)
+		}
+		if tc.expected == http.StatusBadRequest && !strings.Contains(rec.Body.String(), "malformed request body") {
+			t.Errorf("%s: Expected the malformed request body message, got %s", tc.name, rec.Body.String())
+		}
+	}
+
+	/* 3. The Body is streamed, not buffered: a body of several MB goes through untouched */
+	large := `{"description": "` + string(bytes.Repeat([]byte("a"), 8<<20)) + `"}`
+	if rec := send(large, int64(len(large))); rec.Code != http.StatusOK {
+		t.Errorf("Expected Status 200 for a large matching body, got %d", rec.Code)
+	}
+}
+
+/* TESTER for a Body cut short on the wire (the server reads io.ErrUnexpectedEOF) ------------------------------*/
+func TestContentLengthCheck_TruncatedOnTheWire(t *testing.T) {
+	server := httptest.NewServer(ContentLengthCheck(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := utils.DecodeJSON(r.Body, &body, false); err != nil {
+			utils.WriteDecodeError(w, err, "Invalid Inputs.")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})))
+	defer server.Close()
+
+	/* The client declares 100 bytes, sends 15 and stops writing */
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("POST /books HTTP/1.1\r\nHost: test\r\nContent-Type: application/json\r\n" +
+		"Content-Length: 100\r\n\r\n{\"title\": \"Dune"))
+	conn.(*net.TCPConn).CloseWrite()
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(message), "malformed request body") {
+		t.Errorf("Expected Status 400 malformed request body, got %d (%s)", resp.StatusCode, message)
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+// middleware/ PACKAGE ************************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Daily Quota vs Rate Limit
+	- The rate limiters (ratelimit.go, auth_ratelimit.go) smooth out bursts within short windows. This one caps how
+	  many times a user can perform an operation per CALENDAR DAY (UTC), e.g. the page transfers: once the cap is
+	  hit, the user gets 429 (with Retry-After) until midnight UTC.
+   2. Only Successful Requests Count
+	- A slot is taken BEFORE calling the handler (so that concurrent requests can't go over the cap) and given back
+	  if the handler answers with an error status: invalid or failed transfers don't eat into the quota.
+   3. In-Memory Counters
+	- The counters live in the memory of this instance (keyed by User ID and day) and are dropped when the day
+	  changes: with several instances each of them enforces its own cap, and a restart resets them.
+   4. Exempt Roles
+	- Users with one of the exempt roles (e.g. admin) are never counted. NOTE: the transfer routes are currently
+	  admin-only (models.TransferRoles) while admins are exempt, so TRANSFER_DAILY_LIMIT has NO effect yet: it only
+	  kicks in once other roles get added to models.TransferRoles.
+*/
+
+// 1. IMPORT PACKAGES *************************************************************************************************
+import (
+	"bookapi/internal/logger"
+	"bookapi/internal/utils"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 2. UTILITY VARIABLES ***********************************************************************************************
+
+/* Clock of the daily quotas, replaced by the tests to move to another day */
+var quotaNow = time.Now
+
+// 3. CUSTOM http.Handlers ********************************************************************************************
+
+/* DAILY QUOTA Middleware ------------------------------------------------------------------------------------------*/
+/*
+Higher-order function returning a middleware that allows at most limit successful requests per user per UTC day to
+the routes it's registered on (see IMPORTANT NOTES), but for the users with one of the exempt roles. It must run
+after JWTAuth (it needs the User ID). A limit of 0 disables it.
+*/
+func DailyQuota(limit int, exemptRoles ...string) func(http.Handler) http.Handler {
+	/* 1. Counters of the current day (and their lock) owned by this quota only */
+	var (
+		counts = make(map[int]int)
+		day    string
+		lock   sync.Mutex
+	)
+	exempt := make(map[string]struct{}, len(exemptRoles))
+	for _, role := range exemptRoles {
+		exempt[role] = struct{}{}
+	}
+	/* 2. Wrap the original handler (next) with the quota logic. */
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		/* 3. Actual Handler Function that runs for every registered HTTP request. */
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 1. Exempt roles go straight through */
+			role, _ := r.Context().Value(UserRoleKey).(string)
+			if _, ok := exempt[role]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			/* 2. Take a slot for the user within the current day, dropping the counters of the previous one */
+			userID, _ := r.Context().Value(UserIDKey).(int)
+			now := quotaNow().UTC()
+			lock.Lock()
+			if today := now.Format(time.DateOnly); today != day {
+				counts, day = make(map[int]int), today
+			}
+			counts[userID]++
+			count, countedDay := counts[userID], day
+			lock.Unlock()
+
+			/* 3. If the count exceeds the limit, give the slot back and send back 429 via Helper Function */
+			release := func() {
+				lock.Lock()
+				if day == countedDay && counts[userID] > 0 {
+					counts[userID]--
+				}
+				lock.Unlock()
+			}
+			if count > limit {
+				release()
+				midnight := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+				logger.Warnf("daily quota (%d) exceeded for user:%d on %s %s", limit, userID, r.Method, r.URL.Path)
+				w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(midnight.Sub(now).Seconds())), 1)))
+				utils.WriteSafeError(w, http.StatusTooManyRequests,
+					"Daily limit of "+strconv.Itoa(limit)+" reached, please retry after midnight UTC.")
+				return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+			}
+			/* 4. Pass the request to the next handler, giving the slot back if it fails */
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+			if sw.status >= http.StatusBadRequest {
+				release()
+			}
+		})
+	}
+}
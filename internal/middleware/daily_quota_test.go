@@ -0,0 +1,66 @@
+package middleware
+
+// middleware/ PACKAGE TESTS **************************************************************************************
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// 2. TESTS *******************************************************************************************************
+
+/* TESTER for the daily quota: 429 past the limit, failed requests don't count, reset at midnight UTC ----------*/
+func TestDailyQuota(t *testing.T) {
+	/* 1. A quota of 2 per day, with a clock that can be moved to the next day */
+	now := time.Date(2024, 3, 10, 23, 59, 0, 0, time.UTC)
+	quotaNow = func() time.Time { return now }
+	t.Cleanup(func() { quotaNow = time.Now })
+	status := http.StatusOK
+	handler := DailyQuota(2, "admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	send := func(userID int, role string) *httptest.ResponseRecorder {
+		ctx := context.WithValue(context.Background(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, UserRoleKey, role)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/books/transfer", nil).WithContext(ctx))
+		return rec
+	}
+
+	/* 2. A failed request (4xx) gives its slot back: 2 successful ones still fit, the third gets 429 */
+	status = http.StatusBadRequest
+	if rec := send(7, "user"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected the handler's 400, got %d", rec.Code)
+	}
+	status = http.StatusOK
+	for i := range 2 {
+		if rec := send(7, "user"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: Expected Status 200 within the limit, got %d", i+1, rec.Code)
+		}
+	}
+	rec := send(7, "user")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected Status 429 with Retry-After 60 (to midnight UTC), got %d (%q)", rec.Code,
+			rec.Header().Get("Retry-After"))
+	}
+
+	/* 3. Other users and exempt roles aren't affected */
+	if rec := send(8, "user"); rec.Code != http.StatusOK {
+		t.Errorf("Expected Status 200 for another user, got %d", rec.Code)
+	}
+	for range 3 {
+		if rec := send(1, "admin"); rec.Code != http.StatusOK {
+			t.Errorf("Expected Status 200 for an exempt role, got %d", rec.Code)
+		}
+	}
+
+	/* 4. A new UTC day starts from zero */
+	now = now.Add(2 * time.Minute)
+	if rec := send(7, "user"); rec.Code != http.StatusOK {
+		t.Errorf("Expected Status 200 on the next UTC day, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+// middleware/ PACKAGE ************************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Pool Exhaustion
+	- When all the pooled connections are busy, database/sql makes every new query wait until one gets released,
+	  so requests would hang without any feedback for the client until REQUEST_TIMEOUT.
+   2. Acquisition Timeout
+	- The middleware below gives the request a dbpool.Guard: every connection the handler asks for has to come
+	  within the configured timeout, otherwise the context of the request gets cancelled and the client gets
+	  503 "Service busy" instead of the error the handler would have answered (see the dbpool/ package).
+	- It only applies to the queries of the pools whose connector went through dbpool.Connector, and it's only
+	  registered on the routes that use the Database: the other ones never wait for a connection.
+*/
+
+// 1. IMPORT PACKAGES *************************************************************************************************
+import (
+	"bookapi/internal/dbpool"
+	"bookapi/internal/utils"
+	"database/sql"
+	"net/http"
+	"time"
+)
+
+// 2. GO STRUCTS and UTILITY METHODS  *********************************************************************************
+
+/* Response Writer Wrapper - Go Struct */
+/* Drops the response of the handler once its request has timed out waiting for a connection, so that the
+   middleware can answer 503 instead */
+type acquireWriter struct {
+	http.ResponseWriter
+	guard   *dbpool.Guard
+	wrote   bool /* Something has reached the client */
+	dropped bool /* The handler has answered after the timeout */
+}
+
+func (w *acquireWriter) WriteHeader(status int) {
+	if !w.wrote && w.guard.TimedOut() {
+		w.dropped = true
+		return
+	}
+	if !w.dropped {
+		w.wrote = true
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *acquireWriter) Write(b []byte) (int, error) {
+	if !w.wrote && w.guard.TimedOut() {
+		w.dropped = true
+	}
+	if w.dropped {
+		return len(b), nil
+	}
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+/* Give access to the wrapped http.ResponseWriter (e.g. to the error helpers in utils) */
+func (w *acquireWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// 3. CUSTOM http.Handlers ********************************************************************************************
+
+/* DB ACQUISITION TIMEOUT Middleware --------------------------------------------------------------------------------*/
+/* Higher-order function returning 503 when a query of the handler doesn't get a connection from the input pools
+   within the input timeout (see IMPORTANT NOTES). A timeout of 0 disables the middleware. */
+func DBAcquireTimeout(timeout time.Duration, pools ...*sql.DB) func(http.Handler) http.Handler {
+	/* 1. Wrap the original handler (next) with the acquisition timeout. */
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		/* 2. Actual Handler Function that runs for every registered HTTP request. */
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 1. Bound the wait of every connection the handler asks for */
+			ctx, guard := dbpool.WithGuard(r.Context(), timeout, pools...)
+			defer guard.Stop()
+			/* 2. Execute the handler, keeping its response out if it comes after the timeout */
+			aw := &acquireWriter{ResponseWriter: w, guard: guard}
+			next.ServeHTTP(aw, r.WithContext(ctx))
+			/* 3. Timed out before anything reached the client -> tell the client to retry later */
+			if guard.TimedOut() && !aw.wrote {
+				w.Header().Set("Retry-After", "1")
+				utils.WriteSafeError(w, http.StatusServiceUnavailable, "Service busy, please retry later.")
+				return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+			}
+		})
+	}
+}
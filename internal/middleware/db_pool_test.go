@@ -0,0 +1,99 @@
+package middleware
+
+// middleware/ PACKAGE TESTS **************************************************************************************
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"bookapi/internal/dbpool"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// 2. TEST DOUBLES ************************************************************************************************
+
+/* Minimal database/sql connector whose queries take delay and return no rows (no real Database needed) */
+type slowConnector struct{ delay time.Duration }
+type slowConn struct{ delay time.Duration }
+type noRows struct{}
+
+func (c slowConnector) Connect(context.Context) (driver.Conn, error) { return slowConn(c), nil }
+func (c slowConnector) Driver() driver.Driver                        { return nil }
+func (slowConn) Prepare(string) (driver.Stmt, error)                 { return nil, driver.ErrSkip }
+func (slowConn) Close() error                                        { return nil }
+func (slowConn) Begin() (driver.Tx, error)                           { return nil, driver.ErrSkip }
+func (noRows) Columns() []string                                     { return []string{"n"} }
+func (noRows) Close() error                                          { return nil }
+func (noRows) Next([]driver.Value) error                             { return io.EOF }
+
+func (c slowConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	select {
+	case <-time.After(c.delay):
+		return noRows{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+/* Pool of one single connection whose queries take delay, behind DBAcquireTimeout(timeout) */
+func poolHandler(delay, timeout time.Duration) (*sql.DB, http.Handler) {
+	db := sql.OpenDB(dbpool.Connector(slowConnector{delay: delay}))
+	db.SetMaxOpenConns(1)
+	handler := DBAcquireTimeout(timeout, db)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rows, err := db.QueryContext(r.Context(), "SELECT 1")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rows.Close()
+		w.WriteHeader(http.StatusOK)
+	}))
+	return db, handler
+}
+
+// 3. TESTS *******************************************************************************************************
+
+/* TESTER for DB_ACQUIRE_TIMEOUT: a request waiting for a connection of a full pool gets 503 in time ------------*/
+func TestDBAcquireTimeout_PoolFull(t *testing.T) {
+	timeout := 50 * time.Millisecond
+	db, handler := poolHandler(0, timeout)
+	defer db.Close()
+
+	/* 1. Fill the pool: its only connection is taken by someone else */
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	/* 2. The query of the handler can't get a connection: 503 after the timeout, way before any request deadline */
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books", nil))
+	elapsed := time.Since(start)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected Status 503 with Retry-After, got %d", rec.Code)
+	}
+	if elapsed < timeout || elapsed > 10*timeout {
+		t.Errorf("Expected the 503 after about %s, got it after %s", timeout, elapsed)
+	}
+}
+
+/* TESTER for DB_ACQUIRE_TIMEOUT: a slow query holding the connection is never cut ----------------------------*/
+func TestDBAcquireTimeout_SlowQuery(t *testing.T) {
+	timeout := 50 * time.Millisecond
+	db, handler := poolHandler(3*timeout, timeout)
+	defer db.Close()
+
+	/* The pool is full for the whole query, but it's the request itself holding the connection */
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/books", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected Status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,121 @@
+package middleware
+
+// middleware/ PACKAGE ************************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Reading the Body of the HTTP Request
+	- r.Body is a stream that can be read ONLY ONCE! After logging it, the middleware has to put back a fresh
+	  reader (io.NopCloser(bytes.NewReader(..))) otherwise the handlers would decode an empty body.
+   2. Secrets
+	- The values of the secret JSON fields (passwords, tokens, invite codes, see secretFieldRegex), any JWT found
+	  anywhere else (e.g. inside the url of a share link) and the Authorization header are ALWAYS replaced with
+	  **** before getting printed: whoever reads the logs must not be able to log in or take over a session.
+	  The redaction works on the raw text so that it also applies to malformed JSON (i.e. exactly the payloads
+	  this middleware is meant to debug).
+   3. Size Limit
+	- Only the first debugBodyLimit bytes of each body get buffered and logged. The rest of the HTTP Request
+	  is streamed to the handlers as it arrives, so that a huge upload can't make this middleware allocate it all.
+*/
+
+// 1. IMPORT PACKAGES *************************************************************************************************
+import (
+	"bookapi/internal/config"
+	"bookapi/internal/logger"
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+)
+
+// 2. GO STRUCTS and UTILITY VARIABLES  *******************************************************************************
+
+/* Regular Expression matching the value of any secret JSON field (*password, *token, *secret, invite_code, code) */
+var secretFieldRegex = regexp.MustCompile(
+	`(?i)("(?:[a-z_]*password|[a-z_]*token|[a-z_]*secret|invite_code|code)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+/* Regular Expression matching a JWT (header.payload.signature, the first two starting with the base64 of '{"') */
+var jwtRegex = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`)
+
+/* Maximum number of bytes of each body that get buffered and logged (see IMPORTANT NOTES 3.) */
+const debugBodyLimit = 64 << 10
+
+/* Response Writer Wrapper - Go Struct */
+/* Keeps a copy of the status code and of the bytes written to the HTTP Response so that they can be logged */
+type bodyCaptureWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+/* Record the status code before passing it to the wrapped http.ResponseWriter */
+func (w *bodyCaptureWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+/* Give access to the wrapped http.ResponseWriter (e.g. to the error helpers in utils) */
+func (w *bodyCaptureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+/* Copy the written bytes (up to debugBodyLimit) before passing them to the wrapped http.ResponseWriter */
+func (w *bodyCaptureWriter) Write(b []byte) (int, error) {
+	if room := debugBodyLimit - w.body.Len(); room > 0 {
+		w.body.Write(b[:min(len(b), room)])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// 3. CUSTOM http.Handlers ********************************************************************************************
+
+/* DEBUG BODY LOGGER Middleware -------------------------------------------------------------------------------------*/
+/*
+Middleware logging the Body of every HTTP Request and of the corresponding HTTP Response.
+It is enabled only when DEBUG_BODIES=true, otherwise the next handler is returned untouched.
+The lines are logged at DEBUG level, hence they also require LOG_LEVEL=DEBUG to show up.
+*/
+func DebugBodyLogger(cfg config.Config) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		/* 1. If body logging is disabled, don't wrap the next handler at all */
+		if !cfg.DebugBodies {
+			return next
+		}
+		/* 2. Actual Handler Function that runs for every registered HTTP request. */
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 3. Buffer the beginning of the Body of the HTTP Request and put it back in front of the rest of the
+			   stream for the next handlers (see IMPORTANT NOTES 1. and 3.) */
+			var reqBody []byte
+			if r.Body != nil {
+				reqBody, _ = io.ReadAll(io.LimitReader(r.Body, debugBodyLimit))
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+			}
+			/* 4. Log the HTTP Request with the Authorization header and the secrets redacted */
+			auth := r.Header.Get("Authorization")
+			if auth != "" {
+				auth = "****"
+			}
+			logger.Debugf("Request %s %s Authorization=%q Body=%s",
+				r.Method, r.URL.Path, auth, redactSecrets(reqBody))
+			/* 5. Execute the next/inner http.Handler capturing the HTTP Response */
+			capture := &bodyCaptureWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(capture, r)
+			/* 6. Log the captured HTTP Response */
+			logger.Debugf("Response %s %s Status=%d Body=%s",
+				r.Method, r.URL.Path, capture.status, redactSecrets(capture.body.Bytes()))
+		})
+	}
+}
+
+// 4. UTILITY METHODS ************************************************************************************************
+
+/* Replace the values of all the secret JSON fields and all the JWTs with **** */
+func redactSecrets(body []byte) []byte {
+	body = secretFieldRegex.ReplaceAll(body, []byte(`$1"****"`))
+	return jwtRegex.ReplaceAll(body, []byte("****"))
+}
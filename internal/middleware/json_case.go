@@ -0,0 +1,49 @@
+package middleware
+
+// middleware/ PACKAGE **********************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. JSON Key Case
+- Same approach as ProblemJSON (see problem.go): nothing changes in the handlers, the middleware below wraps
+  the http.ResponseWriter and utils.WriteJSON converts the keys to camelCase when it finds the wrapper
+  (see utils/json_case.go).
+- The X-JSON-Case header of the HTTP Request ("camel" or "snake") wins over the configured default (JSON_CASE).
+  Any other header value is ignored.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"bookapi/internal/utils"
+	"net/http"
+	"strings"
+)
+
+// 2. CUSTOM http.Handlers ****************************************************************************************
+
+/* Name of the HTTP Request header choosing the key case of the JSON Response */
+const JSONCaseHeader = "X-JSON-Case"
+
+/* JSON KEY CASE Middleware ------------------------------------------------------------------------------------ */
+/* Higher-order function that takes the default key case (utils.JSONCaseSnake or utils.JSONCaseCamel) and returns
+   the middleware function */
+func JSONCase(defaultCase string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 1. Pick the key case: the header if valid, the default otherwise */
+			keyCase := defaultCase
+			if requested := strings.ToLower(strings.TrimSpace(r.Header.Get(JSONCaseHeader))); requested == utils.JSONCaseSnake ||
+				requested == utils.JSONCaseCamel {
+				keyCase = requested
+			}
+			/* 2. Wrap the http.ResponseWriter only if the keys have to be converted */
+			if keyCase == utils.JSONCaseCamel {
+				w = &utils.CamelCaseWriter{ResponseWriter: w}
+			}
+			/* 3. Continue handling the HTTP Requests with the next registered middleware */
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -15,12 +15,14 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type contextKey string
 
 const UserIDKey contextKey = "user_id"
 const UserRoleKey contextKey = "user_role"
+const ImpersonatorKey contextKey = "impersonated_by" /* ID of the admin acting as the user (impersonation tokens only) */
 
 // 2. CUSTOM http.Handlers *********************************************************************************************
 
@@ -31,7 +33,7 @@ The following middleware method carries out the following tasks:
  2. Verify the token's signature and expiration date
  3. Inject the user ID into the request context
 */
-func JWTAuth(secret string) func(http.Handler) http.Handler {
+func JWTAuth(secret, issuer, audience string, leeway time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			/* 1. Get the value of the Authorization Header of the HTTP Request + Error Handling via Helper Function*/
@@ -43,7 +45,7 @@ func JWTAuth(secret string) func(http.Handler) http.Handler {
 			}
 			/* 2. Extract the Token + Check its validity + Error Handling via Helper Function */
 			tokenStr := strings.TrimPrefix(auth, "Bearer")
-			claims, err := security.ParseToken(tokenStr, secret)
+			claims, err := security.ParseToken(tokenStr, secret, issuer, audience, leeway)
 			if err != nil {
 				utils.WriteSafeError(w, http.StatusUnauthorized, "Invalid or expired token.")
 				return
@@ -66,6 +68,10 @@ func JWTAuth(secret string) func(http.Handler) http.Handler {
 			/* 6. Add the user ID and user ROLE to the request's context */
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
 			ctx = context.WithValue(ctx, UserRoleKey, userRole)
+			/* 6b. Surface the admin behind an impersonation token, so that its actions get audited as such */
+			if impersonatedBy, ok := claims["impersonated_by"].(float64); ok {
+				ctx = context.WithValue(ctx, ImpersonatorKey, int(impersonatedBy))
+			}
 			/* 7. Passes the request (enriched with the userID info) to the next handler */
 			next.ServeHTTP(w, r.WithContext(ctx))
 			/*...Now the handler can access the user ID and know who made the request...*/
@@ -24,7 +24,8 @@ package middleware
 
 // 1. IMPORT PACKAGES *********************************************************************************************
 import (
-	"log"
+	"bookapi/internal/logger"
+	"bookapi/internal/utils"
 	"net/http"
 	"time"
 )
@@ -37,10 +38,52 @@ func Logging(next http.Handler) http.Handler { /*				 		  	  	    >>>>>>>>> CHI
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		/* 1. Get the current time and print HTTP Method infos in the Console */
 		start := time.Now()
-		log.Printf("Started %s %s", r.Method, r.URL.Path)
+		logger.Debugf("Started %s %s", r.Method, r.URL.Path)
 		/* 2. Execute the next/inner http.Handler */
 		next.ServeHTTP(w, r)
 		/* 3. Get the duration time to handle the HTTP Response and print it in the Console */
-		log.Printf("Completed in %v", time.Since(start))
+		logger.Debugf("Completed %s %s in %v", r.Method, r.URL.Path, time.Since(start))
+	})
+}
+
+/* SLOW REQUEST Middleware ------------------------------------------------------------------------------------- */
+/* Middleware logging a WARN line for every HTTP Request whose handling takes longer than the input threshold.
+   The Logging middleware above times every request, this one only alerts about the slow ones.
+   A threshold <= 0 disables the alerting. */
+func SlowRequests(threshold time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		/* 1. If the alerting is disabled, don't wrap the next handler at all */
+		if threshold <= 0 {
+			return next
+		}
+		/* 2. Return a new http.Handler that wraps around the input core/base Handler (next) */
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 3. Get the current time and execute the next/inner http.Handler */
+			start := time.Now()
+			next.ServeHTTP(w, r)
+			/* 4. If the duration exceeds the threshold, print a WARN line in the Console */
+			if duration := time.Since(start); duration > threshold {
+				logger.Warnf("slow request %s %s took %v (threshold %v)", r.Method, r.URL.Path, duration, threshold)
+			}
+		})
+	}
+}
+
+/* PANIC RECOVERY Middleware ----------------------------------------------------------------------------------- */
+/* Recovers from any panic raised by the next handlers, logs it at ERROR level and returns 500 to the client */
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		/* 1. Recover from any panic (http.ErrAbortHandler is a deliberate abort: let it through) */
+		defer func() {
+			if err := recover(); err != nil {
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				logger.Errorf("panic serving %s %s: %v", r.Method, r.URL.Path, err)
+				utils.WriteSafeError(w, http.StatusInternalServerError, "Internal Server Error")
+			}
+		}()
+		/* 2. Execute the next/inner http.Handler */
+		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,76 @@
+package middleware
+
+// middleware/ PACKAGE **********************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Runtime Toggle
+	- Maintenance mode can be switched on/off at runtime (POST /admin/maintenance) with no need to restart the
+	  server. The flag is an atomic.Bool: it gets read by every request and written by the admin endpoint from
+	  different goroutines, so plain bool reads/writes would be a data race.
+   2. Exempted Routes
+	- While in maintenance, every request gets 503 EXCEPT POST /login and /admin/maintenance: otherwise an admin
+	  could never log in and switch maintenance mode off again.
+	- The GET /health probes are exempted too: probes failing during maintenance would get the instances restarted.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"bookapi/internal/utils"
+	"net/http"
+	"sync/atomic"
+)
+
+// 2. GO STRUCTS and UTILITY METHODS  *****************************************************************************
+
+/* Maintenance Mode - Go Struct */
+/* Goroutine-safe maintenance flag shared by the middleware below and the admin endpoint */
+type MaintenanceMode struct {
+	enabled atomic.Bool
+}
+
+/* Constructor */
+func NewMaintenanceMode(enabled bool) *MaintenanceMode {
+	m := &MaintenanceMode{}
+	m.enabled.Store(enabled)
+	return m
+}
+
+/* Whether maintenance mode is on */
+func (m *MaintenanceMode) Enabled() bool {
+	return m.enabled.Load()
+}
+
+/* Switch maintenance mode on/off */
+func (m *MaintenanceMode) Set(enabled bool) {
+	m.enabled.Store(enabled)
+}
+
+/* Routes that stay reachable during maintenance (see IMPORTANT NOTES 2.) */
+var maintenanceExempt = map[string]struct{}{
+	"/login":             {},
+	"/admin/maintenance": {},
+	"/health":            {},
+	"/health/live":       {},
+	"/health/ready":      {},
+	"/health/migrations": {},
+}
+
+// 3. CUSTOM http.Handlers ****************************************************************************************
+
+/* MAINTENANCE Middleware -------------------------------------------------------------------------------------- */
+/* Returns 503 to every request (but the exempted ones) while maintenance mode is on */
+func (m *MaintenanceMode) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		/* 1. Let the request continue if maintenance is off or the route is exempted */
+		if _, exempt := maintenanceExempt[r.URL.Path]; !m.Enabled() || exempt {
+			next.ServeHTTP(w, r)
+			return
+		}
+		/* 2. Otherwise, tell the client to retry later */
+		w.Header().Set("Retry-After", "120")
+		utils.WriteSafeError(w, http.StatusServiceUnavailable, "Service under maintenance, please retry later.")
+	})
+}
@@ -0,0 +1,74 @@
+package middleware
+
+// middleware/ PACKAGE **********************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. OriginGuard vs CORS
+	- CORS only tells the BROWSER whether it may read the response: the request still reaches the handler and a
+	  non-browser client can ignore it altogether. OriginGuard rejects the request SERVER-SIDE (403) instead.
+	- It is meant to be registered per route (e.g. webhook-style endpoints) via
+		> r.With(middleware.OriginGuard("https://hooks.example.com")).Post(...)
+	  The /admin routes get it with the origins of TRUSTED_ORIGINS, when set (see admin_handler.go).
+   2. Origin and Referer
+	- The Origin header is checked first. If missing, the origin (scheme://host[:port]) gets extracted from the
+	  Referer header. Requests carrying neither of them are rejected.
+   3. Use of Hash Tables (Sets) - same as AllowRoles (see roles.go)
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"bookapi/internal/utils"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// 2. UTILITY METHODS *********************************************************************************************
+
+/* Normalize an origin for comparison: lower case, no trailing slash */
+func normalizeOrigin(origin string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+}
+
+/* Get the origin of the request from the Origin header or, as a fallback, from the Referer header */
+func requestOrigin(r *http.Request) string {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		return normalizeOrigin(origin)
+	}
+	referer, err := url.Parse(r.Header.Get("Referer"))
+	if err != nil || referer.Scheme == "" || referer.Host == "" {
+		return ""
+	}
+	return normalizeOrigin(referer.Scheme + "://" + referer.Host)
+}
+
+// 3. CUSTOM http.Handlers ****************************************************************************************
+
+/* TRUSTED-ORIGINS Middleware ---------------------------------------------------------------------------------- */
+/* Middleware designed to restrict access to certain HTTP endpoints to requests coming from trusted origins.
+   Higher-order function that takes a list of allowed origins (e.g. "https://app.example.com") and returns a
+   middleware function.*/
+func OriginGuard(allowed ...string) func(http.Handler) http.Handler {
+	/* 1. Create a set (using a map) of allowed origins for fast lookup */
+	originSet := make(map[string]struct{}, len(allowed))
+	for _, origin := range allowed {
+		originSet[normalizeOrigin(origin)] = struct{}{}
+	}
+	/* 2. Wrap the original handler (next) and add origin-checking logic before calling it. */
+	return func(next http.Handler) http.Handler {
+		/* 3. Actual Handler Function that runs for every registered HTTP request. */
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 4. If the origin is missing or not in the Set, return error via Helper Function. */
+			origin := requestOrigin(r)
+			if _, ok := originSet[origin]; origin == "" || !ok {
+				utils.WriteSafeError(w, http.StatusForbidden, "Forbidden: untrusted origin")
+				return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+			}
+			/* 5. If the origin is trusted proceed to call the original handler. */
+			next.ServeHTTP(w, r)
+		})
+	}
+}
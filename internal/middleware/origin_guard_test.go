@@ -0,0 +1,42 @@
+package middleware
+
+// middleware/ PACKAGE TESTS **************************************************************************************
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// 2. TESTS *******************************************************************************************************
+
+/* TESTER for the trusted-origins check: Origin first, Referer as a fallback, 403 otherwise ---------------------*/
+func TestOriginGuard(t *testing.T) {
+	handler := OriginGuard("https://admin.example.com/", " https://ops.example.com")(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+	for _, tc := range []struct {
+		name     string
+		header   string
+		value    string
+		expected int
+	}{
+		{"allowed origin", "Origin", "https://admin.example.com", http.StatusOK},
+		{"allowed origin, other case", "Origin", "HTTPS://OPS.example.com", http.StatusOK},
+		{"allowed referer", "Referer", "https://ops.example.com/admin/users?page=2", http.StatusOK},
+		{"untrusted origin", "Origin", "https://evil.example.com", http.StatusForbidden},
+		{"untrusted referer", "Referer", "https://evil.example.com/https://admin.example.com", http.StatusForbidden},
+		{"other scheme", "Origin", "http://admin.example.com", http.StatusForbidden},
+		{"no origin", "", "", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/admin/maintenance", nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.expected {
+			t.Errorf("%s: Expected Status %d, got %d", tc.name, tc.expected, rec.Code)
+		}
+	}
+}
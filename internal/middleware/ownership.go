@@ -8,6 +8,7 @@ package middleware
 // 1. IMPORT PACKAGES *************************************************************************************************
 import (
 	"bookapi/internal/utils"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -21,6 +22,9 @@ import (
    A function matching this type will be passed to the middleware below. */
 type OwnerLoader func(r *http.Request, resourceID int) (int, error)
 
+/* Error an OwnerLoader can return when the resource doesn't exist, so that the Client gets 404 instead of 500 */
+var ErrResourceNotFound = errors.New("Resource not found.")
+
 // 3. CUSTOM http.Handlers ********************************************************************************************
 
 /* OWNERSHIP-BASED AUTH Middleware ----------------------------------------------------------------------------------*/
@@ -50,6 +54,10 @@ func EnforceOwnership(paramName string, loader OwnerLoader) func(http.Handler) h
 			/* 3. Call the OwnerLoader function to find out who owns the resource + Error Handling
 			via Helper Function */
 			ownerID, err := loader(r, resourceID)
+			if errors.Is(err, ErrResourceNotFound) {
+				utils.WriteSafeError(w, http.StatusNotFound, "Resource not found.")
+				return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+			}
 			if err != nil {
 				utils.WriteSafeError(w, http.StatusInternalServerError, "Could not verify ownership")
 				return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
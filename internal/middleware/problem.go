@@ -0,0 +1,37 @@
+package middleware
+
+// middleware/ PACKAGE ************************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. RFC 7807 Problem Details
+	- Clients sending "Accept: application/problem+json" get errors as {type, title, status, detail, instance}
+	  instead of the usual {error, message} JSON. Nothing changes in the handlers: the middleware below wraps the
+	  http.ResponseWriter and the error helpers in utils switch format when they find the wrapper.
+   2. Response Writer Wrappers
+	- Middlewares that wrap the http.ResponseWriter AFTER this one must expose Unwrap() so that the helpers can
+	  still find the wrapper (see bodyCaptureWriter and deadlineWriter).
+*/
+
+// 1. IMPORT PACKAGES *************************************************************************************************
+import (
+	"bookapi/internal/utils"
+	"net/http"
+	"strings"
+)
+
+// 2. CUSTOM http.Handlers ********************************************************************************************
+
+/* PROBLEM+JSON Middleware ------------------------------------------------------------------------------------------*/
+func ProblemJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		/* 1. Wrap the http.ResponseWriter only if the client accepts problem+json */
+		if strings.Contains(r.Header.Get("Accept"), "application/problem+json") {
+			w = &utils.ProblemWriter{ResponseWriter: w, Instance: r.URL.Path}
+		}
+		/* 2. Continue handling the HTTP Requests with the next registered middleware */
+		next.ServeHTTP(w, r)
+	})
+}
@@ -5,12 +5,25 @@ package middleware
    that are defined in the handlers/ package.
    This is achieved using the DECORATOR PATTERN. */
 
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Retry-After Jitter
+- Clients limited at the same instant would all retry at the exact reset time (thundering herd): every 429 of
+  the rate limits reports a Retry-After with a random jitter (up to RATE_LIMIT_JITTER) on top of the actual
+  wait, so that the retries spread out.
+*/
+
 // 1. IMPORT PACKAGES *************************************************************************************************
 import (
 	/* INTERNAL Packages */
+	"bookapi/internal/logger"
 	"bookapi/internal/utils"
 	/* EXTERNAL Packages */
+	"context"
+	"math"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -34,7 +47,7 @@ type rateLimitEntry struct {
 
 /* Global Variable */
 var (
-	/* Map storing rate limit info for each IP address */
+	/* Map storing rate limit info for each client key (user ID or IP address) */
 	visitors = make(map[string]*rateLimitEntry)
 	/* Mutex (lock) making sure only one goroutine accesses the map at a time */
 	mu sync.Mutex
@@ -44,8 +57,10 @@ var (
 const (
 	/* Time Window to limit rate */
 	limitWindow = 1 * time.Minute
-	/* Max number of requests allowed per IP within the limit Window */
+	/* Max number of requests allowed per client key within the limit Window */
 	requestCap = 60
+	/* Address of the Redis server used by the production rate limiter */
+	redisAddr = "localhost:6379"
 )
 
 // 3. CUSTOM http.Handlers ********************************************************************************************
@@ -54,56 +69,66 @@ const (
 /*
 Middleware designed to limit the Rate of HTTP Requests to all Endpoints assigned with it.
 Function returning another function — a middleware — that wraps around HTTP handlers to control
-how often they can be called by a user based on their IP Address.
+how often they can be called by a user based on their User ID (authenticated requests) or their IP Address
+(anonymous requests).
+- IMPORTANT!! On protected routes it must be registered AFTER the JWTAuth middleware, otherwise the User ID is
+  not in the request's context yet and every request gets limited by IP.
+- Up to jitter gets added to the Retry-After of its 429 Responses (see IMPORTANT NOTES 1.).
 */
-func RateLimit(next http.Handler) http.Handler {
-	/* 1. Actual Handler Function that runs for every registered HTTP request. */
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		/* 2. Get the IP address of the client sending the HTTP request */
-		ip := r.RemoteAddr
-		/* 3. Lock the visitors map to access it safely */
-		mu.Lock()
-		/* 4. Check if the IP address already has an entry in the map */
-		entry, exists := visitors[ip]
-		/* 5A. ...if the IP address isn't recorded in the map yet or the last request
-		   has been done a while ago (beyond the limit window)... */
-		if !exists || time.Since(entry.LastSeen) > limitWindow {
-			/* ...create a new entry with count=1...*/
-			visitors[ip] = &rateLimitEntry{LastSeen: time.Now(), Count: 1}
-			/*...unlock the visitors map...*/
+func RateLimit(jitter time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		/* 1. Actual Handler Function that runs for every registered HTTP request. */
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 2. Get the key (User ID or IP address) of the client sending the HTTP request */
+			key := rateLimitKey(r, clientIP)
+			/* 3. Lock the visitors map to access it safely */
+			mu.Lock()
+			/* 4. Check if the key already has an entry in the map */
+			entry, exists := visitors[key]
+			/* 5A. ...if the key isn't recorded in the map yet or the last request
+			   has been done a while ago (beyond the limit window)... */
+			if !exists || time.Since(entry.LastSeen) > limitWindow {
+				/* ...create a new entry with count=1...*/
+				visitors[key] = &rateLimitEntry{LastSeen: time.Now(), Count: 1}
+				/*...unlock the visitors map...*/
+				mu.Unlock()
+				/*...move on handling the HTTP request...*/
+				next.ServeHTTP(w, r)
+				return
+			}
+			/* 5B. ...if the key has already been recorded in the map...*/
+			/*...increase the requests' counter...*/
+			entry.Count++
+			/*...update the last seen time...*/
+			entry.LastSeen = time.Now()
+			/*...unlock the map...*/
 			mu.Unlock()
-			/*...move on handling the HTTP request...*/
+
+			/* 6. If the requests count exceeds the cap/limit...*/
+			if entry.Count > requestCap {
+				/*...log the hit and send back 429 Error via Helper Function (every request, even a rejected one, moves
+				  LastSeen forward: the client has to stay quiet for a whole window) */
+				logger.Warnf("rate limit exceeded for %s on %s %s", key, r.Method, r.URL.Path)
+				setRetryAfter(w, limitWindow, jitter)
+				utils.WriteSafeError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+				return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+			}
+			/* 7. If the request is within the limit, pass it to the next handler. */
 			next.ServeHTTP(w, r)
-			return
-		}
-		/* 5B. ...if teh IP address has already been recorded in the map...*/
-		/*...increase the requests' counter...*/
-		entry.Count++
-		/*...update the last seen time...*/
-		entry.LastSeen = time.Now()
-		/*...unlock the map...*/
-		mu.Unlock()
-
-		/* 6. If the requests count exceeds the cap/limit...*/
-		if entry.Count > requestCap {
-			/*...send back 429 Error via Helper Function */
-			utils.WriteSafeError(w, http.StatusTooManyRequests, "Rate limit exceeded")
-			return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
-		}
-		/* 7. If the request is within the limit, pass it to the next handler. */
-		next.ServeHTTP(w, r)
-	})
+		})
+	}
 }
 
 /* PRODUCTION RATE-LIMIT Middleware ----------------------------------------------------------------------------------*/
 /*
 Middleware designed to limit the Rate of HTTP Requests to all Endpoints assigned with it.
 Function returning another function — a middleware — that wraps around HTTP handlers to control
-how often they can be called.
+how often they can be called. Up to jitter gets added to the Retry-After of its 429 Responses (see IMPORTANT
+NOTES 1.).
 */
-func ProductionRateLimit() func(http.Handler) http.Handler {
+func ProductionRateLimit(jitter time.Duration) func(http.Handler) http.Handler {
 	/* 1. Create a Redis Client (i.e. Connection) that connects to Redis running at port 6379 */
-	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	rdb := redis.NewClient(&redis.Options{Addr: redisAddr})
 	/* 2. Set up Storage System */
 	store, err := redisstore.NewStoreWithOptions(rdb, limiter.StoreOptions{})
 	if err != nil {
@@ -116,8 +141,61 @@ func ProductionRateLimit() func(http.Handler) http.Handler {
 	}
 	/* 4. Create the limiter object that enforces the rate limit */
 	limiterInstance := limiter.New(store, rate)
-	/* 5. Wrap the limiter in a middleware that can be used with standard HTTP handlers */
-	middleware := chimiddleware.NewMiddleware(limiterInstance)
+	/* 5. Wrap the limiter in a middleware that can be used with standard HTTP handlers, keying the limits on the
+	   User ID when available and on the limiter's own IP detection otherwise */
+	middleware := chimiddleware.NewMiddleware(limiterInstance, chimiddleware.WithKeyGetter(
+		func(r *http.Request) string { return rateLimitKey(r, limiterInstance.GetIPKey) }),
+		chimiddleware.WithLimitReachedHandler(func(w http.ResponseWriter, r *http.Request) {
+			logger.Warnf("rate limit exceeded for %s on %s %s", rateLimitKey(r, limiterInstance.GetIPKey), r.Method, r.URL.Path)
+			/* The limiter has already set X-RateLimit-Reset (Unix time in seconds at which the period ends) */
+			var wait time.Duration
+			if reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				wait = time.Until(time.Unix(reset, 0))
+			}
+			setRetryAfter(w, wait, jitter)
+			utils.WriteSafeError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+		}))
 	/* 6. Return the middleware function to protect routes */
 	return middleware.Handler
 }
+
+// 4. UTILITY METHODS ************************************************************************************************
+
+/* Redis Ping -------------------------------------------------------------------------------------------------------*/
+/* Checks that the Redis server used by ProductionRateLimit is reachable (used by GET /health/detailed) */
+func PingRedis(ctx context.Context) error {
+	rdb := redis.NewClient(&redis.Options{Addr: redisAddr})
+	defer rdb.Close()
+	return rdb.Ping(ctx).Err()
+}
+
+/* Rate Limit Key ---------------------------------------------------------------------------------------------------*/
+/* Returns the key used to track the requests of a client: the User ID injected by the JWTAuth middleware for
+   authenticated requests, or the IP address returned by the input fallback function for anonymous ones.
+   The prefixes keep the two key spaces apart (i.e. user 1 never shares a bucket with IP "1"). */
+func rateLimitKey(r *http.Request, ipKey func(*http.Request) string) string {
+	if userID, ok := r.Context().Value(UserIDKey).(int); ok {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return "ip:" + ipKey(r)
+}
+
+/* Retry-After ------------------------------------------------------------------------------------------------------*/
+/* Sets the Retry-After header (whole seconds, at least 1) to the input wait plus a random jitter between 0 and the
+   input jitter (see IMPORTANT NOTES 1.) */
+func setRetryAfter(w http.ResponseWriter, wait, jitter time.Duration) {
+	if jitter > 0 {
+		wait += rand.N(jitter + 1)
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+}
+
+/* Client IP --------------------------------------------------------------------------------------------------------*/
+/* Returns the IP address of the client stripped of the port (r.RemoteAddr is in the form "ip:port") */
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
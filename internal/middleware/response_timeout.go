@@ -0,0 +1,149 @@
+package middleware
+
+// middleware/ PACKAGE ************************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. ResponseTimeout vs Timeout (see timeout.go)
+	- Timeout only gives the request a deadline: a handler stuck in something that ignores the context (e.g. a slow
+	  non-context-aware call) keeps the client waiting anyway. ResponseTimeout is the hard guard: when the time is up
+	  the client gets the 503 right away, whatever the handler is doing.
+	- It should be longer than REQUEST_TIMEOUT, so that the (cooperative) Timeout normally fires first.
+   2. Why not http.TimeoutHandler
+	- http.TimeoutHandler works the same way but its 503 has a plain-text Body. Here the 503 is an ErrorResponse
+	  JSON (or problem+json) like every other error of the API.
+   3. No Double Writes
+	- The handler runs in its own goroutine and writes into a BUFFER: the buffered response is copied to the client
+	  only if the handler finishes in time. After the timeout the buffer is closed and every further write of the
+	  handler gets http.ErrHandlerTimeout, so that the client only ever receives ONE response.
+	- A panic of the handler is re-raised in the request goroutine, so that the Recoverer middleware still sees it.
+   4. Streamed Routes
+	- Buffering would defeat the routes that stream big responses (e.g. GET /admin/audit/export): they are exempted
+	  and only bounded by the (cooperative) Timeout middleware.
+*/
+
+// 1. IMPORT PACKAGES *************************************************************************************************
+import (
+	"bookapi/internal/utils"
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 2. GO STRUCTS and UTILITY METHODS  *********************************************************************************
+
+/* Response Writer Wrapper - Go Struct */
+/* Buffers the HTTP Response of the handler until it finishes (or the timeout expires) */
+type bufferedWriter struct {
+	w        http.ResponseWriter /* Real writer: only used to find the wrappers (e.g. ProblemWriter) via Unwrap() */
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+/* Headers of the buffered response (copied to the real ones when the handler finishes in time) */
+func (b *bufferedWriter) Header() http.Header {
+	return b.header
+}
+
+/* Record the status code (only the first one counts) */
+func (b *bufferedWriter) WriteHeader(status int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timedOut || b.status != 0 {
+		return
+	}
+	b.status = status
+}
+
+/* Buffer the Body, or fail if the timeout has already expired (see IMPORTANT NOTES 3.) */
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+/* Give access to the wrapped http.ResponseWriter (e.g. to the error helpers in utils) */
+func (b *bufferedWriter) Unwrap() http.ResponseWriter {
+	return b.w
+}
+
+/* Routes streaming their response, never buffered (see IMPORTANT NOTES 4.) */
+var streamedRoutes = map[string]struct{}{
+	"/admin/audit/export": {},
+}
+
+// 3. CUSTOM http.Handlers ********************************************************************************************
+
+/* RESPONSE TIMEOUT Middleware --------------------------------------------------------------------------------------*/
+/* Middleware answering 503 with a JSON Body if the handler hasn't finished within the input timeout.
+   A timeout <= 0 disables it. */
+func ResponseTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		/* 1. If the timeout is disabled, don't wrap the next handler at all */
+		if timeout <= 0 {
+			return next
+		}
+		/* 2. Actual Handler Function that runs for every registered HTTP request. */
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 3. Let the streamed routes write straight to the client */
+			if _, streamed := streamedRoutes[r.URL.Path]; streamed {
+				next.ServeHTTP(w, r)
+				return
+			}
+			/* 4. Give the context of the HTTP Request the same deadline, so that context-aware calls stop too */
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			/* 5. Run the next/inner http.Handler in its own goroutine, writing into the buffer */
+			bw := &bufferedWriter{w: w, header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(bw, r.WithContext(ctx))
+				close(done)
+			}()
+			/* 6. Wait for the handler, its panic or the timeout, whichever comes first */
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				/* The handler finished in time: copy the buffered response to the client */
+				bw.mu.Lock()
+				defer bw.mu.Unlock()
+				for key, values := range bw.header {
+					w.Header()[key] = values
+				}
+				if bw.status == 0 {
+					bw.status = http.StatusOK
+				}
+				w.WriteHeader(bw.status)
+				w.Write(bw.body.Bytes())
+			case <-ctx.Done():
+				/* Time is up (or the client has gone): close the buffer and send back the 503 error instead */
+				bw.mu.Lock()
+				bw.timedOut = true
+				bw.mu.Unlock()
+				if ctx.Err() == context.DeadlineExceeded {
+					utils.WriteSafeError(w, http.StatusServiceUnavailable, "Request timed out.")
+				}
+			}
+		})
+	}
+}
@@ -14,6 +14,9 @@ package middleware
 	  This is because, as we know, Hash Tables perform way better for Search Algorithm with a computational cost of
 	  θ(1) rather than θ(n) ! Worth the effort of building the Set from scratch in the function below instead of
 	  relying on a simple list/array
+   3. Listing the Roles of a Route (GET /admin/routes)
+	- The handler returned by AllowRoles remembers its allowed roles (roleGuard), so that RouteRoles can find them
+	  among the middlewares that chi.Walk reports for every route, with no list of routes/roles to keep in sync.
 */
 
 // 1. IMPORT PACKAGES *********************************************************************************************
@@ -22,7 +25,26 @@ import (
 	"net/http"
 )
 
-// 2. CUSTOM http.Handlers ****************************************************************************************
+// 2. GO STRUCTS and UTILITY METHODS  *****************************************************************************
+
+/* Handler returned by AllowRoles, remembering the roles it lets through (see IMPORTANT NOTES 3.) */
+type roleGuard struct {
+	http.Handler
+	roles []string
+}
+
+/* Roles allowed by the AllowRoles middleware among the input ones (e.g. from chi.Walk), nil if none restricts roles */
+func RouteRoles(middlewares ...func(http.Handler) http.Handler) []string {
+	for _, mw := range middlewares {
+		/* Wrapping a no-op handler is enough to find out which kind of middleware it is */
+		if guard, ok := mw(http.NotFoundHandler()).(roleGuard); ok {
+			return guard.roles
+		}
+	}
+	return nil
+}
+
+// 3. CUSTOM http.Handlers ****************************************************************************************
 
 /* ROLE-BASED AUTH Middleware ---------------------------------------------------------------------------------- */
 /* Middleware designed to restrict access to certain HTTP endpoints based on the user's role.
@@ -39,8 +61,8 @@ func AllowRoles(allowed ...string) func(http.Handler) http.Handler {
 	}
 	/* 2. Wrap the original handler (next) and add role-checking logic before calling it. */
 	return func(next http.Handler) http.Handler {
-		/* 3. Actual Handler Function that runs for every registered HTTP request. */
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		/* 3. Actual Handler Function that runs for every registered HTTP request (remembering its roles). */
+		return roleGuard{roles: allowed, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			/* 4. Try to get the User's Role from the Context of the HTTP Request. */
 			role, ok := r.Context().Value(UserRoleKey).(string)
 			/* 5. If the role of the user is empty or cannot be extracted, return error via Helper Function. */
@@ -56,6 +78,6 @@ func AllowRoles(allowed ...string) func(http.Handler) http.Handler {
 			}
 			/* 7. If the role is valid proceed to call the original handler. */
 			next.ServeHTTP(w, r)
-		})
+		})}
 	}
 }
@@ -0,0 +1,114 @@
+package middleware
+
+// middleware/ PACKAGE **********************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Semaphore vs ConcurrencyLimit (see concurrency.go)
+	- Both let at most N requests through at the same time. ConcurrencyLimit rejects the extra ones straight away,
+	  while a Semaphore lets them QUEUE for a short while (wait) before giving up with 503: bursts of short
+	  requests (e.g. the page transfers) get smoothed out instead of failing.
+	- The Semaphore is a struct (like MaintenanceMode) so that the admin endpoints can read its numbers: the
+	  queue depth and the requests in flight (see GET /admin/db-stats).
+   2. Why around the Transfers
+	- Every transfer runs a transaction holding row locks and one pooled DB connection until it commits: too many
+	  of them at once would starve the Connection Pool for all the other requests.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"bookapi/internal/logger"
+	"bookapi/internal/utils"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// 2. GO STRUCTS and UTILITY METHODS  *****************************************************************************
+
+/* Semaphore - Go Struct */
+/* At most Capacity concurrent requests, the extra ones waiting up to wait for a free slot */
+type Semaphore struct {
+	name   string
+	slots  chan struct{}
+	wait   time.Duration
+	queued atomic.Int64
+}
+
+/* Constructor */
+/* A capacity of 0 or less disables the limit (nil Semaphore). A wait of 0 rejects the extra requests right away. */
+func NewSemaphore(name string, capacity int, wait time.Duration) *Semaphore {
+	if capacity <= 0 {
+		return nil
+	}
+	return &Semaphore{name: name, slots: make(chan struct{}, capacity), wait: max(wait, 0)}
+}
+
+/* Number of requests currently holding a slot (0 for a disabled Semaphore) */
+func (s *Semaphore) InFlight() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.slots)
+}
+
+/* Number of requests currently waiting for a slot, i.e. the queue depth (0 for a disabled Semaphore) */
+func (s *Semaphore) Queued() int {
+	if s == nil {
+		return 0
+	}
+	return int(s.queued.Load())
+}
+
+/* Take a slot, waiting up to s.wait (and no longer than the request) for one to get free */
+func (s *Semaphore) acquire(r *http.Request) bool {
+	/* 1. Free slot -> go straight through */
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if s.wait <= 0 {
+		return false
+	}
+	/* 2. Otherwise join the queue until a slot gets free, the wait expires or the client goes away */
+	s.queued.Add(1)
+	defer s.queued.Add(-1)
+	timer := time.NewTimer(s.wait)
+	defer timer.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// 3. CUSTOM http.Handlers ****************************************************************************************
+
+/* SEMAPHORE Middleware ---------------------------------------------------------------------------------------- */
+/* Lets at most Capacity requests through at the same time across all the routes it's registered on, answering 503
+   to the ones that couldn't get a slot within the wait (see IMPORTANT NOTES) */
+func (s *Semaphore) Middleware(next http.Handler) http.Handler {
+	/* 1. No limit -> return the original handler as it is */
+	if s == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		/* 2. Get a slot (possibly after queueing), or tell the client to retry later */
+		if !s.acquire(r) {
+			logger.Warnf("%s limit (%d) reached with %d queued: %s %s", s.name, cap(s.slots), s.Queued(), r.Method,
+				r.URL.Path)
+			w.Header().Set("Retry-After", "1")
+			utils.WriteSafeError(w, http.StatusServiceUnavailable, "Too many concurrent requests, please retry later.")
+			return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+		}
+		/* 3. Release the slot when the request has been served (even if the handler panics) */
+		defer func() { <-s.slots }()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,57 @@
+package middleware
+
+// middleware/ PACKAGE ************************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Why a Database Check on top of the Token
+- A JWT stays valid until it expires, whatever happens to its user in the meantime. To make a suspension apply
+  to the tokens issued before it, the active flag of the user is looked up on every protected request.
+- It must run AFTER JWTAuth (it needs the User ID) and after the rate limit (so that floods don't reach the
+  Database).
+*/
+
+// 1. IMPORT PACKAGES *************************************************************************************************
+import (
+	"bookapi/internal/logger"
+	"bookapi/internal/utils"
+	"context"
+	"net/http"
+)
+
+// 2. GO STRUCTS and UTILITY VARIABLES  *******************************************************************************
+
+/* Function telling whether a user is active (e.g. services.UserService.IsActive), false for deleted users too */
+type ActiveChecker func(ctx context.Context, userID int) (bool, error)
+
+// 3. CUSTOM http.Handlers ********************************************************************************************
+
+/* SUSPENDED ACCOUNTS Middleware -----------------------------------------------------------------------------------*/
+/* Middleware answering 403 "account suspended" to the requests whose Token belongs to a suspended user */
+func RejectSuspended(isActive ActiveChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 1. Requests with no authenticated user are not this middleware's business */
+			userID, ok := r.Context().Value(UserIDKey).(int)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			/* 2. Look up the active flag of the user + Error Handling */
+			active, err := isActive(r.Context(), userID)
+			if err != nil {
+				logger.Errorf("checking whether user %d is active: %v", userID, err)
+				utils.WriteSafeError(w, http.StatusInternalServerError, "Could Not Check the Account.")
+				return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+			}
+			/* 3. Suspended users get 403, the others go through */
+			if !active {
+				utils.WriteSafeError(w, http.StatusForbidden, "account suspended")
+				return /* <--- NEVER FORGET the RETURN keyword AFTER calling the RESPONSE HELPER FUNCTIONS!! */
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
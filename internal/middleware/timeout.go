@@ -0,0 +1,91 @@
+package middleware
+
+// middleware/ PACKAGE ************************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Request Deadline
+	- The middleware below doesn't stop the handlers by itself: it gives the context of the HTTP Request a deadline
+	  and every context-aware call (i.e. the repositories/ SQL Queries) gets cancelled when it expires.
+   2. 503 Response
+	- When the deadline has expired, whatever the handler tries to send back (typically a 500/404 caused by the
+	  cancelled query) gets replaced by a 503 JSON error, so that the client knows it's a timeout.
+*/
+
+// 1. IMPORT PACKAGES *************************************************************************************************
+import (
+	"bookapi/internal/utils"
+	"context"
+	"net/http"
+	"time"
+)
+
+// 2. GO STRUCTS and UTILITY METHODS  *********************************************************************************
+
+/* Response Writer Wrapper - Go Struct */
+/* Replaces the HTTP Response of the handler with a 503 error when the deadline of the request has expired */
+type deadlineWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	wroteHeader bool
+	timedOut    bool
+}
+
+/* Write the 503 error instead of the handler's status code if the deadline has expired */
+func (w *deadlineWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.ctx.Err() == context.DeadlineExceeded {
+		w.timedOut = true
+		utils.WriteSafeError(w.ResponseWriter, http.StatusServiceUnavailable, "Request timed out.")
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+/* Give access to the wrapped http.ResponseWriter (e.g. to the error helpers in utils) */
+func (w *deadlineWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+/* Discard the handler's Body if the 503 error has been written in its place */
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.timedOut {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// 3. CUSTOM http.Handlers ********************************************************************************************
+
+/* REQUEST TIMEOUT Middleware ---------------------------------------------------------------------------------------*/
+/* Middleware wrapping the context of every HTTP Request with the input timeout (context.WithTimeout).
+   A timeout <= 0 disables it. */
+func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		/* 1. If the timeout is disabled, don't wrap the next handler at all */
+		if timeout <= 0 {
+			return next
+		}
+		/* 2. Actual Handler Function that runs for every registered HTTP request. */
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			/* 3. Give the context of the HTTP Request a deadline and release its resources when done */
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			/* 4. Execute the next/inner http.Handler with the new context */
+			dw := &deadlineWriter{ResponseWriter: w, ctx: ctx}
+			next.ServeHTTP(dw, r.WithContext(ctx))
+			/* 5. If the handler has written nothing and the deadline has expired, send back the 503 error */
+			if !dw.wroteHeader && ctx.Err() == context.DeadlineExceeded {
+				dw.WriteHeader(http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
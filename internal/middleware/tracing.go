@@ -0,0 +1,78 @@
+package middleware
+
+// middleware/ PACKAGE **********************************************************************************************
+/* The middleware/ package stores all the MIDDLEWARE functions that allow to add functionalities to the HTTP Handlers
+   that are defined in the handlers/ package.
+   This is achieved using the DECORATOR PATTERN. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. One Span per Request
+	- The span starts as soon as the request comes in, as a child of the span in the incoming traceparent header
+	  (if any), and travels in the context of the request: the DB spans created by the handlers' queries become
+	  its children (see tracing/ package).
+   2. Span Name
+	- The span gets named after the ROUTE PATTERN (e.g. "GET /books/{id}") and not the actual path, which would
+	  create one different name per book. The pattern is known only after chi has routed the request, hence the
+	  name gets set at the end (while the span starts with the HTTP Method only).
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 2. GO STRUCTS **************************************************************************************************
+
+/* ResponseWriter remembering the status code sent to the client (200 if WriteHeader is never called) */
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+/* Give access to the wrapped http.ResponseWriter (e.g. to the error helpers in utils) */
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// 3. CUSTOM http.Handlers ****************************************************************************************
+
+/* TRACING Middleware ------------------------------------------------------------------------------------------ */
+/* Wraps every HTTP Request in an OpenTelemetry SERVER span (a no-op when tracing is not exported) */
+func Tracing(next http.Handler) http.Handler {
+	tracer := otel.Tracer("bookapi/http")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		/* 1. Continue the caller's trace, if the request carries a traceparent header */
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		/* 2. Start the span of the request (see IMPORTANT NOTES 2.) */
+		ctx, span := tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			))
+		defer span.End()
+		/* 3. Execute the next/inner http.Handler with the span in the context of the request */
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		/* 4. Name the span after the matched route and record the outcome (5xx = error) */
+		if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
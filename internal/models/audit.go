@@ -0,0 +1,45 @@
+package models
+
+// models/ PACKAGE ************************************************************************************************
+/* The models/ package is used to store all the definitions of all objects that are used in the application.
+   These includes Go Structs and Utility Variables. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Audit Log
+- Every successful change of a book (create, update, delete, transfer, merge) gets recorded as one
+  AuditEntry: WHO (UserID, from the JWT Token) did WHAT (Action) to WHICH resource (Resource, ResourceID)
+  and WHEN (CreatedAt, set by the Database). Entries are never updated nor deleted.
+   2. Impersonation
+- An admin getting a token for another user is recorded as "impersonate" (the admin as UserID, the user as
+  ResourceID), and every change made with that token carries the admin's ID in ImpersonatedBy.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+/* No need to import any package in this case */
+
+// 2. GO STRUCTS **************************************************************************************************
+
+/* Actions recorded in the audit log */
+const (
+	AuditCreate   = "create"
+	AuditUpdate   = "update"
+	AuditDelete   = "delete"
+	AuditTransfer = "transfer"
+	AuditMerge    = "merge"
+	/* An admin got a token to act as another user [POST /admin/users/{id}/impersonate] */
+	AuditImpersonate = "impersonate"
+	/* The owner of a book got a read-only link to it [POST /books/{id}/share] */
+	AuditShare = "share"
+)
+
+/* Audit Entry - one recorded action [GET /me/activity] */
+type AuditEntry struct { /* 		>>>>> SWAGGER <<<<< */
+	ID         int    `json:"id" example:"1"`
+	UserID     int    `json:"user_id" example:"1"`      /* Who (from the JWT Token) */
+	Action     string `json:"action" example:"update"`  /* What: create, update, delete... */
+	Resource   string `json:"resource" example:"book"`  /* Type of the changed resource */
+	ResourceID int    `json:"resource_id" example:"42"` /* Unique ID of the changed resource */
+	/* Admin who acted as UserID via an impersonation token (0/omitted = the user themselves) */
+	ImpersonatedBy int     `json:"impersonated_by,omitempty" example:"1"`
+	CreatedAt      APITime `json:"created_at" example:"2024-01-01T00:00:00Z" swaggertype:"string" format:"date-time"` /* When (set by the Database) */
+}
@@ -15,21 +15,150 @@ package models
 		- Since, in this case, we're using PostgreSQL Databases to store the data, there's no need to declare any
 		  Data Structure here (e.g. Books array) to store the Go Struct Instances. All is handled by the db/ and
 		  repositories/ packages.
-
-
+   3. Timestamps
+		- CreatedAt and UpdatedAt are set by the Database (DEFAULT now() / SET updated_at = now()) and are read-only
+		  for the client: whatever value is sent in the Body of the HTTP Request gets ignored.
+   4. Average Rating
+		- AvgRating is computed from the reviews table when reading books (null when the book has no reviews) and,
+		  like the timestamps, is read-only for the client.
+   5. Publication Year
+		- Year is optional: 0 means unknown and gets stored as NULL (negative values are years BC).
+*/
 
 // 1. IMPORT PACKAGES *********************************************************************************************
-/* No need to import any package in this case */
+import "time"
 
 // 2. GO STRUCTS **************************************************************************************************
 
+/* Max number of pages of one book (also the upper bound of every "pages" field of the HTTP Requests) */
+const MaxPages = 100000
+
+/* Highest id PUT /books/{id} can create: the id sequence follows the clients' ids, half of int4 stays for POST */
+const MaxUpsertBookID = 1 << 30
+
 /* Book */
 type Book struct { /* 				>>>>> SWAGGER <<<<< */
-	ID      int    `json:"id" example:"1"`
-	Title   string `json:"title" example:"The Go Programming Language"` /* 	Title of the book. */
-	Author  string `json:"author" example:"Alan Donovan"`               /* 	Name of the author. */
-	Pages   int    `json:"pages" example:"380"`                         /* 	Number of pages. */
-	OwnerID int    `json:"-" example:"1"`                               // omit from JSON Responses and SWAGGER !
+	ID        int      `json:"id" example:"1"`
+	Title     string   `json:"title" example:"The Go Programming Language"`                                       /* 	Title of the book. */
+	Author    string   `json:"author" example:"Alan Donovan"`                                                     /* 	Name of the author. */
+	Pages     int      `json:"pages" example:"380"`                                                               /* 	Number of pages. */
+	Year      int      `json:"year,omitempty" example:"2015"`                                                     /* 	Publication year (optional, 0 = unknown). */
+	OwnerID   int      `json:"-" example:"1"`                                                                     // omit from JSON Responses and SWAGGER !
+	CreatedAt APITime  `json:"created_at" example:"2024-01-01T00:00:00Z" swaggertype:"string" format:"date-time"` /* 	Creation date (set by the Database). */
+	UpdatedAt APITime  `json:"updated_at" example:"2024-01-01T00:00:00Z" swaggertype:"string" format:"date-time"` /* 	Last update date (set by the Database). */
+	AvgRating *float64 `json:"average_rating" example:"4.5"`                                                      /* 	Average review rating (null if none). */
+	/* 	Number of reviews, only with GET /books?include=reviews_summary (omitted otherwise). */
+	ReviewCount *int `json:"review_count,omitempty" example:"12"`
+}
+
+/* Book with its owner - Admin view of GET /books?all=true (Book itself never exposes the owner) */
+type OwnedBook struct { /* 	>>>>> SWAGGER <<<<< */
+	Book
+	OwnerID int `json:"owner_id" example:"1"` /* 	ID of the user who created the book (0 if none). */
+}
+
+/* Book Comparison - Response of GET /books/compare?a={id}&b={id} */
+type BookComparison struct { /* 	>>>>> SWAGGER <<<<< */
+	A           Book            `json:"a"`           /* 	First book (a query parameter). */
+	B           Book            `json:"b"`           /* 	Second book (b query parameter). */
+	Differences BookDifferences `json:"differences"` /* 	Computed differences (b compared to a). */
+}
+
+/* Book Differences - computed part of a Book Comparison */
+type BookDifferences struct { /* 	>>>>> SWAGGER <<<<< */
+	PagesDelta      int      `json:"pages_delta" example:"-120"`             /* 	b.pages - a.pages. */
+	YearDelta       *int     `json:"year_delta" example:"12"`                /* 	b.year - a.year (null if either year is unknown). */
+	SameAuthor      bool     `json:"same_author" example:"true"`             /* 	Same author, ignoring case and spaces. */
+	DifferentFields []string `json:"different_fields" example:"title,pages"` /* 	JSON names of the fields with different values. */
+}
+
+/* Popular Book - one book of GET /books/popular with the number of users who favorited it */
+type PopularBook struct { /* 		>>>>> SWAGGER <<<<< */
+	Book
+	FavoriteCount int `json:"favorite_count" example:"42"` /* 	Number of users who favorited the book. */
+}
+
+/* Book Patch - Body of PATCH /books/{id}: only the fields that are present (not null) get updated */
+type BookPatch struct { /* 		>>>>> SWAGGER <<<<< */
+	Title  *string `json:"title,omitempty" example:"The Go Programming Language"`
+	Author *string `json:"author,omitempty" example:"Alan Donovan"`
+	Pages  *int    `json:"pages,omitempty" example:"380"`
+	Year   *int    `json:"year,omitempty" example:"2015"`
+}
+
+/* Patch Meta - Meta of the PATCH /books/{id} Response */
+type PatchMeta struct { /* 		>>>>> SWAGGER <<<<< */
+	Changed []string `json:"changed" example:"pages"` /* JSON names of the fields whose value has actually changed */
+}
+
+/* Review - rating (1-5) of one book by one user [POST/GET /books/{id}/reviews] */
+type Review struct { /* 			>>>>> SWAGGER <<<<< */
+	ID        int     `json:"id" example:"1"`
+	BookID    int     `json:"book_id" example:"1"`                                                               /* Reviewed book (from the URL) */
+	UserID    int     `json:"user_id" example:"1"`                                                               /* Reviewer (from the JWT Token) */
+	Rating    int     `json:"rating" example:"5"`                                                                /* From 1 to 5 */
+	Comment   string  `json:"comment,omitempty" example:"A classic."`                                            /* Optional text */
+	CreatedAt APITime `json:"created_at" example:"2024-01-01T00:00:00Z" swaggertype:"string" format:"date-time"` /* Set by the Database */
+	UpdatedAt APITime `json:"updated_at" example:"2024-01-01T00:00:00Z" swaggertype:"string" format:"date-time"` /* Set by the Database */
+}
+
+/* Review Request - Body of POST /books/{id}/reviews */
+type ReviewRequest struct { /* 		>>>>> SWAGGER <<<<< */
+	Rating  int    `json:"rating" example:"5"`                     /* From 1 to 5 */
+	Comment string `json:"comment,omitempty" example:"A classic."` /* Optional text */
+}
+
+/* Citation - formatted citation of one book [GET /books/{id}/cite] */
+type Citation struct { /* 			>>>>> SWAGGER <<<<< */
+	Style    string `json:"style" example:"apa"`                                                 /* Citation style */
+	Citation string `json:"citation" example:"Donovan, A. (2015). The Go Programming Language."` /* Formatted text */
+}
+
+/* Book Filter - optional filters of GET /books (nil = no bound, Limit 0 = no limit) */
+type BookFilter struct {
+	CreatedFrom *time.Time /* Only books created at or after this date [created_from] */
+	CreatedTo   *time.Time /* Only books created at or before this date [created_to] */
+	Limit       int        /* Max number of books to return [limit] */
+	Offset      int        /* Number of books to skip [offset] */
+	/* Whether to fill the review_count of every book [include=reviews_summary] */
+	IncludeReviewsSummary bool
+}
+
+/* Book Query - Body of POST /books/query: every given filter must match (empty/null = not applied), Limit 0 = 20 */
+type BookQuery struct { /* 		>>>>> SWAGGER <<<<< */
+	Author        string `json:"author,omitempty" example:"Alan Donovan"` /* Author (exact, case insensitive) */
+	TitleContains string `json:"title_contains,omitempty" example:"Go"`   /* Part of the title (case insensitive) */
+	PagesMin      *int   `json:"pages_min,omitempty" example:"100"`       /* Min number of pages (included) */
+	PagesMax      *int   `json:"pages_max,omitempty" example:"500"`       /* Max number of pages (included) */
+	YearMin       *int   `json:"year_min,omitempty" example:"1990"`       /* Min publication year (included) */
+	YearMax       *int   `json:"year_max,omitempty" example:"2020"`       /* Max publication year (included) */
+	Sort          string `json:"sort,omitempty" example:"-pages"`         /* Sort field, "-" prefix = descending */
+	Limit         int    `json:"limit,omitempty" example:"20"`            /* Page size (1-100, default 20) */
+	Offset        int    `json:"offset,omitempty" example:"0"`            /* Number of books to skip */
+}
+
+/* Ownership Request - Body of POST /books/ownership */
+type OwnershipRequest struct { /* 	>>>>> SWAGGER <<<<< */
+	IDs []int `json:"ids" example:"1,2,3"` /* Books to check (1-100 IDs) */
+}
+
+/* Pages Total - total number of pages of the caller's books [GET /me/pages/total] */
+type PagesTotal struct { /* 		>>>>> SWAGGER <<<<< */
+	TotalPages int `json:"total_pages" example:"1250"` /* 0 if the user has no books */
+}
+
+/* Sync Meta - Meta of GET /books/changes (delta sync) */
+type SyncMeta struct { /* 		>>>>> SWAGGER <<<<< */
+	Since    APITime `json:"since" swaggertype:"string" format:"date-time" example:"2024-01-01T00:00:00Z"`
+	SyncedAt APITime `json:"synced_at" swaggertype:"string" format:"date-time" example:"2024-01-02T00:00:00Z"` /* since of the next call */
+}
+
+/* Share Link - Response of POST /books/{id}/share: read-only access to one book, no authentication needed */
+type ShareLink struct { /* 		>>>>> SWAGGER <<<<< */
+	BookID    int     `json:"book_id" example:"1"`
+	Token     string  `json:"token"`
+	URL       string  `json:"url" example:"https://api.example.com/shared/eyJhbGciOi..."`                        /* GET it to read the book */
+	ExpiresAt APITime `json:"expires_at" example:"2024-01-08T00:00:00Z" swaggertype:"string" format:"date-time"` /* SHARE_LINK_TTL, no other way to revoke it */
 }
 
 /* Transfer Request */
@@ -38,3 +167,31 @@ type TransferRequest struct { /* 	>>>>> SWAGGER <<<<< */
 	ToID   int `json:"to_id" example:"2"`   /*Unique ID of the book that receives pages */
 	Pages  int `json:"pages" example:"50"`  /*Number of pages transferred*/
 }
+
+/* Merge Request - Body of POST /books/{id}/merge */
+type MergeRequest struct { /* 		>>>>> SWAGGER <<<<< */
+	MergeFromID int `json:"merge_from_id" example:"2"` /* Unique ID of the duplicate book merged into {id} (deleted) */
+}
+
+/* Book Validation Result [POST /books/validate] (invalid books get 422 with the failed fields instead) */
+type BookValidation struct { /* 	>>>>> SWAGGER <<<<< */
+	Valid bool `json:"valid" example:"true"`
+}
+
+/* Pages Update - one item of the Bulk Pages Update Request */
+type PagesUpdate struct { /* 		>>>>> SWAGGER <<<<< */
+	ID    int `json:"id" example:"1"`      /* Unique ID of the book to update */
+	Pages int `json:"pages" example:"100"` /* New number of pages of the book */
+}
+
+/* Bulk Update Failure - one item that could not be updated */
+type BulkUpdateFailure struct { /* 	>>>>> SWAGGER <<<<< */
+	ID     int    `json:"id" example:"3"`                  /* Unique ID of the book that failed */
+	Reason string `json:"reason" example:"Book Not Found"` /* Why the update failed */
+}
+
+/* Bulk Update Result */
+type BulkUpdateResult struct { /* 	>>>>> SWAGGER <<<<< */
+	Succeeded []int               `json:"succeeded"` /* IDs of the updated books */
+	Failed    []BulkUpdateFailure `json:"failed"`    /* Books that could not be updated */
+}
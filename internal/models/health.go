@@ -0,0 +1,49 @@
+package models
+
+// models/ PACKAGE ************************************************************************************************
+/* The models/ package is used to store all the definitions of all objects that are used in the application.
+   These includes Go Structs and Utility Variables. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Health Report
+- Returned by GET /health/detailed (dashboards) while GET /health, /health/live and /health/ready (probes) only
+  answer {"status": "ok"} (or {"status": "shutting_down"}, readiness only).
+  The report status is "degraded" as soon as one of the Dependencies is not reachable or the Database schema is
+  behind the latest version known by the code (Migrations, also served alone by GET /health/migrations).
+*/
+
+/* Health Check Statuses */
+const (
+	HealthOK           = "ok"            /* Every dependency is reachable */
+	HealthDegraded     = "degraded"      /* At least one dependency is NOT reachable */
+	HealthDisabled     = "disabled"      /* The dependency is not used by the current configuration (e.g. Redis) */
+	HealthShuttingDown = "shutting_down" /* The graceful shutdown has begun: no new requests, please */
+	HealthBehind       = "behind"        /* The Database schema misses some migrations known by the code */
+)
+
+/* Detailed Health Report - returned by GET /health/detailed */
+type HealthReport struct {
+	Status       string            `json:"status" example:"ok"`
+	Ready        bool              `json:"ready" example:"true"` /* False once the graceful shutdown has begun */
+	GoVersion    string            `json:"go_version" example:"go1.24.2"`
+	Uptime       string            `json:"uptime" example:"3h12m5s"`
+	Dependencies map[string]string `json:"dependencies"` /* Dependency name -> "ok", "disabled" or the error */
+	Migrations   MigrationStatus   `json:"migrations"`
+	Goroutines   int               `json:"goroutines" example:"12"`
+	Memory       MemoryStats       `json:"memory"`
+}
+
+/* Migration Status - schema version of the Database vs the latest one known by the code [GET /health/migrations] */
+type MigrationStatus struct { /* 		>>>>> SWAGGER <<<<< */
+	Status         string `json:"status" example:"ok"` /* "ok", "behind" or the error reading the version */
+	CurrentVersion int    `json:"current_version" example:"1"`
+	LatestVersion  int    `json:"latest_version" example:"1"`
+}
+
+/* Subset of runtime.MemStats worth showing on a dashboard */
+type MemoryStats struct {
+	AllocBytes     uint64 `json:"alloc_bytes"`      /* Bytes of allocated heap objects */
+	HeapInuseBytes uint64 `json:"heap_inuse_bytes"` /* Bytes in in-use heap spans */
+	SysBytes       uint64 `json:"sys_bytes"`        /* Total bytes obtained from the OS */
+	NumGC          uint32 `json:"num_gc"`           /* Number of completed GC cycles */
+}
@@ -0,0 +1,62 @@
+package models
+
+// models/ PACKAGE ************************************************************************************************
+/* The models/ package is used to store all the definitions of all objects that are used in the application.
+   These includes Go Structs and Utility Variables. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. One Timestamp Format
+- time.Time serializes in the time zone it carries (e.g. "2024-01-01T01:00:00+01:00" when read in the local zone of
+  the server) and with as many fractional digits as it has: the same instant could look different from one
+  endpoint to another. Every timestamp of the responses is therefore an APITime, ALWAYS serialized as RFC 3339
+  in UTC with second precision (APITimeLayout, e.g. "2024-01-01T00:00:00Z").
+- APITime embeds time.Time: all its methods (After, Unix, UTC, Format...) are available as they are, and the
+  wrapped value is at .Time. It can be scanned straight from the Database (TIMESTAMPTZ columns).
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// 2. GO STRUCTS **************************************************************************************************
+
+/* Layout of every timestamp of the API responses (see IMPORTANT NOTES 1.) */
+const APITimeLayout = "2006-01-02T15:04:05Z"
+
+/* Timestamp of the API responses - RFC 3339, UTC, second precision */
+type APITime struct {
+	time.Time
+}
+
+/* Constructor */
+func NewAPITime(t time.Time) APITime {
+	return APITime{Time: t}
+}
+
+/* Serialize as a JSON string in APITimeLayout, whatever the time zone of the wrapped value */
+func (t APITime) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + t.UTC().Format(APITimeLayout) + `"`), nil
+}
+
+/* Accept any RFC 3339 string (e.g. the responses read back by the clients or the tests) */
+func (t *APITime) UnmarshalJSON(data []byte) error {
+	return t.Time.UnmarshalJSON(data)
+}
+
+/* Read the value of a TIMESTAMPTZ column (implements sql.Scanner) */
+func (t *APITime) Scan(src any) error {
+	value, ok := src.(time.Time)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into APITime", src)
+	}
+	t.Time = value
+	return nil
+}
+
+/* Pass the wrapped time.Time to the Database driver (implements driver.Valuer) */
+func (t APITime) Value() (driver.Value, error) {
+	return t.Time, nil
+}
@@ -0,0 +1,42 @@
+package models
+
+// models/ PACKAGE TESTS ******************************************************************************************
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// 2. TESTS *******************************************************************************************************
+
+/* TESTER for the JSON format of the timestamps -----------------------------------------------------------------*/
+func TestAPITime_MarshalJSON(t *testing.T) {
+	/* 1. The same instant in a non-UTC zone and with fractional seconds... */
+	zone := time.FixedZone("CET", 3600)
+	book := Book{ID: 1, CreatedAt: NewAPITime(time.Date(2024, 1, 1, 1, 30, 0, 123456789, zone))}
+	body, err := json.Marshal(book)
+	if err != nil {
+		t.Fatalf("Marshaling failed: %v", err)
+	}
+	/* 2. ...is always written as RFC 3339 in UTC with second precision */
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("Unmarshaling failed: %v", err)
+	}
+	if fields["created_at"] != "2024-01-01T00:30:00Z" {
+		t.Errorf("Expected created_at %q, got %v", "2024-01-01T00:30:00Z", fields["created_at"])
+	}
+	if fields["updated_at"] != "0001-01-01T00:00:00Z" {
+		t.Errorf("Expected the zero updated_at %q, got %v", "0001-01-01T00:00:00Z", fields["updated_at"])
+	}
+	/* 3. And it's read back as the same instant (to the second) */
+	var decoded Book
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("Decoding failed: %v", err)
+	}
+	if !decoded.CreatedAt.Equal(book.CreatedAt.Truncate(time.Second)) {
+		t.Errorf("Expected %v, got %v", book.CreatedAt.Truncate(time.Second), decoded.CreatedAt)
+	}
+}
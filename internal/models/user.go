@@ -6,10 +6,10 @@ package models
 
 /* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
 /* 1. Omitting Go Struct Fields from JSON
-		- When a field/property of a Go Struct has to be kept secret and, hence, not included in the encoded JSON
-		  object returned to the client via HTTP Response (e.g. Password) the json tag we need to use is as follows
-		  	-> `json:"-"`
-
+- When a field/property of a Go Struct has to be kept secret and, hence, not included in the encoded JSON
+  object returned to the client via HTTP Response (e.g. Password) the json tag we need to use is as follows
+  	-> `json:"-"`
+*/
 
 // 1. IMPORT PACKAGES *********************************************************************************************
 /* No need to import any package in this case */
@@ -21,11 +21,105 @@ type User struct { /* 				>>>>> SWAGGER <<<<< */
 	ID       int    `json:"id" example:"1"`                       /* User's unique id */
 	Role     string `json:"role" example:"user"`                  /* User's role for authorization */
 	Email    string `json:"email" example:"john.golan@gmail.com"` /* User's email address */
-	Password string `json:"-" example:"secretwordXXX`             // omit from JSON Responses!!
+	Password string `json:"-" example:"secretwordXXX"`            // omit from JSON Responses!!
+	Active   bool   `json:"active" example:"true"`                /* false = suspended by an admin */
+}
+
+/* Roles a user can have */
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+/* Roles allowed per capability, used by the routes AND by GET /me/permissions so that frontend and backend agree */
+var (
+	AdminRoles      = []string{RoleAdmin} /* /admin/... routes and GET /health/detailed */
+	TransferRoles   = []string{RoleAdmin} /* POST /books/transfer and /books/transfer/batch */
+	DeleteBookRoles = []string{RoleAdmin} /* DELETE /books/{id} (on top of the ownership check) */
+)
+
+/* Capabilities of the authenticated user [GET /me/permissions] */
+type Permissions struct { /* 	>>>>> SWAGGER <<<<< */
+	UserID         int    `json:"user_id" example:"1"`
+	Role           string `json:"role" example:"user"`
+	CanTransfer    bool   `json:"can_transfer" example:"false"`     /* POST /books/transfer(/batch) */
+	CanDeleteBooks bool   `json:"can_delete_books" example:"false"` /* DELETE /books/{id} of their own books */
+	CanAdmin       bool   `json:"can_admin" example:"false"`        /* /admin/... routes */
 }
 
 /* Register Request */
 type RegisterRequest struct { /* 	>>>>> SWAGGER <<<<< */
-	Email    string `json:"email" example:"john.golan@gmail.com"` /* User's email address */
-	Password string `json:"password" example:"secretwordXXX`      /* User's login password */
+	Email      string `json:"email" example:"john.golan@gmail.com"`                   /* User's email address */
+	Password   string `json:"password" example:"secretwordXXX"`                       /* User's login password */
+	InviteCode string `json:"invite_code" example:"3f9c2a7d41b8e6f05c1d9a2b7e4f8c30"` /* Single-use code from POST /admin/invites */
+}
+
+/* Invite code allowing one registration [POST /admin/invites] */
+type Invite struct { /* 	>>>>> SWAGGER <<<<< */
+	Code      string  `json:"code" example:"3f9c2a7d41b8e6f05c1d9a2b7e4f8c30"`                                   /* Code to send to the invited user */
+	CreatedAt APITime `json:"created_at" example:"2024-01-15T10:30:00Z" swaggertype:"string" format:"date-time"` /* Creation date */
+}
+
+/* Request Body of POST /admin/invites (an empty Body generates one code) */
+type InviteRequest struct { /* 	>>>>> SWAGGER <<<<< */
+	Count int `json:"count" example:"5"` /* Number of codes to generate (1-100) */
+}
+
+/* Request Body of POST /admin/users/roles */
+type RoleAssignmentRequest struct { /* 	>>>>> SWAGGER <<<<< */
+	IDs  []int  `json:"ids" example:"2,3,5"`  /* Users to update (1-100) */
+	Role string `json:"role" example:"admin"` /* New role of all of them: user or admin */
+}
+
+/* Response of POST /admin/users/{id}/suspend and /unsuspend */
+type UserActiveState struct { /* 	>>>>> SWAGGER <<<<< */
+	ID     int  `json:"id" example:"2"`
+	Active bool `json:"active" example:"false"` /* false = suspended */
+}
+
+/* User Export - Response of GET /me/export: all the data of the user (GDPR data portability) */
+type UserExport struct { /* 	>>>>> SWAGGER <<<<< */
+	ExportedAt APITime  `json:"exported_at" example:"2024-01-01T00:00:00Z" swaggertype:"string" format:"date-time"`
+	Profile    User     `json:"profile"`
+	Books      []Book   `json:"books"`     /* Books owned by the user */
+	Favorites  []Book   `json:"favorites"` /* Books favorited by the user */
+	Reviews    []Review `json:"reviews"`   /* Reviews written by the user */
+	/* Audit trail of the user, newest first (every entry, not one page as GET /me/activity) */
+	Activity []AuditEntry `json:"activity"`
+}
+
+/* Response of POST /admin/users/{id}/impersonate */
+type ImpersonationToken struct { /* 	>>>>> SWAGGER <<<<< */
+	Token          string  `json:"token"`
+	UserID         int     `json:"user_id" example:"2"`                                                               /* User the token acts as */
+	ImpersonatedBy int     `json:"impersonated_by" example:"1"`                                                       /* Admin who asked for it */
+	ExpiresAt      APITime `json:"expires_at" example:"2024-01-01T00:15:00Z" swaggertype:"string" format:"date-time"` /* Short-lived: IMPERSONATION_TTL */
+}
+
+/* Response of POST /admin/users/roles */
+type RoleAssignmentResult struct { /* 	>>>>> SWAGGER <<<<< */
+	Updated int `json:"updated" example:"3"` /* Number of users actually found and updated */
+}
+
+/* Number of books owned by one user [GET /admin/stats/books-per-user] */
+type UserBookCount struct { /* 	>>>>> SWAGGER <<<<< */
+	UserID    int    `json:"user_id" example:"1"`                  /* User's unique id */
+	Email     string `json:"email" example:"john.golan@gmail.com"` /* User's email address */
+	BookCount int    `json:"book_count" example:"12"`              /* Number of books owned by the user */
+}
+
+/* Outcome of one row of the bulk user import [POST /admin/users/import] */
+const (
+	ImportCreated = "created" /* User registered */
+	ImportSkipped = "skipped" /* Email already registered (or repeated in the same file) */
+	ImportFailed  = "failed"  /* Invalid row (e.g. missing email or password) */
+)
+
+/* Result of one row of the bulk user import [POST /admin/users/import] */
+type UserImportResult struct { /* >>>>> SWAGGER <<<<< */
+	Row    int    `json:"row" example:"1"`                                        /* 1-based row number (header excluded) */
+	Email  string `json:"email" example:"john.golan@gmail.com"`                   /* Email of the row */
+	Status string `json:"status" example:"created"`                               /* created, skipped or failed */
+	ID     int    `json:"id,omitempty" example:"6"`                               /* Id of the created user */
+	Reason string `json:"reason,omitempty" example:"Email is already registered"` /* Why the row was not created */
 }
@@ -25,12 +25,56 @@ package models
 
 /* Success Response */
 type SuccessResponse struct { /* 	>>>>> SWAGGER <<<<< */
-	Data interface{} `json:"data" example"{id:1, title:"The Fractal Brain Theory", author:"Tsang", pages:"500}"`
+	Data interface{} `json:"data"`
 	Meta interface{} `json:"meta"`
 }
 
+/* Pagination - Meta of the paginated list responses */
+type Pagination struct { /* 		>>>>> SWAGGER <<<<< */
+	Total  int `json:"total" example:"42"` /* Number of items matching the request (all pages) */
+	Limit  int `json:"limit" example:"20"` /* Max number of items per page */
+	Offset int `json:"offset" example:"0"` /* Number of items skipped */
+}
+
 /* Error Response */
 type ErrorResponse struct { /* 	>>>>> SWAGGER <<<<< */
 	Error   string `json:"error"`                             /* Stringified Error Object */
 	Message string `json:"message" example:"Book not found."` /* Customized Error Message */
+	/* Validation failures keyed by JSON field name (validation errors only) */
+	Fields map[string]string `json:"fields,omitempty" example:"to_id:Receiver Book ID is invalid"`
+}
+
+/* Maintenance State [GET/POST /admin/maintenance] */
+type MaintenanceState struct { /* 	>>>>> SWAGGER <<<<< */
+	Enabled *bool `json:"enabled" example:"true"` /* Whether maintenance mode is on (required in POST Requests) */
+}
+
+/* Connection Pool Statistics [GET /admin/db-stats] - subset of sql.DBStats */
+type DBStats struct { /* 	>>>>> SWAGGER <<<<< */
+	MaxOpenConnections int   `json:"max_open_connections" example:"10"` /* Pool size limit (0 = unlimited) */
+	OpenConnections    int   `json:"open_connections" example:"4"`      /* In use + idle */
+	InUse              int   `json:"in_use" example:"1"`
+	Idle               int   `json:"idle" example:"3"`
+	WaitCount          int64 `json:"wait_count" example:"0"`          /* Total number of waits for a free connection */
+	WaitDurationMs     int64 `json:"wait_duration_ms" example:"0"`    /* Total time spent waiting for a free connection */
+	TransfersInFlight  int   `json:"transfers_in_flight" example:"2"` /* Transfer transactions running (TRANSFER_CONCURRENCY) */
+	TransfersQueued    int   `json:"transfers_queued" example:"0"`    /* Transfers waiting for a slot (queue depth) */
+}
+
+/* Registered Route [GET /admin/routes] */
+type RouteInfo struct { /* 	>>>>> SWAGGER <<<<< */
+	Method  string   `json:"method" example:"DELETE"`
+	Pattern string   `json:"pattern" example:"/books/{id}/"`
+	Roles   []string `json:"roles" example:"admin"` /* Roles allowed by the ROLE-BASED AUTH (empty = no role restriction) */
+}
+
+/* Problem Details (RFC 7807) - Error Response sent when the client asks for application/problem+json */
+type ProblemDetails struct { /* 	>>>>> SWAGGER <<<<< */
+	Type     string `json:"type" example:"about:blank"`       /* URI identifying the problem type */
+	Title    string `json:"title" example:"Not Found"`        /* Short summary of the problem type */
+	Status   int    `json:"status" example:"404"`             /* HTTP Status Code */
+	Detail   string `json:"detail" example:"Book Not Found."` /* Explanation specific to this occurrence */
+	Instance string `json:"instance" example:"/books/42"`     /* Path of the HTTP Request that caused it */
+	/* Extension member: validation failures keyed by JSON field name (validation errors only) */
+	Fields map[string]string `json:"fields,omitempty" example:"to_id:Receiver Book ID is invalid"`
 }
@@ -0,0 +1,114 @@
+package repositories
+
+// repositories/ PACKAGE **********************************************************************************************
+/* The repositories/ package is used to store all the objects definitions and all the methods that are used to execute
+   SQL Queries on the connected Database for all CRUD Operations (Create, Read, Update, Delete)
+   This package is responsible for DATABASE ACCESS LOGIC. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Append-Only
+		- The audit_log table only gets INSERTs: there's no method to update or delete its rows.
+   2. Newest First
+		- Entries are read ordered by created_at DESC (then id DESC, for entries recorded in the same instant),
+		  which is also the order of the (user_id, created_at DESC) index.
+   3. Row by Row Export
+		- ForEach hands the entries to its callback one row at a time (oldest first) instead of returning a list,
+		  so that exporting the whole log never holds it in memory.
+*/
+
+// 1. IMPORT PACKAGES *************************************************************************************************
+import (
+	"bookapi/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// 2. GO STRUCTS and UTILITY VARIABLES ********************************************************************************
+
+/* STRUCT */
+type AuditRepository struct {
+	DB *sql.DB
+}
+
+/* STRUCT BUILDER */
+func NewAuditRepository(db *sql.DB) (*AuditRepository, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+	return &AuditRepository{DB: db}, nil
+}
+
+// 3. QUERY METHODS ***************************************************************************************************
+
+/* CREATE - record one action -----------------------------------------------------------------------------------*/
+func (r *AuditRepository) Create(ctx context.Context, entry models.AuditEntry) error {
+	_, err := r.DB.ExecContext(ctx, `INSERT INTO audit_log (user_id, action, resource, resource_id, impersonated_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0))`, entry.UserID, entry.Action, entry.Resource, entry.ResourceID,
+		entry.ImpersonatedBy)
+	return err
+}
+
+/* READ BY USER - [GET /me/activity HTTP Method] ----------------------------------------------------------------*/
+/* One page of the entries of the user, newest first (limit 0 -> every entry, for GET /me/export) */
+func (r *AuditRepository) FindByUser(ctx context.Context, userID, limit, offset int) ([]models.AuditEntry, error) {
+	/* 1. Execute the SQL Query expecting a list of DB Table Rows, newest first */
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, user_id, action, resource, COALESCE(resource_id, 0),
+		COALESCE(impersonated_by, 0), created_at FROM audit_log WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT NULLIF($2, 0) OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	/* 2. Make sure that the DB Table Rows get CLOSED when the current function finishes */
+	defer rows.Close()
+	/* 3. Create an empty list (encoded as [] and not null) and fill it looping through the rows */
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Action, &e.Resource, &e.ResourceID, &e.ImpersonatedBy,
+			&e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	/* 4. Checks if there were any errors while reading the rows, then return the list */
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+/* COUNT BY USER - [GET /me/activity HTTP Method] ---------------------------------------------------------------*/
+/* Number of entries of the user (used to paginate) */
+func (r *AuditRepository) CountByUser(ctx context.Context, userID int) (int, error) {
+	var total int
+	err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log WHERE user_id = $1`, userID).Scan(&total)
+	return total, err
+}
+
+/* FOR EACH - [GET /admin/audit/export HTTP Method] -------------------------------------------------------------*/
+/* Call fn on every entry created within the optional [from, to] range (nil -> unbounded), oldest first, stopping
+   at the first error (see IMPORTANT NOTES 3.) */
+func (r *AuditRepository) ForEach(ctx context.Context, from, to *time.Time, fn func(models.AuditEntry) error) error {
+	/* 1. Execute the SQL Query expecting a list of DB Table Rows, oldest first */
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, user_id, action, resource, COALESCE(resource_id, 0),
+		COALESCE(impersonated_by, 0), created_at FROM audit_log WHERE created_at BETWEEN COALESCE($1::timestamptz, '-infinity')
+		AND COALESCE($2::timestamptz, 'infinity') ORDER BY created_at ASC, id ASC`, from, to)
+	if err != nil {
+		return err
+	}
+	/* 2. Make sure that the DB Table Rows get CLOSED when the current function finishes */
+	defer rows.Close()
+	/* 3. Hand every row to the callback as soon as it's read */
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Action, &e.Resource, &e.ResourceID, &e.ImpersonatedBy,
+			&e.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	/* 4. Checks if there were any errors while reading the rows */
+	return rows.Err()
+}
@@ -20,56 +20,187 @@ package repositories
 		  that it implements all the methods declared in the interface...and with the correct signature!!
    		  LET'S REMEMBER THAT, in GO, NON-STATIC METHODS (I.E. CLASSES METHODS) ARE DEFINED SPECIFYING A POINTER
 		  TO THE CORRESPONDING GO STRUCT / CLASS BEFORE THE NAME OF THE METHOD! SEE THE QUERY CRUD METHODS BELOW !
+   3. Context-Aware Queries
+		- Every method takes the context of the HTTP Request (ctx) as first input and runs its SQL Queries with the
+		  ...Context(ctx, ..) variants of the database/sql methods. In this way, when the context gets cancelled
+		  (client gone, request timeout...) the running query gets cancelled too instead of holding a connection.
+   4. Read Replica (DB_READ_URL)
+		- The plain reads (FindAll, Count, FindByID, FindByIDs) run on ReadDB, everything else on the primary (DB).
+		  Without a replica ReadDB is the primary itself.
+		- A replica may lag a little behind the primary: methods reading back their own writes (e.g. Merge) MUST
+		  use the primary, otherwise they could return the book as it was before the write.
+   5. Prepared Statements
+		- The hot queries (FindByID, Create, Update, Delete) get prepared ONCE by NewBookRepository and the
+		  resulting *sql.Stmt are reused by every call. Without them lib/pq sends every parametrized query as
+		  Parse + Describe (one round-trip) and then Bind + Execute (a second round-trip), with the Database
+		  parsing and planning the SQL text each time; a prepared statement skips the first round-trip.
+		  The gain is then one network round-trip (plus the parsing) per call, so it grows with the latency between
+		  the API and the Database. Measure it on the actual deployment with BenchmarkFindByID (prepared vs
+		  unprepared FindByID, see book_repository_test.go) rather than relying on an estimate.
+		- *sql.Stmt is safe for concurrent use: database/sql re-prepares it transparently on the pooled
+		  connections that don't have it yet.
+		- The statements MUST be closed via Close() on shutdown (see router.go).
 */
 
 // 1. IMPORT PACKAGES **********************************************************************************************
 import (
 	"bookapi/internal/models"
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 // 2. GO STRUCTS and UTILITY VARIABLES ********************************************************************************
 
 /* Interface */
 type BookRepository interface {
-	Create(book models.Book) (models.Book, error)
-	FindAll() ([]models.Book, error)
-	FindByID(id int) (*models.Book, error)
-	Update(id int, book models.Book) (*models.Book, error)
-	Delete(id int) error
-	TransferPages(req models.TransferRequest) error
-	GetOwnerID(bookID int) (int, error)
+	Create(ctx context.Context, book models.Book) (models.Book, error)
+	FindAll(ctx context.Context, filter models.BookFilter) ([]models.Book, error)
+	Search(ctx context.Context, query string) ([]models.Book, error)
+	Count(ctx context.Context, filter models.BookFilter) (int, error)
+	FindByQuery(ctx context.Context, q models.BookQuery) ([]models.Book, error)
+	CountByQuery(ctx context.Context, q models.BookQuery) (int, error)
+	FindByID(ctx context.Context, id int) (*models.Book, error)
+	FindByIDs(ctx context.Context, ids []int) ([]models.Book, error)
+	Update(ctx context.Context, id int, book models.Book) (*models.Book, error)
+	Delete(ctx context.Context, id int) error
+	TransferPages(ctx context.Context, req models.TransferRequest) error
+	TransferPagesBatch(ctx context.Context, transfers []models.TransferRequest) error
+	Merge(ctx context.Context, intoID, fromID, ownerID int) (*models.Book, error)
+	GetOwnerID(ctx context.Context, bookID int) (int, error)
+	FindAuthors(ctx context.Context, prefix string, ownerID int) ([]string, error)
+	UpdatePages(ctx context.Context, updates []models.PagesUpdate, ownerID int) (models.BulkUpdateResult, error)
+	UpdateIfUnmodifiedSince(ctx context.Context, id int, book models.Book, since time.Time) (*models.Book, error)
+	Upsert(ctx context.Context, book models.Book) (models.Book, bool, error)
+	UpsertReview(ctx context.Context, review models.Review) (models.Review, bool, error)
+	FindReviews(ctx context.Context, bookID, limit, offset int) ([]models.Review, int, error)
+	AddFavorite(ctx context.Context, userID, bookID int) error
+	RemoveFavorite(ctx context.Context, userID, bookID int) error
+	FindFavorites(ctx context.Context, userID int) ([]models.Book, error)
+	FindByOwner(ctx context.Context, ownerID int) ([]models.Book, error)
+	FindReviewsByUser(ctx context.Context, userID int) ([]models.Review, error)
+	FindChangedSince(ctx context.Context, since time.Time) ([]models.Book, time.Time, error)
+	SumPagesByOwner(ctx context.Context, ownerID int) (int, error)
+	FindOwnedIDs(ctx context.Context, ids []int, ownerID int) ([]int, error)
+	FindPopular(ctx context.Context, limit, offset int) ([]models.PopularBook, int, error)
+}
+
+/* Errors */
+/* Returned when the caller tries to modify a book they don't own */
+var ErrNotOwner = errors.New("Forbidden: not owner")
+
+/* Returned when the requested book doesn't exist */
+var ErrBookNotFound = errors.New("Book not found.")
+
+/* Returned when a conditional update finds the book modified after the given date */
+var ErrPreconditionFailed = errors.New("Book has been modified since the given date")
+
+/* Creation date range of GET /books ($1 = from, $2 = to): a NULL bound becomes -infinity/infinity, i.e. no bound */
+const createdRangeClause = `b.created_at BETWEEN COALESCE($1::timestamptz, '-infinity')
+		AND COALESCE($2::timestamptz, 'infinity')`
+
+/* Average rating + number of reviews of every reviewed book in ONE pass, to LEFT JOIN to the books (alias b) */
+const avgRatingJoin = `LEFT JOIN (SELECT book_id, ROUND(AVG(rating), 2)::float8 AS avg_rating,
+		COUNT(*) AS review_count FROM reviews GROUP BY book_id) ra ON ra.book_id = b.id`
+
+/* Store the average rating read from the Database into the book (NULL -> no reviews -> nil) */
+func setAvgRating(book *models.Book, avg sql.NullFloat64) {
+	if avg.Valid {
+		book.AvgRating = &avg.Float64
+	}
 }
 
 /* Struct */
 type PgBookRepository struct {
-	DB *sql.DB
+	DB     *sql.DB /* Primary: writes and reads that must see them */
+	ReadDB *sql.DB /* Read Replica (or the primary itself): plain reads */
+	/* Prepared Statements of the hot queries (see IMPORTANT NOTES 5.) */
+	findByIDStmt *sql.Stmt /* on ReadDB */
+	createStmt   *sql.Stmt /* on DB */
+	updateStmt   *sql.Stmt /* on DB */
+	deleteStmt   *sql.Stmt /* on DB */
+}
+
+/* Queries of the Prepared Statements */
+const (
+	findByIDQuery = `SELECT b.id, b.title, b.author, b.pages, COALESCE(b.year, 0),
+		b.created_at, b.updated_at, ra.avg_rating FROM books b ` + avgRatingJoin + ` WHERE b.id = $1`
+	createQuery = `INSERT INTO books (title, author, pages, year, owner_id) VALUES ($1, $2, $3, NULLIF($4, 0), $5)
+			  RETURNING id, created_at, updated_at`
+	updateQuery = `UPDATE books SET title=$1, author=$2, pages=$3, year=NULLIF($5, 0), updated_at=now() WHERE id=$4
+			  RETURNING created_at, updated_at`
+	deleteQuery = `DELETE FROM books WHERE id = $1`
+)
+
+/* Struct Builder (nil readDB = reads on the primary) - prepares the hot queries + Error Handling (e.g. no Database) */
+func NewBookRepository(db, readDB *sql.DB) (*PgBookRepository, error) {
+	/* 1. Fail fast without a primary + fall back to the primary for the reads */
+	if db == nil {
+		return nil, ErrNilDB
+	}
+	if readDB == nil {
+		readDB = db
+	}
+	r := &PgBookRepository{DB: db, ReadDB: readDB}
+	/* 2. Prepare the statements, closing the ones already prepared if one of them fails */
+	prepare := func(db *sql.DB, query string, stmt **sql.Stmt) error {
+		prepared, err := db.Prepare(query)
+		if err != nil {
+			return fmt.Errorf("Could not prepare statement: %w", err)
+		}
+		*stmt = prepared
+		return nil
+	}
+	err := errors.Join(
+		prepare(readDB, findByIDQuery, &r.findByIDStmt),
+		prepare(db, createQuery, &r.createStmt),
+		prepare(db, updateQuery, &r.updateStmt),
+		prepare(db, deleteQuery, &r.deleteStmt),
+	)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
 }
 
-/* Struct Builder */
-func NewBookRepository(db *sql.DB) BookRepository {
-	return &PgBookRepository{DB: db}
+/* Close the Prepared Statements (the connection pools are NOT closed: they are owned by the caller) */
+func (r *PgBookRepository) Close() error {
+	var errs []error
+	for _, stmt := range []*sql.Stmt{r.findByIDStmt, r.createStmt, r.updateStmt, r.deleteStmt} {
+		if stmt != nil {
+			errs = append(errs, stmt.Close())
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // 3. QUERY CRUD METHODS **********************************************************************************************
 
 /* CREATE - [POST /books HTTP Method] ---------------------------------------------------------------------------*/
-func (r *PgBookRepository) Create(book models.Book) (models.Book, error) {
-	/* 1. Build the SQL Query */
-	query := `INSERT INTO books (title, author, pages, owner_id) VALUES ($1, $2, $3, $4) RETURNING id`
-	/* 3. Execute the SQL Query expecting one single row from the DB Table, fill the placeholders
-	      in the SQL query with the listed input values and finally read the returned id and
-		  store it in book.ID */
-	err := r.DB.QueryRow(query, book.Title, book.Author, book.Pages, book.OwnerID).Scan(&book.ID)
-	/* 4. Return the udpated book object and any error that might occur. */
-	return book, err
+func (r *PgBookRepository) Create(ctx context.Context, book models.Book) (models.Book, error) {
+	/* 1. Execute the Prepared Statement (see createQuery) expecting one single row from the DB Table, fill the
+	      placeholders in the SQL query with the listed input values and finally read the returned id and
+		  timestamps and store them in the book object */
+	err := r.createStmt.QueryRowContext(ctx, book.Title, book.Author, book.Pages, book.Year, book.OwnerID).
+		Scan(&book.ID, &book.CreatedAt, &book.UpdatedAt)
+	/* 2. Return the udpated book object and any error that might occur (unique violations as typed error). */
+	return book, translateUniqueViolation(err)
 }
 
 /* READ ALL - [GET /books HTTP Method] -------------------------------------------------------------------------*/
-func (r *PgBookRepository) FindAll() ([]models.Book, error) {
-	/* 1. Execute the SQL Query expecting a list of DB Table Rows */
-	rows, err := r.DB.Query("SELECT id, title, author, pages FROM books ORDER BY id ASC")
+func (r *PgBookRepository) FindAll(ctx context.Context, filter models.BookFilter) ([]models.Book, error) {
+	/* 1. Execute the SQL Query expecting a list of DB Table Rows (Limit 0 -> LIMIT NULL, i.e. no limit) */
+	rows, err := r.ReadDB.QueryContext(ctx, `SELECT b.id, b.title, b.author, b.pages, COALESCE(b.year, 0),
+		COALESCE(b.owner_id, 0), b.created_at, b.updated_at, ra.avg_rating, COALESCE(ra.review_count, 0)
+		FROM books b `+avgRatingJoin+`
+		WHERE `+createdRangeClause+` ORDER BY b.id ASC LIMIT NULLIF($3, 0) OFFSET $4`,
+		filter.CreatedFrom, filter.CreatedTo, filter.Limit, filter.Offset)
 	/* 2. If an error occurs, return null list together with encountered error */
 	if err != nil {
 		return nil, err
@@ -77,20 +208,28 @@ func (r *PgBookRepository) FindAll() ([]models.Book, error) {
 	/* 3. Make sure that the DB Table Rows get CLOSED when the current function
 	   finishes in order to avoid locked memory */
 	defer rows.Close()
-	/* 4. Create an empty list to store the book objects extracted from the DB Table */
-	var books []models.Book
+	/* 4. Create an empty list (encoded as [] and not null) to store the book objects extracted from the DB Table */
+	books := []models.Book{}
 	/* 5. Looping through the rows of the DB Table, extract the field values and store
 	      them in the corresponding attributes of each new book object that gets then
 		  addedd to the books list. */
 	for rows.Next() {
 		/* Create a new book struct instance */
 		var b models.Book
+		var avg sql.NullFloat64
+		var reviewCount int
 		/* Get data from the DB Table row and assign it to the book object */
-		err := rows.Scan(&b.ID, &b.Title, &b.Author, &b.Pages)
+		err := rows.Scan(&b.ID, &b.Title, &b.Author, &b.Pages, &b.Year, &b.OwnerID, &b.CreatedAt, &b.UpdatedAt,
+			&avg, &reviewCount)
 		/* Return an error if an error occurs in the process. */
 		if err != nil {
 			return nil, err
 		}
+		setAvgRating(&b, avg)
+		/* The review count is part of the response only when asked for (include=reviews_summary) */
+		if filter.IncludeReviewsSummary {
+			b.ReviewCount = &reviewCount
+		}
 		/* Add the built book object to the list */
 		books = append(books, b)
 	}
@@ -102,10 +241,207 @@ func (r *PgBookRepository) FindAll() ([]models.Book, error) {
 	return books, nil
 }
 
+/* SEARCH - [GET /books?q= HTTP Method] -----------------------------------------------------------------------*/
+/* Books whose title or author contains the query (case insensitive), sorted by id. The % and _ of the query are
+   escaped (see escapeLike): they match themselves, not any character(s). */
+func (r *PgBookRepository) Search(ctx context.Context, query string) ([]models.Book, error) {
+	/* 1. Execute the SQL Query with the escaped query wrapped in wildcards */
+	rows, err := r.ReadDB.QueryContext(ctx, `SELECT b.id, b.title, b.author, b.pages, COALESCE(b.year, 0),
+		b.created_at, b.updated_at, ra.avg_rating FROM books b `+avgRatingJoin+`
+		WHERE b.title ILIKE $1 ESCAPE '\' OR b.author ILIKE $1 ESCAPE '\' ORDER BY b.id ASC`,
+		"%"+escapeLike(query)+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	/* 2. Create an empty list (encoded as [] and not null) and fill it looping through the rows */
+	books := []models.Book{}
+	for rows.Next() {
+		var b models.Book
+		var avg sql.NullFloat64
+		if err := rows.Scan(&b.ID, &b.Title, &b.Author, &b.Pages, &b.Year, &b.CreatedAt, &b.UpdatedAt, &avg); err != nil {
+			return nil, err
+		}
+		setAvgRating(&b, avg)
+		books = append(books, b)
+	}
+	/* 3. Checks if there were any errors while reading the rows, then return the list */
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+/* Escape the LIKE wildcards (% and _) and the escape character itself (\), so that they match literally */
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+/* Extra time GET /books/changes goes back: covers the writes pg_stat_activity can't see (other roles, commits) */
+const changesSafetyMargin = time.Minute
+
+/* FIND CHANGED SINCE - [GET /books/changes HTTP Method] --------------------------------------------------------*/
+/* Books created or updated at or after since, oldest change first, together with the since of the next call
+   (synced). It reads the PRIMARY (not the replica): a lagging replica would hide the latest changes, and the client
+   would never ask for them again with its next since.
+   A row gets updated_at = now(), i.e. the START of its transaction, but only becomes visible at its COMMIT: a
+   transaction still running now may commit later with an updated_at in the past. Hence synced comes from the
+   Database clock and goes back to the start of the oldest open transaction (and at least changesSafetyMargin):
+   whatever is committed after this call has an updated_at >= synced. It is read BEFORE the books, so that a
+   transaction committing in between is either in the books or still open when synced is taken. */
+func (r *PgBookRepository) FindChangedSince(ctx context.Context, since time.Time) ([]models.Book, time.Time, error) {
+	/* 1. Read the since of the next call (LEAST ignores the NULL of no open transaction) */
+	var synced time.Time
+	err := r.DB.QueryRowContext(ctx, `SELECT LEAST(now() - make_interval(secs => $1),
+		(SELECT MIN(xact_start) FROM pg_stat_activity WHERE pid <> pg_backend_pid()))`,
+		changesSafetyMargin.Seconds()).Scan(&synced)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	/* 2. Execute the SQL Query expecting a list of DB Table Rows (books_updated_at_idx) */
+	rows, err := r.DB.QueryContext(ctx, `SELECT b.id, b.title, b.author, b.pages, COALESCE(b.year, 0),
+		b.created_at, b.updated_at, ra.avg_rating FROM books b `+avgRatingJoin+`
+		WHERE b.updated_at >= $1 ORDER BY b.updated_at ASC, b.id ASC`, since)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer rows.Close()
+	/* 3. Create an empty list (encoded as [] and not null) and fill it looping through the rows */
+	books := []models.Book{}
+	for rows.Next() {
+		var b models.Book
+		var avg sql.NullFloat64
+		if err := rows.Scan(&b.ID, &b.Title, &b.Author, &b.Pages, &b.Year, &b.CreatedAt, &b.UpdatedAt, &avg); err != nil {
+			return nil, time.Time{}, err
+		}
+		setAvgRating(&b, avg)
+		books = append(books, b)
+	}
+	/* 4. Checks if there were any errors while reading the rows, then return the list */
+	if err := rows.Err(); err != nil {
+		return nil, time.Time{}, err
+	}
+	return books, synced, nil
+}
+
+/* COUNT - [GET /books HTTP Method] -----------------------------------------------------------------------------*/
+/* Number of books matching the filter, ignoring Limit and Offset (used to paginate) */
+func (r *PgBookRepository) Count(ctx context.Context, filter models.BookFilter) (int, error) {
+	var total int
+	err := r.ReadDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM books b WHERE `+createdRangeClause,
+		filter.CreatedFrom, filter.CreatedTo).Scan(&total)
+	return total, err
+}
+
+/* Sortable fields of POST /books/query -> SQL column: the ORDER BY is built ONLY from these values, never the input */
+var bookQuerySortColumns = map[string]string{
+	"id":         "b.id",
+	"title":      "b.title",
+	"author":     "b.author",
+	"pages":      "b.pages",
+	"year":       "b.year",
+	"created_at": "b.created_at",
+}
+
+/* Whether the input sort value (e.g. "-pages") is supported by FindByQuery */
+func IsBookQuerySortable(sort string) bool {
+	_, ok := bookQuerySortColumns[strings.TrimPrefix(sort, "-")]
+	return sort == "" || ok
+}
+
+/* Build the ORDER BY of POST /books/query from the (validated) sort, b.id last so that pages never skip/repeat rows */
+func bookQueryOrderBy(sort string) string {
+	column, ok := bookQuerySortColumns[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return "b.id ASC"
+	}
+	direction := "ASC"
+	if strings.HasPrefix(sort, "-") {
+		direction = "DESC"
+	}
+	if column == "b.id" {
+		return "b.id " + direction /* ids are unique: no tie-breaker needed */
+	}
+	return column + " " + direction + " NULLS LAST, b.id ASC"
+}
+
+/* Build the WHERE clause of POST /books/query: one condition per filter, values ONLY as placeholders ($1, $2...) */
+func bookQueryWhere(q models.BookQuery) (string, []any) {
+	conditions, args := []string{"TRUE"}, []any{}
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if q.Author != "" {
+		add("lower(b.author) = lower($%d)", q.Author)
+	}
+	if q.TitleContains != "" {
+		add("strpos(lower(b.title), lower($%d)) > 0", q.TitleContains) /* No LIKE: no wildcards to escape */
+	}
+	if q.PagesMin != nil {
+		add("b.pages >= $%d", *q.PagesMin)
+	}
+	if q.PagesMax != nil {
+		add("b.pages <= $%d", *q.PagesMax)
+	}
+	if q.YearMin != nil {
+		add("b.year >= $%d", *q.YearMin)
+	}
+	if q.YearMax != nil {
+		add("b.year <= $%d", *q.YearMax)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+/* QUERY - [POST /books/query HTTP Method] ----------------------------------------------------------------------*/
+/* Books matching the (already validated) query object, sorted and paginated */
+func (r *PgBookRepository) FindByQuery(ctx context.Context, q models.BookQuery) ([]models.Book, error) {
+	/* 1. Build the WHERE and ORDER BY clauses (id as tie-breaker, so that the pages are stable) */
+	where, args := bookQueryWhere(q)
+	orderBy := bookQueryOrderBy(q.Sort)
+	args = append(args, q.Limit, q.Offset)
+	/* 2. Execute the SQL Query expecting a list of DB Table Rows */
+	rows, err := r.ReadDB.QueryContext(ctx, fmt.Sprintf(`SELECT b.id, b.title, b.author, b.pages, COALESCE(b.year, 0),
+		b.created_at, b.updated_at, ra.avg_rating FROM books b `+avgRatingJoin+`
+		WHERE %s ORDER BY %s LIMIT $%d OFFSET $%d`, where, orderBy, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	/* 3. Make sure that the DB Table Rows get CLOSED when the current function finishes */
+	defer rows.Close()
+	/* 4. Create an empty list (encoded as [] and not null) and fill it looping through the rows */
+	books := []models.Book{}
+	for rows.Next() {
+		var b models.Book
+		var avg sql.NullFloat64
+		if err := rows.Scan(&b.ID, &b.Title, &b.Author, &b.Pages, &b.Year, &b.CreatedAt, &b.UpdatedAt, &avg); err != nil {
+			return nil, err
+		}
+		setAvgRating(&b, avg)
+		books = append(books, b)
+	}
+	/* 5. Checks if there were any errors while reading the rows, then return the list */
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+/* COUNT BY QUERY - [POST /books/query HTTP Method] -------------------------------------------------------------*/
+/* Number of books matching the query object, ignoring Sort, Limit and Offset (used to paginate) */
+func (r *PgBookRepository) CountByQuery(ctx context.Context, q models.BookQuery) (int, error) {
+	where, args := bookQueryWhere(q)
+	var total int
+	err := r.ReadDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM books b WHERE `+where, args...).Scan(&total)
+	return total, err
+}
+
 /* TRANSFER - [POST /transfer HTTP Method] -------------------------------------------------------------------------*/
-func (r *PgBookRepository) TransferPages(req models.TransferRequest) error {
+func (r *PgBookRepository) TransferPages(ctx context.Context, req models.TransferRequest) error {
 	/* 1. Start a new DB Transaction using the Go's standard library database/sql  + Error Handling */
-	tx, err := r.DB.Begin()
+	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -124,14 +460,14 @@ func (r *PgBookRepository) TransferPages(req models.TransferRequest) error {
 	}()
 
 	/* 3. Execute an SQL Query that subtracts the input fields' value from the book record having id=fromID */
-	_, err = tx.Exec(`UPDATE books SET pages = pages - $1 WHERE id = $2`, req.Pages, req.FromID)
+	_, err = tx.ExecContext(ctx, `UPDATE books SET pages = pages - $1, updated_at = now() WHERE id = $2`, req.Pages, req.FromID)
 	if err != nil {
 		/* If an error occurs, stop and send out the error. */
 		return err
 	}
 
 	/* 4. Execute an SQL Query that adds the input fields' value to the book record having id=toID */
-	_, err = tx.Exec(`UPDATE books SET pages = pages + $1 WHERE id = $2`, req.Pages, req.ToID)
+	_, err = tx.ExecContext(ctx, `UPDATE books SET pages = pages + $1, updated_at = now() WHERE id = $2`, req.Pages, req.ToID)
 	if err != nil {
 		/* If an error occurs, stop and send out the error. */
 		return err
@@ -141,19 +477,165 @@ func (r *PgBookRepository) TransferPages(req models.TransferRequest) error {
 	return nil
 }
 
+/* TRANSFER BATCH - [POST /books/transfer/batch HTTP Method] -----------------------------------------------------*/
+/* Applies all the transfers (in order) within one transaction: either all of them succeed or none does. The first
+   transfer failing (missing book or source with not enough pages) rolls back everything -> *TransferBatchError. */
+func (r *PgBookRepository) TransferPagesBatch(ctx context.Context, transfers []models.TransferRequest) error {
+	/* 1. Start a new DB Transaction + Error Handling */
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	/* 2. ROLLBACK the Transaction whenever the function returns before the COMMIT (no-op after the COMMIT) */
+	defer tx.Rollback()
+
+	/* 3. Loop through the transfers... */
+	for i, t := range transfers {
+		/* 3.1 Subtract the pages from the source, only if it has enough of them (the check sees the effects of
+		   the previous transfers of the batch) */
+		res, err := tx.ExecContext(ctx, `UPDATE books SET pages = pages - $1, updated_at = now()
+			WHERE id = $2 AND pages >= $1`, t.Pages, t.FromID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			/* No row updated: tell a missing book apart from a book with not enough pages */
+			var exists bool
+			if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM books WHERE id = $1)`, t.FromID).
+				Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				return &TransferBatchError{Index: i, Reason: fmt.Sprintf("Book %d not found", t.FromID)}
+			}
+			return &TransferBatchError{Index: i, Reason: fmt.Sprintf("Book %d has fewer than %d pages", t.FromID, t.Pages)}
+		}
+		/* 3.2 Add the pages to the destination */
+		res, err = tx.ExecContext(ctx, `UPDATE books SET pages = pages + $1, updated_at = now() WHERE id = $2`,
+			t.Pages, t.ToID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return &TransferBatchError{Index: i, Reason: fmt.Sprintf("Book %d not found", t.ToID)}
+		}
+	}
+
+	/* 4. COMMIT the Transaction */
+	return tx.Commit()
+}
+
+/* MERGE - [POST /books/{id}/merge HTTP Method] ------------------------------------------------------------------*/
+/* Adds the pages of book fromID to book intoID and deletes book fromID, in one transaction. Non-admin callers
+   (ownerID != 0) must own both books. Reviews and favorites of the deleted book go away with it (ON DELETE CASCADE). */
+func (r *PgBookRepository) Merge(ctx context.Context, intoID, fromID, ownerID int) (*models.Book, error) {
+	/* 1. Start a new DB Transaction + Error Handling */
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	/* 2. ROLLBACK the Transaction whenever the function returns before the COMMIT (no-op after the COMMIT) */
+	defer tx.Rollback()
+
+	/* 3. Lock both book rows (always in id order, to avoid deadlocks with a concurrent opposite merge) */
+	rows, err := tx.QueryContext(ctx, `SELECT id, owner_id, pages FROM books WHERE id IN ($1, $2)
+		ORDER BY id FOR UPDATE`, intoID, fromID)
+	if err != nil {
+		return nil, err
+	}
+	pages := map[int]int{}
+	for rows.Next() {
+		var id, bookOwnerID, bookPages int
+		if err := rows.Scan(&id, &bookOwnerID, &bookPages); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		/* 3.1 If the caller doesn't own one of the books, stop and ROLLBACK */
+		if ownerID != 0 && bookOwnerID != ownerID {
+			rows.Close()
+			return nil, ErrNotOwner
+		}
+		pages[id] = bookPages
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	/* 3.2 Both books must exist */
+	if len(pages) != 2 {
+		return nil, ErrBookNotFound
+	}
+
+	/* 4. Add the pages of the duplicate to the kept book, then delete the duplicate */
+	if _, err := tx.ExecContext(ctx, `UPDATE books SET pages = pages + $1, updated_at = now() WHERE id = $2`,
+		pages[fromID], intoID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM books WHERE id = $1`, fromID); err != nil {
+		return nil, err
+	}
+
+	/* 5. COMMIT the Transaction, then return the merged book */
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	/* Read back from the primary (see IMPORTANT NOTES 4.) */
+	return scanBook(r.DB.QueryRowContext(ctx, findByIDQuery, intoID))
+}
+
+/* READ BY IDS - [GET /books?ids=1,2,3 HTTP Method] ------------------------------------------------------------*/
+/* Books matching any of the input IDs, sorted by id. IDs not matching any book are simply absent. */
+func (r *PgBookRepository) FindByIDs(ctx context.Context, ids []int) ([]models.Book, error) {
+	/* 1. Execute the SQL Query passing the IDs as one PostgreSQL array parameter */
+	rows, err := r.ReadDB.QueryContext(ctx, `SELECT b.id, b.title, b.author, b.pages, COALESCE(b.year, 0),
+		b.created_at, b.updated_at, ra.avg_rating FROM books b `+avgRatingJoin+`
+		WHERE b.id = ANY($1) ORDER BY b.id ASC`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	/* 2. Make sure that the DB Table Rows get CLOSED when the current function finishes */
+	defer rows.Close()
+	/* 3. Create an empty list (encoded as [] and not null) and fill it looping through the rows */
+	books := []models.Book{}
+	for rows.Next() {
+		var b models.Book
+		var avg sql.NullFloat64
+		if err := rows.Scan(&b.ID, &b.Title, &b.Author, &b.Pages, &b.Year, &b.CreatedAt, &b.UpdatedAt, &avg); err != nil {
+			return nil, err
+		}
+		setAvgRating(&b, avg)
+		books = append(books, b)
+	}
+	/* 4. Checks if there were any errors while reading the rows, then return the list */
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
 /* READ BY ID - [GET /books/{id} HTTP Method] ------------------------------------------------------------------*/
-func (r *PgBookRepository) FindByID(id int) (*models.Book, error) {
+func (r *PgBookRepository) FindByID(ctx context.Context, id int) (*models.Book, error) {
+	return scanBook(r.findByIDStmt.QueryRowContext(ctx, id))
+}
+
+/* Read the book returned by findByIDQuery, whatever ran it (prepared statement, pool or transaction) */
+func scanBook(row *sql.Row) (*models.Book, error) {
 	/* 1. Create a new instance of the Go Struct "Book" */
 	var book models.Book
-	/* 2. Execute the SQL Query returning one DB Table Row from which we extract the
-	   fields values and assign them to the attributes of the Book object. */
-	err := r.DB.QueryRow(`SELECT id, title, author, pages FROM books WHERE id = $1`, id).
-		Scan(&book.ID, &book.Title, &book.Author, &book.Pages)
+	/* 2. Read the DB Table Row returned by the SQL Query, extracting the fields values and
+	   assigning them to the attributes of the Book object. */
+	var avg sql.NullFloat64
+	err := row.Scan(&book.ID, &book.Title, &book.Author, &book.Pages, &book.Year, &book.CreatedAt, &book.UpdatedAt, &avg)
+	setAvgRating(&book, avg)
 
 	/* 3. If an error has occured but this error is due to the fact that no DB table row
 	   satisfies the SQL Query...that's not actually an error, so just return null. */
 	if err == sql.ErrNoRows {
-		return nil, errors.New("Book Not Found")
+		return nil, ErrBookNotFound
 	}
 	/* 4. If the error is due to some other reason, that's definitely an error so return
 	it in the error output of the function. */
@@ -165,39 +647,113 @@ func (r *PgBookRepository) FindByID(id int) (*models.Book, error) {
 }
 
 /* UPDATE - [PUT /books/{id} HTTP Method] ---------------------------------------------------------------------*/
-func (r *PgBookRepository) Update(id int, book models.Book) (*models.Book, error) {
-	/* 1. Build the SQL Query */
-	query := `UPDATE books SET title=$1, author=$2, pages=$3 WHERE id=$4`
-	/* 2. Execute the SQL Query filling in the placeholders using the DB.Exec method
-	      that DOESN'T return ANY ROW as output but rather a RESULT Object that stores
-		  information about how many rows were affected by the updated (RowsAffected()). */
-	res, err := r.DB.Exec(query, book.Title, book.Author, book.Pages, id)
-	/* 3. If the query fails, return nil and an error. */
+func (r *PgBookRepository) Update(ctx context.Context, id int, book models.Book) (*models.Book, error) {
+	/* 1. Execute the Prepared Statement (see updateQuery - the updated_at timestamp gets refreshed by the
+	   Database) filling in the placeholders and read back the timestamps of the updated row.
+	   If no row has been updated, QueryRow returns sql.ErrNoRows: warn the Client that no book has been found. */
+	err := r.updateStmt.QueryRowContext(ctx, book.Title, book.Author, book.Pages, id, book.Year).
+		Scan(&book.CreatedAt, &book.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrBookNotFound
+	}
+	/* 2. If the query fails, return nil and an error. */
 	if err != nil {
 		return nil, err
 	}
-	/* 4. Get the number of rows affected and whether any error occurred */
-	rowsAffected, err := res.RowsAffected()
-	/*...if an error occured, return it together with a null book object */
+	/* 3. Update the id of the input book with the input id */
+	book.ID = id
+	/* 4. Return updated book object and null error */
+	return &book, nil
+}
+
+/* CONDITIONAL UPDATE - [PUT /books/{id} HTTP Method + If-Unmodified-Since Header] ----------------------------*/
+/* Same as Update(..) but the row gets updated ONLY IF it hasn't been modified after the input date (compared at
+   one second precision, like HTTP dates). The check and the update happen in ONE single SQL Query so that no
+   other update can slip in between. */
+func (r *PgBookRepository) UpdateIfUnmodifiedSince(ctx context.Context, id int, book models.Book, since time.Time) (*models.Book, error) {
+	/* 1. Build the SQL Query */
+	query := `UPDATE books SET title=$1, author=$2, pages=$3, year=NULLIF($6, 0), updated_at=now()
+			  WHERE id=$4 AND date_trunc('second', updated_at) <= $5
+			  RETURNING created_at, updated_at`
+	/* 2. Execute the SQL Query reading back the timestamps of the updated row */
+	err := r.DB.QueryRowContext(ctx, query, book.Title, book.Author, book.Pages, id, since, book.Year).
+		Scan(&book.CreatedAt, &book.UpdatedAt)
+	/* 3. If no row has been updated, find out whether the book is missing or has been modified since */
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := r.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM books WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrBookNotFound
+		}
+		return nil, ErrPreconditionFailed
+	}
 	if err != nil {
 		return nil, err
 	}
-	/*...if no rows were affected, warn the Client that no book has been found. */
-	if rowsAffected == 0 {
-		return nil, errors.New("Book Not Found.")
-	}
-	/* 5. Update the id of the input book with the input id */
+	/* 4. Return updated book object and null error */
 	book.ID = id
-	/* 6. Return updated book object and null error */
 	return &book, nil
 }
 
+/* UPSERT --------------------------------------------------------------------------------------------------------*/
+/* Inserts the input book WITH ITS ID or, if a book with that id already exists, replaces its title, author,
+   pages and year (the owner of an existing book never changes). Returns the resulting row and whether it has been
+   inserted (true) or replaced (false).
+   The replacement only happens if the existing book belongs to book.OwnerID, otherwise ErrNotOwner: the ownership
+   check done before (EnforceOwnership) can't see a book inserted meanwhile by someone else, e.g. by a concurrent
+   PUT on the same missing id, and the upsert must never overwrite it.
+   Single place for the upsert SQL: callers (create-or-replace PUT, imports, ...) must not build their own.
+   Since an explicit id bypasses the books_id_seq sequence, the sequence gets moved past the inserted id (never
+   backward) within the same transaction, otherwise a later POST /books would collide with the inserted id. */
+func (r *PgBookRepository) Upsert(ctx context.Context, book models.Book) (models.Book, bool, error) {
+	/* 1. Start a new DB Transaction + Error Handling */
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Book{}, false, err
+	}
+	/* 2. ROLLBACK the Transaction whenever the function returns before the COMMIT (no-op after the COMMIT) */
+	defer tx.Rollback()
+	/* 3. Execute the upsert and scan the resulting row */
+	query := `INSERT INTO books (id, title, author, pages, year, owner_id) VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6)
+			  ON CONFLICT (id) DO UPDATE SET title = EXCLUDED.title, author = EXCLUDED.author,
+			  pages = EXCLUDED.pages, year = EXCLUDED.year, updated_at = now()
+			  WHERE books.owner_id = EXCLUDED.owner_id
+			  RETURNING id, title, author, pages, COALESCE(year, 0), COALESCE(owner_id, 0), created_at, updated_at,
+			  (xmax = 0)`
+	var result models.Book
+	var inserted bool
+	err = tx.QueryRowContext(ctx, query, book.ID, book.Title, book.Author, book.Pages, book.Year, book.OwnerID).
+		Scan(&result.ID, &result.Title, &result.Author, &result.Pages, &result.Year, &result.OwnerID,
+			&result.CreatedAt, &result.UpdatedAt, &inserted)
+	/* No row back -> the id is taken by a book of another owner (the WHERE of the DO UPDATE skipped it) */
+	if err == sql.ErrNoRows {
+		return models.Book{}, false, ErrNotOwner
+	}
+	if err != nil {
+		return models.Book{}, false, err
+	}
+	/* 4. Keep the id sequence ahead of the explicitly inserted id - (xmax = 0) is true only for inserted rows.
+	   The sequence only ever moves FORWARD: the ids of deleted books (and the ones handed out to transactions
+	   still running) must never be given out again. */
+	if inserted {
+		_, err = tx.ExecContext(ctx, `SELECT setval('books_id_seq',
+			GREATEST($1, (SELECT last_value FROM books_id_seq)))`, result.ID)
+		if err != nil {
+			return models.Book{}, false, err
+		}
+	}
+	/* 5. COMMIT the Transaction and return the resulting row + whether it has been inserted */
+	return result, inserted, tx.Commit()
+}
+
 /* DELETE - [DELETE /books/{id} HTTP Method] ------------------------------------------------------------------*/
-func (r *PgBookRepository) Delete(id int) error {
+func (r *PgBookRepository) Delete(ctx context.Context, id int) error {
 	/* 1. Execute SQL Query deleting the record which id matches the input one.
 	      The DB.Exec method DOESN'T return ANY ROW as output but rather a RESULT Object that stores
 		  information about how many rows were affected by the delete operation (RowsAffected()) */
-	res, err := r.DB.Exec(`DELETE FROM books WHERE id = $1`, id)
+	res, err := r.deleteStmt.ExecContext(ctx, id) /* Prepared Statement (see deleteQuery) */
 	/* 2. If an error has occured, return it as output */
 	if err != nil {
 		return err
@@ -218,11 +774,341 @@ func (r *PgBookRepository) Delete(id int) error {
 /* This method is specifically created to encapsulate the extraction of the input book's owner id from the Database.
    This method is called exclusively within the OWNERSHIP-BASED Authorization Middleware EnforceOwnership(..) in the
    file middleware/ownership.go. to carry out authorization checks on HTTP Requests */
-func (r *PgBookRepository) GetOwnerID(bookID int) (int, error) {
+func (r *PgBookRepository) GetOwnerID(ctx context.Context, bookID int) (int, error) {
 	/* 1. Create int variable to hold the ID of the book's owner */
 	var ownerID int
 	/* 2. Execute SQL Query extracting the ID of the owner of the book matching the input book ID */
-	err := r.DB.QueryRow("SELECT owner_id FROM books WHERE id = $1", bookID).Scan(&ownerID)
-	/* 3. Return owner ID and any error */
+	err := r.DB.QueryRowContext(ctx, "SELECT owner_id FROM books WHERE id = $1", bookID).Scan(&ownerID)
+	/* 3. If no book matches the input ID, return the dedicated error so that callers can tell it apart */
+	if err == sql.ErrNoRows {
+		return 0, ErrBookNotFound
+	}
+	/* 4. Return owner ID and any error */
 	return ownerID, err
 }
+
+/* READ DISTINCT AUTHORS - [GET /books/authors HTTP Method] -----------------------------------------------------*/
+/* Returns the sorted list of unique authors whose name starts with the input prefix (case-insensitive).
+   When ownerID is 0 the authors of ALL books are returned, otherwise only the ones of the owner's books. */
+func (r *PgBookRepository) FindAuthors(ctx context.Context, prefix string, ownerID int) ([]string, error) {
+	/* 1. Build the SQL Query - starts_with(..) avoids treating % and _ in the prefix as LIKE wildcards */
+	query := `SELECT DISTINCT author FROM books
+			  WHERE starts_with(lower(author), lower($1)) AND ($2 = 0 OR owner_id = $2)
+			  ORDER BY author`
+	/* 2. Execute the SQL Query expecting a list of DB Table Rows + Error Handling */
+	rows, err := r.DB.QueryContext(ctx, query, prefix, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	/* 3. Make sure that the DB Table Rows get CLOSED when the current function finishes */
+	defer rows.Close()
+	/* 4. Create an empty list so that no authors get encoded as [] rather than null */
+	authors := []string{}
+	/* 5. Looping through the rows of the DB Table, extract the author names and add them to the list */
+	for rows.Next() {
+		var author string
+		if err := rows.Scan(&author); err != nil {
+			return nil, err
+		}
+		authors = append(authors, author)
+	}
+	/* 6. Checks if there were any errors while reading the rows. */
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	/* 7. Return the list of authors and a null error. */
+	return authors, nil
+}
+
+/* BULK UPDATE PAGES - [PUT /books/pages HTTP Method] -----------------------------------------------------------*/
+/* Sets the pages of many books within ONE single DB Transaction. Books that don't exist are reported as failed,
+   while a book not owned by the caller (ownerID != 0) rolls back the WHOLE transaction returning ErrNotOwner.
+   When ownerID is 0 (admin) no ownership check is carried out. */
+func (r *PgBookRepository) UpdatePages(ctx context.Context, updates []models.PagesUpdate, ownerID int) (models.BulkUpdateResult, error) {
+	/* 1. Create the result object with empty lists so that they get encoded as [] rather than null */
+	result := models.BulkUpdateResult{Succeeded: []int{}, Failed: []models.BulkUpdateFailure{}}
+	/* 2. Start a new DB Transaction + Error Handling */
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	/* 3. ROLLBACK the Transaction whenever the function returns before the COMMIT (no-op after the COMMIT) */
+	defer tx.Rollback()
+
+	/* 4. Loop through the updates... */
+	for _, u := range updates {
+		/* 4.1 Lock the book row and get its owner - a missing book is reported as failed */
+		var bookOwnerID int
+		err := tx.QueryRowContext(ctx, `SELECT owner_id FROM books WHERE id = $1 FOR UPDATE`, u.ID).Scan(&bookOwnerID)
+		if err == sql.ErrNoRows {
+			result.Failed = append(result.Failed, models.BulkUpdateFailure{ID: u.ID, Reason: "Book Not Found"})
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		/* 4.2 If the caller doesn't own the book, stop and ROLLBACK everything */
+		if ownerID != 0 && bookOwnerID != ownerID {
+			return models.BulkUpdateResult{}, ErrNotOwner
+		}
+		/* 4.3 Set the new number of pages */
+		if _, err := tx.ExecContext(ctx, `UPDATE books SET pages = $1, updated_at = now() WHERE id = $2`, u.Pages, u.ID); err != nil {
+			return result, err
+		}
+		result.Succeeded = append(result.Succeeded, u.ID)
+	}
+
+	/* 5. COMMIT the Transaction and return the result together with any error */
+	return result, tx.Commit()
+}
+
+// 4. REVIEWS QUERY METHODS *******************************************************************************************
+
+/* UPSERT REVIEW - [POST /books/{id}/reviews HTTP Method] -------------------------------------------------------*/
+/* One review per user per book: a second review by the same user replaces the first one (rating and comment).
+   A book_id not matching any book violates the foreign key (SQLSTATE 23503) -> ErrBookNotFound. */
+func (r *PgBookRepository) UpsertReview(ctx context.Context, review models.Review) (models.Review, bool, error) {
+	/* 1. Build the SQL Query - (xmax = 0) is true only for inserted rows */
+	query := `INSERT INTO reviews (book_id, user_id, rating, comment) VALUES ($1, $2, $3, NULLIF($4, ''))
+			  ON CONFLICT (book_id, user_id) DO UPDATE SET rating = EXCLUDED.rating, comment = EXCLUDED.comment,
+			  updated_at = now()
+			  RETURNING id, created_at, updated_at, (xmax = 0)`
+	/* 2. Execute the SQL Query reading back id and timestamps of the review + whether it has been inserted */
+	var inserted bool
+	err := r.DB.QueryRowContext(ctx, query, review.BookID, review.UserID, review.Rating, review.Comment).
+		Scan(&review.ID, &review.CreatedAt, &review.UpdatedAt, &inserted)
+	/* 3. Translate the foreign key violation into the dedicated error */
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return models.Review{}, false, ErrBookNotFound
+	}
+	if err != nil {
+		return models.Review{}, false, err
+	}
+	return review, inserted, nil
+}
+
+/* FIND REVIEWS - [GET /books/{id}/reviews HTTP Method] ---------------------------------------------------------*/
+/* One page of the reviews of a book (newest first) together with the total number of its reviews */
+func (r *PgBookRepository) FindReviews(ctx context.Context, bookID, limit, offset int) ([]models.Review, int, error) {
+	/* 1. Count the reviews, telling a missing book apart from a book without reviews (NULL count) */
+	var total sql.NullInt64
+	err := r.DB.QueryRowContext(ctx, `SELECT (SELECT COUNT(*) FROM reviews WHERE book_id = b.id)
+		FROM books b WHERE b.id = $1`, bookID).Scan(&total)
+	if err == sql.ErrNoRows {
+		return nil, 0, ErrBookNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	/* 2. Execute the SQL Query expecting one page of DB Table Rows (newest first) */
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, book_id, user_id, rating, COALESCE(comment, ''), created_at, updated_at
+		FROM reviews WHERE book_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`, bookID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	/* 3. Create an empty list (encoded as [] and not null) and fill it looping through the rows */
+	reviews := []models.Review{}
+	for rows.Next() {
+		var rv models.Review
+		if err := rows.Scan(&rv.ID, &rv.BookID, &rv.UserID, &rv.Rating, &rv.Comment, &rv.CreatedAt, &rv.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		reviews = append(reviews, rv)
+	}
+	/* 4. Checks if there were any errors while reading the rows, then return the page and the total */
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return reviews, int(total.Int64), nil
+}
+
+// 5. FAVORITES QUERY METHODS *****************************************************************************************
+
+/* ADD FAVORITE - [POST /books/{id}/favorite HTTP Method] -------------------------------------------------------*/
+/* Idempotent: the (user_id, book_id) primary key prevents duplicates and ON CONFLICT DO NOTHING turns a repeated
+   favorite into a no-op. A book_id not matching any book violates the foreign key -> ErrBookNotFound. */
+func (r *PgBookRepository) AddFavorite(ctx context.Context, userID, bookID int) error {
+	_, err := r.DB.ExecContext(ctx, `INSERT INTO favorites (user_id, book_id) VALUES ($1, $2)
+		ON CONFLICT (user_id, book_id) DO NOTHING`, userID, bookID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return ErrBookNotFound
+	}
+	return err
+}
+
+/* REMOVE FAVORITE - [DELETE /books/{id}/favorite HTTP Method] --------------------------------------------------*/
+/* Idempotent: removing a book that isn't a favorite is a no-op, unless the book doesn't exist -> ErrBookNotFound */
+func (r *PgBookRepository) RemoveFavorite(ctx context.Context, userID, bookID int) error {
+	/* 1. Remove the favorite + Error Handling */
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM favorites WHERE user_id = $1 AND book_id = $2`, userID, bookID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	/* 2. Nothing removed: tell a missing book apart from a book that simply wasn't a favorite */
+	var exists bool
+	if err := r.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM books WHERE id = $1)`, bookID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrBookNotFound
+	}
+	return nil
+}
+
+/* SUM PAGES BY OWNER - [GET /me/pages/total HTTP Method] ------------------------------------------------------*/
+/* Total number of pages of the books owned by the input user: SUM gives NULL (-> 0) when there are no books */
+func (r *PgBookRepository) SumPagesByOwner(ctx context.Context, ownerID int) (int, error) {
+	var total int
+	err := r.ReadDB.QueryRowContext(ctx, `SELECT COALESCE(SUM(pages), 0) FROM books WHERE owner_id = $1`, ownerID).
+		Scan(&total)
+	return total, err
+}
+
+/* FIND OWNED IDs - [POST /books/ownership HTTP Method] --------------------------------------------------------*/
+/* The subset of the input book IDs owned by the input user, in one single query (missing books are not owned) */
+func (r *PgBookRepository) FindOwnedIDs(ctx context.Context, ids []int, ownerID int) ([]int, error) {
+	/* 1. Execute the SQL Query passing the IDs as one PostgreSQL array parameter */
+	rows, err := r.ReadDB.QueryContext(ctx, `SELECT id FROM books WHERE id = ANY($1) AND owner_id = $2`,
+		pq.Array(ids), ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	/* 2. Collect the owned IDs */
+	owned := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		owned = append(owned, id)
+	}
+	return owned, rows.Err()
+}
+
+/* FIND POPULAR - [GET /books/popular HTTP Method] --------------------------------------------------------------*/
+/* One page of the favorited books, most favorited first, together with the total number of favorited books.
+   Books nobody favorited are not "popular" and are left out (inner JOIN). */
+func (r *PgBookRepository) FindPopular(ctx context.Context, limit, offset int) ([]models.PopularBook, int, error) {
+	/* 1. Count the favorited books + Error Handling */
+	var total int
+	if err := r.ReadDB.QueryRowContext(ctx, `SELECT COUNT(DISTINCT book_id) FROM favorites`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	/* 2. Execute the SQL Query counting the favorites of every book and expecting one page of DB Table Rows */
+	rows, err := r.ReadDB.QueryContext(ctx, `SELECT b.id, b.title, b.author, b.pages, COALESCE(b.year, 0),
+		b.created_at, b.updated_at, ra.avg_rating, COUNT(f.user_id) AS favorite_count
+		FROM books b JOIN favorites f ON f.book_id = b.id `+avgRatingJoin+`
+		GROUP BY b.id, ra.avg_rating
+		ORDER BY favorite_count DESC, b.id ASC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	/* 3. Create an empty list (encoded as [] and not null) and fill it looping through the rows */
+	books := []models.PopularBook{}
+	for rows.Next() {
+		var b models.PopularBook
+		var avg sql.NullFloat64
+		if err := rows.Scan(&b.ID, &b.Title, &b.Author, &b.Pages, &b.Year, &b.CreatedAt, &b.UpdatedAt, &avg,
+			&b.FavoriteCount); err != nil {
+			return nil, 0, err
+		}
+		setAvgRating(&b.Book, avg)
+		books = append(books, b)
+	}
+	/* 4. Checks if there were any errors while reading the rows, then return the page and the total */
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return books, total, nil
+}
+
+/* FIND BY OWNER - [GET /me/export HTTP Method] -----------------------------------------------------------------*/
+/* Every book owned by the user, oldest first */
+func (r *PgBookRepository) FindByOwner(ctx context.Context, ownerID int) ([]models.Book, error) {
+	/* 1. Execute the SQL Query expecting a list of DB Table Rows */
+	rows, err := r.DB.QueryContext(ctx, `SELECT b.id, b.title, b.author, b.pages, COALESCE(b.year, 0),
+		b.created_at, b.updated_at, ra.avg_rating FROM books b `+avgRatingJoin+`
+		WHERE b.owner_id = $1 ORDER BY b.created_at ASC, b.id ASC`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	/* 2. Create an empty list (encoded as [] and not null) and fill it looping through the rows */
+	books := []models.Book{}
+	for rows.Next() {
+		var b models.Book
+		var avg sql.NullFloat64
+		if err := rows.Scan(&b.ID, &b.Title, &b.Author, &b.Pages, &b.Year, &b.CreatedAt, &b.UpdatedAt, &avg); err != nil {
+			return nil, err
+		}
+		setAvgRating(&b, avg)
+		books = append(books, b)
+	}
+	/* 3. Checks if there were any errors while reading the rows, then return the list */
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+/* FIND REVIEWS BY USER - [GET /me/export HTTP Method] ----------------------------------------------------------*/
+/* Every review written by the user, oldest first */
+func (r *PgBookRepository) FindReviewsByUser(ctx context.Context, userID int) ([]models.Review, error) {
+	/* 1. Execute the SQL Query expecting a list of DB Table Rows */
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, book_id, user_id, rating, COALESCE(comment, ''), created_at, updated_at
+		FROM reviews WHERE user_id = $1 ORDER BY created_at ASC, id ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	/* 2. Create an empty list (encoded as [] and not null) and fill it looping through the rows */
+	reviews := []models.Review{}
+	for rows.Next() {
+		var rv models.Review
+		if err := rows.Scan(&rv.ID, &rv.BookID, &rv.UserID, &rv.Rating, &rv.Comment, &rv.CreatedAt, &rv.UpdatedAt); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, rv)
+	}
+	/* 3. Checks if there were any errors while reading the rows, then return the list */
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+/* FIND FAVORITES - [GET /me/favorites HTTP Method] -------------------------------------------------------------*/
+func (r *PgBookRepository) FindFavorites(ctx context.Context, userID int) ([]models.Book, error) {
+	/* 1. Execute the SQL Query expecting a list of DB Table Rows (latest favorites first) */
+	rows, err := r.DB.QueryContext(ctx, `SELECT b.id, b.title, b.author, b.pages, COALESCE(b.year, 0),
+		b.created_at, b.updated_at, ra.avg_rating FROM favorites f JOIN books b ON b.id = f.book_id `+avgRatingJoin+`
+		WHERE f.user_id = $1 ORDER BY f.created_at DESC, b.id ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	/* 2. Create an empty list (encoded as [] and not null) and fill it looping through the rows */
+	books := []models.Book{}
+	for rows.Next() {
+		var b models.Book
+		var avg sql.NullFloat64
+		if err := rows.Scan(&b.ID, &b.Title, &b.Author, &b.Pages, &b.Year, &b.CreatedAt, &b.UpdatedAt, &avg); err != nil {
+			return nil, err
+		}
+		setAvgRating(&b, avg)
+		books = append(books, b)
+	}
+	/* 3. Checks if there were any errors while reading the rows, then return the list */
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
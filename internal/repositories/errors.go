@@ -0,0 +1,77 @@
+package repositories
+
+// repositories/ PACKAGE **********************************************************************************************
+/* The repositories/ package is used to store all the objects definitions and all the methods that are used to execute
+   SQL Queries on the connected Database for all CRUD Operations (Create, Read, Update, Delete)
+   This package is responsible for DATABASE ACCESS LOGIC. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Scope of errors.go
+		- Translation of the PostgreSQL errors returned by lib/pq (*pq.Error, identified by their SQLSTATE code)
+		  into typed errors that the upper layers can check with errors.Is/errors.As, without importing lib/pq.
+   2. Unique Violations (SQLSTATE 23505)
+		- The name of the conflicting column(s) is read from the Detail of the error, e.g.
+			Key (email)=(jane@example.com) already exists.
+		  so that the handlers can name it in the 409 Conflict Response.
+*/
+
+// 1. IMPORT PACKAGES **********************************************************************************************
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/lib/pq"
+)
+
+// 2. TYPED ERRORS *************************************************************************************************
+
+/* Returned by the repository constructors without a connection pool: fails the startup, not the first query */
+var ErrNilDB = errors.New("repositories: nil database connection (was the Database initialized?)")
+
+/* Matched (via errors.Is) by every *AlreadyExistsError */
+var ErrAlreadyExists = errors.New("already exists")
+
+/* Returned when an INSERT/UPDATE collides with a unique constraint */
+type AlreadyExistsError struct {
+	Field string /* Conflicting column(s), e.g. "email" ("value" if unknown) */
+}
+
+/* Implement the error interface */
+func (e *AlreadyExistsError) Error() string {
+	return "A record with the same " + e.Field + " already exists"
+}
+
+/* Make errors.Is(err, ErrAlreadyExists) true for any *AlreadyExistsError */
+func (e *AlreadyExistsError) Is(target error) bool {
+	return target == ErrAlreadyExists
+}
+
+/* Returned when one transfer of a batch cannot be applied (the whole batch gets rolled back) */
+type TransferBatchError struct {
+	Index  int    /* Position of the failed transfer within the batch (0-based) */
+	Reason string /* e.g. "Book 3 has fewer than 50 pages" */
+}
+
+/* Implement the error interface */
+func (e *TransferBatchError) Error() string {
+	return fmt.Sprintf("Transfer %d failed: %s", e.Index, e.Reason)
+}
+
+// 3. UTILITY METHODS **********************************************************************************************
+
+/* Column(s) named in the Detail of a unique violation (see IMPORTANT NOTES 2.) */
+var conflictKey = regexp.MustCompile(`Key \(([^)]+)\)=`)
+
+/* Translate a unique violation into an *AlreadyExistsError, leaving any other error (or nil) untouched */
+func translateUniqueViolation(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return err
+	}
+	field := "value"
+	if match := conflictKey.FindStringSubmatch(pqErr.Detail); match != nil {
+		field = match[1]
+	}
+	return &AlreadyExistsError{Field: field}
+}
@@ -0,0 +1,66 @@
+package repositories
+
+// repositories/ PACKAGE **********************************************************************************************
+/* The repositories/ package is used to store all the objects definitions and all the methods that are used to execute
+   SQL Queries on the connected Database for all CRUD Operations (Create, Read, Update, Delete)
+   This package is responsible for DATABASE ACCESS LOGIC. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Schema Versions
+		- The schema_migrations table holds one row per schema version applied to the Database (see db/init/). The
+		  Database is up to date when its highest version is at least SchemaVersion, the latest one this code
+		  knows about.
+		- Whenever the schema changes: bump SchemaVersion AND insert the new version in the SQL applying the change.
+   2. Missing Table
+		- A Database created before schema_migrations existed has no such table: it's reported as version 0
+		  (i.e. behind), not as an error.
+*/
+
+// 1. IMPORT PACKAGES *************************************************************************************************
+import (
+	"bookapi/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// 2. GO STRUCTS and UTILITY VARIABLES ********************************************************************************
+
+/* Latest schema version known by the code (see IMPORTANT NOTES 1.) */
+const SchemaVersion = 1
+
+/* STRUCT */
+type SchemaRepository struct {
+	DB *sql.DB
+}
+
+/* STRUCT BUILDER */
+func NewSchemaRepository(db *sql.DB) (*SchemaRepository, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+	return &SchemaRepository{DB: db}, nil
+}
+
+// 3. QUERY METHODS ***************************************************************************************************
+
+/* MIGRATION STATUS - [GET /health/migrations HTTP Method] -----------------------------------------------------------*/
+/* Current version of the Database vs latest known one, "ok" when up to date and "behind" otherwise */
+func (r *SchemaRepository) MigrationStatus(ctx context.Context) (models.MigrationStatus, error) {
+	/* 1. Read the highest applied version (0 if none or if the table doesn't exist yet, see IMPORTANT NOTES 2.) */
+	status := models.MigrationStatus{LatestVersion: SchemaVersion}
+	err := r.DB.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).
+		Scan(&status.CurrentVersion)
+	var pqErr *pq.Error
+	if err != nil && !(errors.As(err, &pqErr) && pqErr.Code == "42P01") { /* 42P01 = undefined_table */
+		return models.MigrationStatus{}, err
+	}
+	/* 2. Compare it with the latest known version */
+	status.Status = models.HealthOK
+	if status.CurrentVersion < status.LatestVersion {
+		status.Status = models.HealthBehind
+	}
+	return status, nil
+}
@@ -14,48 +14,102 @@ package repositories
 			-> NON-STATIC Method. It belongs to and gets executed by instances of UserRepository Struct
 		- func Create(user models.User) (models.User, error)
 			-> STATIC Method. It can be executed without any instance of UserRepository.
-
+   3. Invite Codes
+		- ConsumeInvite(..) marks a code as used only if it is still unused (single UPDATE ... WHERE used_at IS NULL):
+		  two concurrent registrations with the same code can't both succeed, since the second UPDATE waits for the
+		  row lock of the first one and then finds the code already used.
 */
 
 // 1. IMPORT PACKAGES *************************************************************************************************
 import (
 	"bookapi/internal/models"
+	"context"
 	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 // 2. GO STRUCTS and UTILITY VARIABLES ********************************************************************************
 
+/* DB INTERFACE */
+/* Subset of the methods used by the queries below: satisfied by both *sql.DB and *sql.Tx, so that the same
+   repository methods can run either directly on the pool or inside a transaction (see WithinTx) */
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+/* Errors */
+/* Returned when an invite code doesn't exist or has already been used */
+var ErrInvalidInvite = errors.New("Invalid or already used invite code")
+
+/* Error returned when the input user id doesn't exist */
+var ErrUserNotFound = errors.New("User not found")
+
 /* STRUCT */
 type UserRepository struct {
-	DB *sql.DB
+	DB     DBTX
+	ReadDB DBTX /* Read Replica (or the primary itself) used by the stats queries (see book_repository.go) */
 }
 
-/* STRUCT BUILDER */
-func NewUserRepository(db *sql.DB) *UserRepository {
-	return &UserRepository{DB: db}
+/* STRUCT BUILDER - readDB may be nil, in which case the stats queries use the primary too (db may NOT) */
+func NewUserRepository(db, readDB *sql.DB) (*UserRepository, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+	if readDB == nil {
+		readDB = db
+	}
+	return &UserRepository{DB: db, ReadDB: readDB}, nil
+}
+
+/* TRANSACTIONS */
+/* Runs fn with a copy of the repository bound to a new transaction: COMMIT if fn returns nil, ROLLBACK otherwise */
+func (r *UserRepository) WithinTx(ctx context.Context, fn func(txRepo *UserRepository) error) error {
+	/* 1. Transactions can only be started from the pool (no nested transactions) */
+	db, ok := r.DB.(*sql.DB)
+	if !ok {
+		return errors.New("nested transactions are not supported")
+	}
+	/* 2. Start a new DB Transaction + Error Handling */
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	/* 3. ROLLBACK the Transaction whenever the function returns before the COMMIT (no-op after the COMMIT) */
+	defer tx.Rollback()
+	/* 4. Run fn on the transaction-bound repository and COMMIT if everything went well */
+	if err := fn(&UserRepository{DB: tx, ReadDB: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // 3. QUERY CRUD METHODS **********************************************************************************************
 
 /* CREATE - [POST /register HTTP Method] ---------------------------------------------------------------------------*/
-func (r *UserRepository) Create(user models.User) (models.User, error) {
+func (r *UserRepository) Create(ctx context.Context, user models.User) (models.User, error) {
 	/* 1. Build SQL Query string adding user object in DB Table */
 	query := `INSERT INTO users (email, password) VALUES ($1, $2) RETURNING id`
 	/* 2. Execute Query passing user email and password in the placeholders and assigning id of db table row to the
 	the input user object. If any error occurs, the error gets returned in err */
-	err := r.DB.QueryRow(query, user.Email, user.Password).Scan(&user.ID)
-	/* 3. Return input user object with updated id based on assignment in DB table + any error */
-	return user, err
+	err := r.DB.QueryRowContext(ctx, query, user.Email, user.Password).Scan(&user.ID)
+	/* 3. Return input user object with updated id based on assignment in DB table + any error (a concurrent
+	   registration of the same email violates the unique constraint -> *AlreadyExistsError) */
+	return user, translateUniqueViolation(err)
 }
 
 /* FIND BY EMAIL - [GET /register HTTP Method] ---------------------------------------------------------------------*/
-func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	/* 1. Declare a new User Go Struct to hold values extracted from the DB Table*/
 	var user models.User
 	/* 2. Execute SQL Query looking for user matching input email, return any encoutered error and populate the
 	   fields of the Go Struct with the corresponding table row values. */
-	err := r.DB.QueryRow(`SELECT id, role, email, password FROM users WHERE email = $1`, email).
-		Scan(&user.ID, &user.Role, &user.Email, &user.Password)
+	err := r.DB.QueryRowContext(ctx, `SELECT id, role, email, password, active FROM users WHERE lower(email) = lower($1)`, email).
+		Scan(&user.ID, &user.Role, &user.Email, &user.Password, &user.Active)
 	/* 3. If the encountered error is due to no rows returned by the query....that's not an error but just an
 	      indication that there's no user in the database associated with the input email....so return null
 		  user object and null error...*/
@@ -70,10 +124,135 @@ func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
+/* UPDATE PASSWORD - [POST /login HTTP Method] -----------------------------------------------------------------*/
+/* Replaces the password hash of a user (used to upgrade the hashes to a higher bcrypt cost) */
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int, hash string) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE users SET password = $1 WHERE id = $2`, hash, id)
+	return err
+}
+
+/* CREATE INVITES - [POST /admin/invites HTTP Method] -------------------------------------------------------------*/
+/* Stores the input (already generated) codes in one INSERT and returns them with their creation date */
+func (r *UserRepository) CreateInvites(ctx context.Context, codes []string, createdBy int) ([]models.Invite, error) {
+	/* 1. Execute the SQL Query inserting one row per code */
+	rows, err := r.DB.QueryContext(ctx, `INSERT INTO invites (code, created_by) SELECT unnest($1::text[]), $2
+		RETURNING code, created_at`, pq.Array(codes), createdBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	/* 2. Collect the created invites */
+	invites := make([]models.Invite, 0, len(codes))
+	for rows.Next() {
+		var invite models.Invite
+		if err := rows.Scan(&invite.Code, &invite.CreatedAt); err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+/* CONSUME INVITE - [POST /register HTTP Method] -------------------------------------------------------------------*/
+/* Marks the code as used by the input user, or returns ErrInvalidInvite (see IMPORTANT NOTES 3.) */
+func (r *UserRepository) ConsumeInvite(ctx context.Context, code string, userID int) error {
+	/* 1. Execute the SQL Query updating the code only if it is still unused */
+	result, err := r.DB.ExecContext(ctx,
+		`UPDATE invites SET used_by = $2, used_at = now() WHERE code = $1 AND used_at IS NULL`, code, userID)
+	if err != nil {
+		return err
+	}
+	/* 2. No updated rows means that the code doesn't exist or has already been used */
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrInvalidInvite
+	}
+	return nil
+}
+
+/* FIND RECENT REGISTRATION - [POST /register HTTP Method] -------------------------------------------------------*/
+/* User with the input email who registered (i.e. consumed an invite code) within the input window, nil if none.
+   The window is checked against the clock of the Database, the same one that has set used_at. */
+func (r *UserRepository) FindRegisteredWithin(ctx context.Context, email string, window time.Duration) (*models.User, error) {
+	var user models.User
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT u.id, u.role, u.email, u.password, u.active
+		FROM users u JOIN invites i ON i.used_by = u.id
+		WHERE lower(u.email) = lower($1) AND i.used_at >= now() - make_interval(secs => $2)
+		LIMIT 1`, email, window.Seconds()).
+		Scan(&user.ID, &user.Role, &user.Email, &user.Password, &user.Active)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+/* UPDATE ROLES - [POST /admin/users/roles HTTP Method] -----------------------------------------------------------*/
+/* Sets the role of all the input users in ONE statement (hence atomically: either all of them or none get updated)
+   and returns how many users have been found and updated */
+func (r *UserRepository) UpdateRoles(ctx context.Context, ids []int, role string) (int, error) {
+	result, err := r.DB.ExecContext(ctx, `UPDATE users SET role = $1 WHERE id = ANY($2)`, role, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	updated, err := result.RowsAffected()
+	return int(updated), err
+}
+
+/* SET ACTIVE - [POST /admin/users/{id}/suspend and /unsuspend HTTP Methods] ------------------------------------*/
+/* Suspends (active = false) or reactivates the user, or returns ErrUserNotFound */
+func (r *UserRepository) SetActive(ctx context.Context, id int, active bool) error {
+	result, err := r.DB.ExecContext(ctx, `UPDATE users SET active = $1 WHERE id = $2`, active, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+/* FIND BY ID - [POST /admin/users/{id}/impersonate HTTP Method] ---------------------------------------------------*/
+/* The user with the input ID (password hash left out), or ErrUserNotFound */
+func (r *UserRepository) FindByID(ctx context.Context, id int) (*models.User, error) {
+	var user models.User
+	err := r.DB.QueryRowContext(ctx, `SELECT id, role, email, active FROM users WHERE id = $1`, id).
+		Scan(&user.ID, &user.Role, &user.Email, &user.Active)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+/* IS ACTIVE - [every PROTECTED route] ------------------------------------------------------------------------------*/
+/* Whether the user is NOT suspended, or ErrUserNotFound. Runs on the primary (NOT the replica), so that a
+   suspension takes effect straight away */
+func (r *UserRepository) IsActive(ctx context.Context, id int) (bool, error) {
+	var active bool
+	err := r.DB.QueryRowContext(ctx, `SELECT active FROM users WHERE id = $1`, id).Scan(&active)
+	if err == sql.ErrNoRows {
+		return false, ErrUserNotFound
+	}
+	return active, err
+}
+
 /* FIND ALL - [GET /admin/users HTTP Method] ---------------------------------------------------------------------*/
-func (r *UserRepository) FindAll() ([]models.User, error) {
+func (r *UserRepository) FindAll(ctx context.Context) ([]models.User, error) {
 	/* 1. Execute the SQL Query expecting a list of DB Table Rows */
-	rows, err := r.DB.Query("SELECT id, role, email, password FROM users ORDER BY id ASC")
+	rows, err := r.DB.QueryContext(ctx, "SELECT id, role, email, password, active FROM users ORDER BY id ASC")
 	/* 2. If an error occurs, return null list together with encountered error */
 	if err != nil {
 		return nil, err
@@ -81,8 +260,8 @@ func (r *UserRepository) FindAll() ([]models.User, error) {
 	/* 3. Make sure that the DB Table Rows get CLOSED when the current function
 	   finishes in order to avoid locked memory */
 	defer rows.Close()
-	/* 4. Create an empty list to store the user objects extracted from the DB Table */
-	var users []models.User
+	/* 4. Create an empty list (encoded as [] and not null) to store the user objects extracted from the DB Table */
+	users := []models.User{}
 	/* 5. Looping through the rows of the DB Table, extract the field values and store
 	      them in the corresponding attributes of each new user object that gets then
 		  addedd to the users list. */
@@ -90,7 +269,7 @@ func (r *UserRepository) FindAll() ([]models.User, error) {
 		/* Create a new book struct instance */
 		var user models.User
 		/* Get data from the DB Table row and assign it to the book object */
-		err := rows.Scan(&user.ID, &user.Role, &user.Email, &user.Password)
+		err := rows.Scan(&user.ID, &user.Role, &user.Email, &user.Password, &user.Active)
 		/* Return an error if an error occurs in the process. */
 		if err != nil {
 			return nil, err
@@ -105,3 +284,36 @@ func (r *UserRepository) FindAll() ([]models.User, error) {
 	/* 7. Return the list of books and a null error. */
 	return users, nil
 }
+
+/* BOOKS PER USER - [GET /admin/stats/books-per-user HTTP Method] ------------------------------------------------*/
+/* Counts the books owned by every user, sorted by count (descending). Users without books are included with a
+   count of 0 (LEFT JOIN). A limit of 0 returns all the users (LIMIT NULL means no limit). */
+func (r *UserRepository) BooksPerUser(ctx context.Context, limit int) ([]models.UserBookCount, error) {
+	/* 1. Execute the SQL Query expecting a list of DB Table Rows */
+	query := `SELECT u.id, u.email, COUNT(b.id) AS book_count
+			  FROM users u LEFT JOIN books b ON b.owner_id = u.id
+			  GROUP BY u.id, u.email
+			  ORDER BY book_count DESC, u.id ASC
+			  LIMIT NULLIF($1, 0)`
+	rows, err := r.ReadDB.QueryContext(ctx, query, limit)
+	/* 2. If an error occurs, return null list together with encountered error */
+	if err != nil {
+		return nil, err
+	}
+	/* 3. Make sure that the DB Table Rows get CLOSED when the current function finishes */
+	defer rows.Close()
+	/* 4. Create an empty list (encoded as [] and not null) and fill it looping through the rows */
+	counts := []models.UserBookCount{}
+	for rows.Next() {
+		var count models.UserBookCount
+		if err := rows.Scan(&count.UserID, &count.Email, &count.BookCount); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	/* 5. Checks if there were any errors while reading the rows, then return the list */
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
@@ -12,91 +12,199 @@ package router
   		  In Go the difference between PUBLIC and PRIVATE variables is defined as follows:
 			- CAPITAL first letter -> PUBLIC variable
 			- LOWER CASE first letter -> PRIVATE variable
-   2. Use of _"github.com/lib/pb
-		- The PostgreSQL driver gets imported anonymously
-		- It is needed for sql.Open to work with PostgreSQL).
+   2. Use of "github.com/lib/pq"
+		- The PostgreSQL driver used to be imported anonymously (needed for sql.Open to work with PostgreSQL).
+		- Now its connector (pq.NewConnector) is used directly, so that it can be wrapped by the DB circuit breaker
+		  and by the acquisition timeout (dbpool) before being handed to otelsql.OpenDB.
+   3. Routes that never touch the Database
+		- The middleware that only makes sense around queries (DB circuit breaker, DB_ACQUIRE_TIMEOUT) is registered
+		  on the routes using the Database only: the static ones (Swagger, OpenAPI spec, example book) keep
+		  working during a Database outage.
 */
 
 // 1. IMPORT PACKAGES *********************************************************************************************
 import (
+	"bookapi/internal/breaker"
 	bookConfig "bookapi/internal/config"
+	"bookapi/internal/dbpool"
 	"bookapi/internal/handlers"
+	"bookapi/internal/logger"
 	"bookapi/internal/middleware"
 	"bookapi/internal/repositories"
 	"bookapi/internal/services"
+	"context"
 	"fmt"
 	"time"
 
 	"database/sql"
+	"database/sql/driver"
 	"log"
 	"net/http"
 
-	"github.com/go-chi/chi/v5"                          /* 						    >>>>>> CHI Router <<<<< */
-	chimiddleware "github.com/go-chi/chi/v5/middleware" /* 							>>>>>> CHI Router <<<<< */
-	_ "github.com/lib/pq"
+	"github.com/go-chi/chi/v5" /* 						    >>>>>> CHI Router <<<<< */
+	"github.com/lib/pq"
+
+	"github.com/XSAM/otelsql" /* 						    >>>>>> OPENTELEMETRY <<<<< */
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	_ "bookapi/docs" /* 						 					 				>>>>>> SWAGGER <<<<<<< */
 
 	httpSwagger "github.com/swaggo/http-swagger/v2" /* 						 		>>>>>> SWAGGER <<<<<<< */
 )
 
-func NewRouter(cfg bookConfig.Config) http.Handler {
+/* Returns the router + the function releasing its resources (statements, pools), to call once the server stopped */
+func NewRouter(cfg bookConfig.Config, readiness *handlers.Readiness) (http.Handler, func()) {
 	/* 1. Open a connection to the PostgreSQL database using the URL from the config + Error Handling */
-	db, err := initPostgres(cfg.DBURL)
+	dbBreaker := breaker.New("primary", cfg.DBBreakerFailures, cfg.DBBreakerCooldown, cfg.DBBreakerHalfOpen)
+	db, err := initPostgres(cfg.DBURL, dbBreaker)
 	if err != nil {
 		log.Fatal("Failed to connect to DB: ", err)
 	}
+	/* 1b. Open a second connection pool to the optional read replica (reads fall back to db when unset) */
+	readDB, readBreaker := db, dbBreaker
+	if cfg.DBReadURL != "" {
+		readBreaker = breaker.New("replica", cfg.DBBreakerFailures, cfg.DBBreakerCooldown, cfg.DBBreakerHalfOpen)
+		if readDB, err = initPostgres(cfg.DBReadURL, readBreaker); err != nil {
+			log.Fatal("Failed to connect to read replica DB: ", err)
+		}
+	}
 
 	/* 2. Create Repository instances using the database connection. */
-	userRepo := repositories.NewUserRepository(db)
-	bookRepo := repositories.NewBookRepository(db)
+	userRepo, err := repositories.NewUserRepository(db, readDB)
+	if err != nil {
+		log.Fatal("Failed to create the user repository: ", err)
+	}
+	bookRepo, err := repositories.NewBookRepository(db, readDB)
+	if err != nil {
+		log.Fatal("Failed to create the book repository: ", err)
+	}
+	auditRepo, err := repositories.NewAuditRepository(db)
+	if err != nil {
+		log.Fatal("Failed to create the audit repository: ", err)
+	}
+	schemaRepo, err := repositories.NewSchemaRepository(db)
+	if err != nil {
+		log.Fatal("Failed to create the schema repository: ", err)
+	}
 	/* 3. Create Service instances using the repositories. */
-	userService := services.NewUserService(userRepo)
-	bookService := services.NewBookService(bookRepo)
+	userService := services.NewUserService(userRepo, cfg.PasswordPepper, cfg.BcryptCost, cfg.RegisterReplayWindow,
+		cfg.AllowedEmailDomains)
+	bookService := services.NewBookService(bookRepo, cfg.MaxTransferPages, cfg.SanitizeInput)
+	auditService := services.NewAuditService(auditRepo)
+	exportService := services.NewExportService(userRepo, bookRepo, auditRepo)
 	/* 4. Create Handler instances using the services. */
-	userHandler := handlers.NewUserHandler(userService)
-	adminHandler := handlers.NewAdminHandler(userService)
-	authHandler := handlers.NewAuthHandler(userService, cfg.JWTSecret)
-	bookHandler := handlers.NewBookHandler(bookService)
+	/* Login and registration share one stricter limiter with its own buckets (decoupled from the global one) */
+	authLimit := middleware.AuthRateLimit(cfg.AuthRateLimit, cfg.AuthRateWindow, cfg.RateLimitJitter)
+	userHandler := handlers.NewUserHandler(userService, exportService, authLimit, cfg.RegistrationEnabled)
+	maintenance := middleware.NewMaintenanceMode(cfg.MaintenanceMode)
+	/* One cap shared by both transfer routes, whose queue depth is reported by GET /admin/db-stats */
+	transfers := middleware.NewSemaphore("transfer", cfg.TransferConcurrency, cfg.TransferQueueWait)
+	adminHandler := handlers.NewAdminHandler(userService, auditService, maintenance, transfers, db, cfg)
+	authHandler := handlers.NewAuthHandler(userService, cfg.JWTSecret, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTLeeway,
+		authLimit)
+	bookHandler := handlers.NewBookHandler(bookService, auditService, transfers, cfg)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	/* Redis is only used (and so only checked by the health handler) by the production rate limiter */
+	useRedis := cfg.ServerPort == "6379"
+	var pingRedis func(ctx context.Context) error
+	if useRedis {
+		pingRedis = middleware.PingRedis
+	}
+	healthHandler := handlers.NewHealthHandler(db, pingRedis, schemaRepo.MigrationStatus, readiness)
 
 	/* 5. Create new CHI Router. */
 	r := chi.NewRouter()
 	/* 6. Apply Middleware */
-	r.Use(middleware.CorsMiddleware(cfg))              /* 	>>>> Custom CORS Middleware <<<< */
-	r.Use(middleware.Logging, chimiddleware.Recoverer) /*   >>>> Custom and CHI-Built-In Middleware <<<<< */
-	r.Use(middleware.HSTS)                             /* 					  >>>> HTTPS Middleware <<<<< */
-	if cfg.ServerPort == "6379" {
-		r.Use(middleware.ProductionRateLimit()) /* 			 			 >>>> RATE LIMIT Middleware <<<<< */
-	} else {
-		r.Use(middleware.RateLimit) /* 			 						 >>>> RATE LIMIT Middleware <<<<< */
+	r.Use(middleware.Tracing)                                /* 			  >>>> OPENTELEMETRY Middleware <<<< */
+	r.Use(middleware.CorsMiddleware(cfg))                    /* 	>>>> Custom CORS Middleware <<<< */
+	r.Use(middleware.Logging, middleware.Recoverer)          /*   >>>> Custom and CHI-Built-In Middleware <<<<< */
+	r.Use(middleware.ProblemJSON)                            /* 					  >>>> RFC 7807 ERRORS Middleware <<<<< */
+	r.Use(middleware.AcceptCheck)                            /* 		  >>>> CONTENT NEGOTIATION Middleware <<<<< */
+	r.Use(middleware.JSONCase(cfg.JSONCase))                 /* 				  >>>> JSON KEY CASE Middleware <<<<< */
+	r.Use(middleware.BlockUserAgents(cfg.BlockedUserAgents)) /* 		  >>>> BLOCKED USER AGENTS Middleware <<<<< */
+	r.Use(maintenance.Middleware)                            /* 						  >>>> MAINTENANCE Middleware <<<<< */
+	r.Use(middleware.SlowRequests(cfg.SlowRequestThreshold)) /* 	  >>>> SLOW REQUESTS Middleware <<<<< */
+	r.Use(middleware.ResponseTimeout(cfg.ResponseTimeout))   /* 	  >>>> RESPONSE TIMEOUT Middleware <<<<< */
+	r.Use(middleware.Timeout(cfg.RequestTimeout))            /* 	  >>>> REQUEST TIMEOUT Middleware <<<<< */
+	r.Use(middleware.HSTS)                                   /* 					  >>>> HTTPS Middleware <<<<< */
+	r.Use(middleware.ContentLengthCheck)                     /* 		  >>>> CONTENT-LENGTH CHECK Middleware <<<<< */
+	r.Use(middleware.DebugBodyLogger(cfg))                   /* 			  >>>> DEBUG BODIES Middleware <<<<< */
+	/* 7. Select the Rate Limit Middleware - registered per group below (NOT globally) so that on protected
+	   routes it runs AFTER the JWT authentication and can limit by User ID rather than by IP. */
+	rateLimit := middleware.RateLimit(cfg.RateLimitJitter) /* 			 >>>> RATE LIMIT Middleware <<<<< */
+	if useRedis {
+		rateLimit = middleware.ProductionRateLimit(cfg.RateLimitJitter) /* 			 	 >>>> RATE LIMIT Middleware <<<<< */
+	}
+	/* Middleware of the routes using the Database only (see IMPORTANT NOTES 3.) */
+	usesDB := []func(http.Handler) http.Handler{
+		middleware.DBCircuitBreaker(dbBreaker, readBreaker),           /* 	 >>>> DB CIRCUIT BREAKER Middleware <<<<< */
+		middleware.DBAcquireTimeout(cfg.DBAcquireTimeout, db, readDB), /* >>>> DB POOL EXHAUSTION Middleware <<<<< */
 	}
-	/* 7. Register all the Routes to the corresponding Handlers. */
-	userHandler.RegisterRoutes(r)
-	authHandler.RegisterRoutes(r)
-	adminHandler.RegisterRoutes(r.With(middleware.JWTAuth(cfg.JWTSecret)))
-	bookHandler.RegisterRoutes(r)
-	//(r.With(middleware.JWTAuth(cfg.JWTSecret)))
-
-	/* 8. Register the Swagger Route to its imported Handler */
+	/* 8. Register all the PUBLIC Routes to the corresponding Handlers - Rate Limit by IP (but the probes) */
+	healthHandler.RegisterPublicRoutes(r)
 	r.Group(func(r chi.Router) {
-		//r.Use(middleware.JWTAuth(cfg.JWTSecret))
+		r.Use(rateLimit)
+		/* Register the Swagger Route to its imported Handler */
 		r.Get("/swagger/*", httpSwagger.WrapHandler)
+		r.Get("/openapi.json", handlers.GetOpenAPISpec) /* Raw spec for code generators */
+		r.Get("/books/example", bookHandler.GetBookExample)
+		r.Group(func(r chi.Router) {
+			r.Use(usesDB...)
+			userHandler.RegisterRoutes(r)
+			authHandler.RegisterRoutes(r)
+			bookHandler.RegisterPublicRoutes(r)
+		})
+	})
+	/* 9. Register all the PROTECTED Routes to the corresponding Handlers - Rate Limit by User ID */
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.JWTAuth(cfg.JWTSecret, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTLeeway), rateLimit)
+		r.Use(usesDB...)
+		r.Use(middleware.RejectSuspended(userService.IsActive)) /* 	>>>> SUSPENDED ACCOUNTS Middleware <<<<< */
+		adminHandler.RegisterRoutes(r)
+		userHandler.RegisterProtectedRoutes(r)
+		bookHandler.RegisterRoutes(r)
+		auditHandler.RegisterRoutes(r)
+		healthHandler.RegisterRoutes(r)
 	})
 
-	/* 9. Return the configured router so it can be used in main.go. */
-	return r
+	/* 10. Let GET /admin/routes walk the routes, now that all of them are registered */
+	adminHandler.Routes = r
+
+	/* 11. Return the configured router so it can be used in main.go, with its cleanup function. */
+	cleanup := func() {
+		if err := bookRepo.Close(); err != nil {
+			logger.Errorf("closing prepared statements: %v", err)
+		}
+		if readDB != db {
+			readDB.Close()
+		}
+		db.Close()
+	}
+	return r, cleanup
 }
 
 // 2. DB UTILITY METHODS ******************************************************************************************
 
-/* Initialize Connection to PostgreSQL Database */
-func initPostgres(connStr string) (*sql.DB, error) {
+/* Initialize Connection to PostgreSQL Database - every new connection goes through the input circuit breaker */
+func initPostgres(connStr string, dbBreaker *breaker.Breaker) (*sql.DB, error) {
 
-	/* 1. Create the Connection to the DB Engine (PostgreSQL) + Error Handling */
-	db, err := sql.Open("postgres", connStr)
+	/* 1. Create the Connection to the DB Engine (PostgreSQL) + Error Handling. The connector goes through the
+	   circuit breaker (nil = disabled) and the acquisition timeout (dbpool) and is wrapped by otelsql, so that every SQL call made within a traced
+	   HTTP Request gets its own child span (no span otherwise) */
+	connector, err := pq.NewConnector(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("Could not open DB: %w", err)
 	}
+	db := otelsql.OpenDB(dbBreaker.Connector(dbpool.Connector(connector)),
+		otelsql.WithAttributes(attribute.String("db.system", "postgresql")),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanFromContext(ctx).SpanContext().IsValid()
+			},
+		}))
 
 	/* 2. Verify presence/status of the connection */
 	if err := db.Ping(); err != nil {
@@ -115,7 +223,7 @@ func initPostgres(connStr string) (*sql.DB, error) {
 	db.SetConnMaxIdleTime(30 * time.Minute)
 
 	/* 4. Send Info Message to user via Console Window */
-	log.Println("Connnected to PostgreSQL successfully.")
+	logger.Infof("Connnected to PostgreSQL successfully.")
 
 	/* 5. Return Pointer to Database Connection and Error object */
 	return db, nil
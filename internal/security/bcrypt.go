@@ -9,6 +9,16 @@ package security
 /* 1. Bcrypt Hashing Algorithm
 - bcrypt is a cryptographic hashing algorithm designed for password hashing.
   It’s slow by design to resist brute-force attacks.
+   2. Pepper
+- The optional pepper (PASSWORD_PEPPER) is an application-wide secret appended to the password before hashing and
+  before comparing: unlike the salt, it is NOT stored in the Database, so a leaked users table alone isn't enough
+  to brute-force the hashes. An empty pepper changes nothing (hashes created before it was set keep working only
+  while it stays empty!).
+- bcrypt only accepts up to 72 bytes: password + pepper longer than that can't be hashed (HashPassword fails).
+   3. Cost Upgrades
+- The cost factor is stored inside every hash. When BCRYPT_COST gets raised, the existing hashes keep their old
+  (lower) cost: RehashIfWeaker re-hashes the password at the new cost on the next successful login, when the
+  plain text password is available, so that the hashes get upgraded gradually without forcing password resets.
 */
 
 // 1. IMPORT PACKAGES *******************************************************************************************
@@ -19,19 +29,39 @@ import (
 // 2. HASHING METHODS *******************************************************************************************
 
 /* Convert String Password to Hash */
-func HashPassword(password string) (string, error) {
+func HashPassword(password, pepper string, cost int) (string, error) {
 	/* 1. Convert the input string password into a Hash via bcrypt algorithm + return any error.
-	DefaultCost=10...a cost factor value that is a good balance between security and performance.
-	The cost factor is a measure of the computational complexity of the algorithm. */
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	DefaultCost=10 (used when cost < MinCost)...a cost factor value that is a good balance between security and
+	performance. The cost factor is a measure of the computational complexity of the algorithm. */
+	hash, err := bcrypt.GenerateFromPassword([]byte(password+pepper), cost)
 	/* 2. Convert byte slice hash to string and return it together with any error encountered */
 	return string(hash), err
 }
 
 /* Compare Hash with String Password */
-func CheckPasswordHash(password, hash string) bool {
-	/* 1. Convert hash and password to byte slices and compares the two returning an error if not successful */
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+func CheckPasswordHash(password, hash, pepper string) bool {
+	/* 1. Convert hash and (peppered) password to byte slices and compares the two returning an error if not
+	   successful - the pepper MUST be appended exactly as in HashPassword */
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password+pepper))
 	/* 2. Return True if match, False if not */
 	return err == nil
 }
+
+/* Re-hash the (verified!) password if its hash cost is below cost (IMPORTANT NOTES 3.): new hash + true if upgraded */
+func RehashIfWeaker(password, hash, pepper string, cost int) (string, bool, error) {
+	/* 1. Read the cost stored in the hash + Error Handling */
+	current, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return "", false, err
+	}
+	/* 2. Nothing to do if the hash is already as strong as required */
+	if current >= max(cost, bcrypt.MinCost) {
+		return "", false, nil
+	}
+	/* 3. Otherwise hash the password again at the new cost */
+	upgraded, err := HashPassword(password, pepper, cost)
+	if err != nil {
+		return "", false, err
+	}
+	return upgraded, true, nil
+}
@@ -0,0 +1,41 @@
+package security
+
+// security/ PACKAGE TESTS ****************************************************************************************
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 2. TESTS *******************************************************************************************************
+
+/* TESTER for the upgrade of the password hashes to a higher cost ---------------------------------------------*/
+func TestRehashIfWeaker(t *testing.T) {
+	/* 1. A hash created at the minimum cost (kept low to keep the test fast) */
+	oldHash, err := HashPassword("secret", "pepper", bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Hashing failed: %v", err)
+	}
+
+	/* 2. A higher configured cost upgrades it, and the new hash still matches the password */
+	newHash, upgraded, err := RehashIfWeaker("secret", oldHash, "pepper", bcrypt.MinCost+1)
+	if err != nil || !upgraded {
+		t.Fatalf("Expected the hash to be upgraded, got upgraded=%v err=%v", upgraded, err)
+	}
+	if cost, _ := bcrypt.Cost([]byte(newHash)); cost != bcrypt.MinCost+1 {
+		t.Errorf("Expected the new hash to have cost %d, got %d", bcrypt.MinCost+1, cost)
+	}
+	if !CheckPasswordHash("secret", newHash, "pepper") {
+		t.Errorf("The upgraded hash doesn't match the password anymore")
+	}
+
+	/* 3. The same or a lower configured cost leaves it untouched */
+	if _, upgraded, err := RehashIfWeaker("secret", newHash, "pepper", bcrypt.MinCost); upgraded || err != nil {
+		t.Errorf("Expected no upgrade to a lower cost, got upgraded=%v err=%v", upgraded, err)
+	}
+	if _, upgraded, _ := RehashIfWeaker("secret", newHash, "pepper", bcrypt.MinCost+1); upgraded {
+		t.Errorf("Expected no upgrade to the same cost")
+	}
+}
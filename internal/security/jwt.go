@@ -12,10 +12,27 @@ package security
    2. JWT Token
 	- A secure string used to identify a user (like a digital ID card) which can be used for login sessions
   	  or API authentication
+   3. Issuer (iss) and Audience (aud) Claims
+	- Every token says who issued it and who it is meant for, so that an API gateway can validate it too.
+	  ParseToken(..) rejects tokens whose iss/aud claims are missing or don't match the configured values.
+   4. Leeway
+	- The clocks of different servers are never perfectly in sync: a token issued/expiring "now" on one server may
+	  look not valid yet/expired on another. The exp, iat and nbf claims are therefore checked allowing a small
+	  configurable leeway (JWT_LEEWAY, 30s by default).
+   5. Impersonation Tokens
+	- GenerateImpersonationToken(..) issues a token for a user on behalf of an admin: on top of the usual claims it
+	  carries the admin's ID as "impersonated_by" and it expires much sooner (IMPERSONATION_TTL, 15m by default).
+	  It can't be extended via POST /auth/extend: the admin has to ask for a new one (and get audited again).
+   6. Share Tokens
+	- GenerateShareToken(..) issues the token of a read-only link to ONE book (GET /shared/{token}): it identifies
+	  no user, only the book ("share_book_id"). Its audience is the configured one + ShareAudienceSuffix, so that
+	  ParseToken(..) (hence JWTAuth, /auth/verify and /auth/extend) rejects it, while ParseShareToken(..) rejects
+	  every other token. There's no revocation list: a link stops working when it expires (SHARE_LINK_TTL).
 */
 
 // 1. IMPORT PACKAGES *********************************************************************************************
 import (
+	"errors"
 	"strings"
 	"time"
 
@@ -23,13 +40,15 @@ import (
 )
 
 /* Method allowing to create a secure token for a user */
-func GenerateToken(userID int, userRole string, secret string) (string, error) {
+func GenerateToken(userID int, userRole string, secret, issuer, audience string) (string, error) {
 	/* 1. Define the "claims" (i.e. - the inside part) of the Token */
 	claims := jwt.MapClaims{
 		"user_id":   userID,                                /* Embed the user's id in the token */
 		"user_role": userRole,                              /* Embed the user's role in the token */
 		"exp":       time.Now().Add(24 * time.Hour).Unix(), /* Set the expiration time to 24 hours from now.*/
 		"iat":       time.Now().Unix(),                     /* Set the issued-at time to the current time.*/
+		"iss":       issuer,                                /* Set who issued the token (i.e. this API) */
+		"aud":       audience,                              /* Set who the token is meant for */
 	}
 	/* 2. Create the token using the secure method HS256 including in it user info and time settings */
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -37,18 +56,78 @@ func GenerateToken(userID int, userRole string, secret string) (string, error) {
 	return token.SignedString([]byte(secret))
 }
 
+/* Method allowing an admin (impersonatedBy) to act as the input user for ttl + expiration (see IMPORTANT NOTES 5.) */
+func GenerateImpersonationToken(userID int, userRole string, impersonatedBy int, ttl time.Duration, secret, issuer,
+	audience string) (string, time.Time, error) {
+	/* 1. Define the claims of the Token: the usual ones plus the impersonator, with a short expiration */
+	expiresAt := time.Now().Add(ttl)
+	claims := jwt.MapClaims{
+		"user_id":         userID,
+		"user_role":       userRole,
+		"impersonated_by": impersonatedBy, /* Embed the admin acting as the user */
+		"exp":             expiresAt.Unix(),
+		"iat":             time.Now().Unix(),
+		"iss":             issuer,
+		"aud":             audience,
+	}
+	/* 2. Create and sign the token exactly as GenerateToken does */
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	return token, expiresAt, err
+}
+
+/* Suffix appended to the configured audience in the share tokens (see IMPORTANT NOTES 6.) */
+const ShareAudienceSuffix = ":share"
+
+/* Error returned by ParseShareToken(..) for valid tokens that are not share tokens */
+var ErrNotShareToken = errors.New("not a share token")
+
+/* Method creating the token of a read-only link to the input book for ttl + expiration (see IMPORTANT NOTES 6.) */
+func GenerateShareToken(bookID int, ttl time.Duration, secret, issuer, audience string) (string, time.Time, error) {
+	/* 1. Define the claims of the Token: the shared book only, no user */
+	expiresAt := time.Now().Add(ttl)
+	claims := jwt.MapClaims{
+		"share_book_id": bookID, /* Embed the shared book's id in the token */
+		"exp":           expiresAt.Unix(),
+		"iat":           time.Now().Unix(),
+		"iss":           issuer,
+		"aud":           audience + ShareAudienceSuffix, /* Never accepted as an authentication token */
+	}
+	/* 2. Create and sign the token exactly as GenerateToken does */
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	return token, expiresAt, err
+}
+
+/* Method checking a share token (signature, iss, share audience, expiration) and returning the shared book ID */
+func ParseShareToken(tokenStr, secret, issuer, audience string, leeway time.Duration) (int, error) {
+	/* 1. Same checks as any other token, but against the share audience */
+	claims, err := ParseToken(tokenStr, secret, issuer, audience+ShareAudienceSuffix, leeway)
+	if err != nil {
+		return 0, err
+	}
+	/* 2. Read the shared book ID (JSON numbers are decoded as float64) */
+	bookID, ok := claims["share_book_id"].(float64)
+	if !ok {
+		return 0, ErrNotShareToken
+	}
+	return int(bookID), nil
+}
+
 /* Method allowing to check that whether the token is valid and read the info inside it */
-func ParseToken(tokenStr, secret string) (jwt.MapClaims, error) {
+func ParseToken(tokenStr, secret, issuer, audience string, leeway time.Duration) (jwt.MapClaims, error) {
 	/* 1. Remove empty spaces within the Token string if present */
 	tokenStr = strings.ReplaceAll(tokenStr, " ", "")
-	/* 2. Try to decode the input Token with the input Key */
+	/* 2. Try to decode the input Token with the input Key, requiring the expected issuer and audience and
+	   tolerating the input clock skew (see IMPORTANT NOTES 4.) */
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
-	})
-	/* 3. If the Token is broken (err!=nil) or expired (!token.Valid), return an error */
-	if err != nil || !token.Valid {
+	}, jwt.WithIssuer(issuer), jwt.WithAudience(audience), jwt.WithLeeway(leeway))
+	/* 3. If the Token is broken/mismatching (err!=nil) or expired (!token.Valid), return an error */
+	if err != nil {
 		return nil, err
 	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
 	/* 4. Try to extract the Claims of the token (the part that holds user info and timestamps)
 	   also checking whether they are in the expected format (jwt.MapClaims) */
 	claims, ok := token.Claims.(jwt.MapClaims)
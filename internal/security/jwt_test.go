@@ -0,0 +1,88 @@
+package security
+
+// security/ PACKAGE TESTS ****************************************************************************************
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 2. TESTS *******************************************************************************************************
+
+/* TESTER for the clock skew tolerated when parsing a Token ---------------------------------------------------*/
+func TestParseToken_Leeway(t *testing.T) {
+	/* 1. A token that expired 10 seconds ago (e.g. according to a server whose clock is slightly ahead) */
+	claims := jwt.MapClaims{
+		"user_id":   1,
+		"user_role": "user",
+		"exp":       time.Now().Add(-10 * time.Second).Unix(),
+		"iat":       time.Now().Add(-time.Hour).Unix(),
+		"iss":       "bookapi",
+		"aud":       "bookapi",
+	}
+	tokenStr, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Signing failed: %v", err)
+	}
+
+	/* 2. It's still accepted within a 30s leeway.. */
+	if _, err := ParseToken(tokenStr, "secret", "bookapi", "bookapi", 30*time.Second); err != nil {
+		t.Errorf("Expected the token to be accepted within the leeway, got %v", err)
+	}
+	/* 3. ..but rejected with no leeway */
+	if _, err := ParseToken(tokenStr, "secret", "bookapi", "bookapi", 0); err == nil {
+		t.Errorf("Expected the expired token to be rejected with no leeway")
+	}
+}
+
+/* TESTER for the short-lived tokens issued to the admins impersonating a user ---------------------------------*/
+func TestGenerateImpersonationToken(t *testing.T) {
+	/* 1. Admin 1 acting as user 2 for 15 minutes */
+	tokenStr, expiresAt, err := GenerateImpersonationToken(2, "user", 1, 15*time.Minute, "secret", "bookapi", "bookapi")
+	if err != nil {
+		t.Fatalf("Generating the token failed: %v", err)
+	}
+	claims, err := ParseToken(tokenStr, "secret", "bookapi", "bookapi", 0)
+	if err != nil {
+		t.Fatalf("Expected a valid token, got %v", err)
+	}
+	/* 2. It carries the user, the impersonating admin and the short expiration */
+	if claims["user_id"] != float64(2) || claims["impersonated_by"] != float64(1) {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp.Unix() != expiresAt.Unix() || time.Until(expiresAt) > 15*time.Minute {
+		t.Errorf("Expected the token to expire in 15 minutes at %v, got %v (%v)", expiresAt, exp, err)
+	}
+}
+
+/* TESTER for the tokens of the read-only share links ------------------------------------------------------------*/
+func TestGenerateShareToken(t *testing.T) {
+	/* 1. Share link to book 7 for one hour */
+	tokenStr, expiresAt, err := GenerateShareToken(7, time.Hour, "secret", "bookapi", "bookapi")
+	if err != nil {
+		t.Fatalf("Generating the token failed: %v", err)
+	}
+	if time.Until(expiresAt) > time.Hour {
+		t.Errorf("Expected the token to expire within an hour, got %v", expiresAt)
+	}
+	bookID, err := ParseShareToken(tokenStr, "secret", "bookapi", "bookapi", 0)
+	if err != nil || bookID != 7 {
+		t.Fatalf("Expected book 7, got %d (%v)", bookID, err)
+	}
+	/* 2. It's never accepted as an authentication token... */
+	if _, err := ParseToken(tokenStr, "secret", "bookapi", "bookapi", 0); err == nil {
+		t.Errorf("Expected the share token to be rejected as an authentication token")
+	}
+	/* 3. ...and an authentication token is never accepted as a share token */
+	authToken, err := GenerateToken(1, "user", "secret", "bookapi", "bookapi")
+	if err != nil {
+		t.Fatalf("Generating the token failed: %v", err)
+	}
+	if _, err := ParseShareToken(authToken, "secret", "bookapi", "bookapi", 0); err == nil {
+		t.Errorf("Expected the authentication token to be rejected as a share token")
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+// services/ PACKAGE **********************************************************************************************
+/* The services/ package stores all the Business Logic, hence the methods that carry out operations and
+   modifications to data/data structures while being completely decoupled from HTTP Requests and Methods. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Recording Failures
+	- The audit entry gets recorded AFTER the change has succeeded: failing to record it is logged as ERROR but
+	  doesn't turn the (already committed) change into an error for the client.
+	- Record still returns the error, for the actions that must NOT happen unaudited (e.g. the impersonation
+	  token only gets handed out once its entry has been recorded).
+   2. Nil AuditService
+	- Record is a no-op (nil error) on a nil *AuditService, so that handlers built without one (e.g. in the handler tests)
+	  don't need any special case.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	/* INTERNAL Packages */
+	"bookapi/internal/logger"
+	"bookapi/internal/models"
+	"bookapi/internal/repositories"
+
+	/* EXTERNAL Packages */
+	"context"
+	"time"
+)
+
+// 2. GO STRUCTS and UTILITY VARIABLES ****************************************************************************
+
+/* STRUCT */
+type AuditService struct {
+	Repo *repositories.AuditRepository
+}
+
+/* STRUCT BUILDER */
+func NewAuditService(repo *repositories.AuditRepository) *AuditService {
+	return &AuditService{Repo: repo}
+}
+
+// 3. BUSINESS LOGIC METHODS **************************************************************************************
+
+/* RECORD Action ------------------------------------------------------------------------------------------------*/
+/* Record that the user (possibly impersonated, see models/audit.go) performed the action on the resource
+   (see IMPORTANT NOTES 1. and 2.) */
+func (s *AuditService) Record(ctx context.Context, entry models.AuditEntry) error {
+	if s == nil {
+		return nil
+	}
+	/* The request context may be cancelled right after the response: record the entry anyway */
+	if err := s.Repo.Create(context.WithoutCancel(ctx), entry); err != nil {
+		logger.Errorf("Could not record audit entry %+v: %v", entry, err)
+		return err
+	}
+	return nil
+}
+
+/* LIST Activity ------------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /me/activity: one page of the user's entries (newest first)
+   together with the total number of entries */
+func (s *AuditService) ListActivity(ctx context.Context, userID, limit, offset int) ([]models.AuditEntry, int, error) {
+	entries, err := s.Repo.FindByUser(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.Repo.CountByUser(ctx, userID)
+	return entries, total, err
+}
+
+/* EXPORT Audit Log ---------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /admin/audit/export: hand every entry of the optional [from, to]
+   range to fn, oldest first, one at a time (nothing gets buffered here) */
+func (s *AuditService) Export(ctx context.Context, from, to *time.Time, fn func(models.AuditEntry) error) error {
+	return s.Repo.ForEach(ctx, from, to, fn)
+}
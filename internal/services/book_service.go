@@ -30,6 +30,20 @@ package services
 		  Hence the need to create a BookService interface that both the bookService struct and mockBookService
 		  struct have to implement (in Go, it's just enough that the signatures of all their methods match with
 		  the ones of the interface!)
+	6. Request Coalescing (GetBookByID)
+		- Concurrent GetBookByID calls for the SAME id share ONE DB query (singleflight): under a thundering herd
+		  on a hot book the Database sees 1 query instead of 100. Each caller gets its own copy of the book.
+		- The shared query runs with the context of the first caller MINUS its cancellation, so that one client
+		  going away doesn't fail the requests of all the others waiting for the same book. It gets its own
+		  deadline instead (sharedReadTimeout), so that a stuck query can't hold the id forever.
+		- Each caller waits for the shared result OR for its own context: a caller that goes away (or times out)
+		  returns straight away with its context error, while the query keeps running for the others.
+	7. Input Sanitization (SANITIZE_INPUT, opt-in)
+		- When enabled, the HTML tags in the title and author of every book written by the clients get stripped
+		  (and the remaining special characters escaped, e.g. & -> &amp;) with bluemonday's strict policy BEFORE
+		  validating and storing them, so that a frontend rendering them as HTML can't run a stored <script>.
+		- It is OFF by default: API-only consumers get back exactly the values they sent. Only the incoming values
+		  get sanitized (e.g. PATCH sanitizes just the fields in the patch), so stored values never get escaped twice.
 */
 
 // 1. IMPORT PACKAGES *********************************************************************************************
@@ -41,7 +55,17 @@ import (
 	"bookapi/internal/repositories"
 
 	/* EXTERNAL Packages */
+	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/microcosm-cc/bluemonday"
+	"golang.org/x/sync/singleflight"
 )
 
 // 2. GO STRUCTS and UTILITY VARIABLES ****************************************************************************
@@ -55,103 +79,338 @@ import (
    have to implement (in Go, it's just enough that the signatures of all their methods match with the ones of the
    interface!) */
 type BookService interface {
-	ListBooks() ([]models.Book, error)
-	GetBookByID(id int) (*models.Book, error)
-	CreateBook(book models.Book) (models.Book, error)
-	TransferPages(req models.TransferRequest) error
-	UpdateBook(id int, updated models.Book) (*models.Book, error)
-	DeleteBook(id int) error
-	GetOwnerID(bookID int) (int, error)
+	ListBooks(ctx context.Context, filter models.BookFilter) ([]models.Book, error)
+	SearchBooks(ctx context.Context, query string) ([]models.Book, error)
+	CountBooks(ctx context.Context, filter models.BookFilter) (int, error)
+	QueryBooks(ctx context.Context, q models.BookQuery) ([]models.Book, models.Pagination, error)
+	GetBookByID(ctx context.Context, id int) (*models.Book, error)
+	GetBooksByIDs(ctx context.Context, ids []int) ([]models.Book, error)
+	CreateBook(ctx context.Context, book models.Book) (models.Book, error)
+	ValidateBook(ctx context.Context, book models.Book) error
+	TransferPages(ctx context.Context, req models.TransferRequest) error
+	TransferPagesBatch(ctx context.Context, transfers []models.TransferRequest) error
+	MergeBooks(ctx context.Context, intoID, fromID, ownerID int) (*models.Book, error)
+	UpdateBook(ctx context.Context, id int, updated models.Book) (*models.Book, error)
+	PatchBook(ctx context.Context, id int, patch models.BookPatch) (*models.Book, []string, error)
+	DeleteBook(ctx context.Context, id int) error
+	GetOwnerID(ctx context.Context, bookID int) (int, error)
+	ListAuthors(ctx context.Context, prefix string, ownerID int) ([]string, error)
+	UpdatePages(ctx context.Context, updates []models.PagesUpdate, ownerID int) (models.BulkUpdateResult, error)
+	UpdateBookIfUnmodifiedSince(ctx context.Context, id int, updated models.Book, since time.Time) (*models.Book, error)
+	ReplaceBook(ctx context.Context, id int, book models.Book) (*models.Book, bool, error)
+	ReviewBook(ctx context.Context, review models.Review) (models.Review, bool, error)
+	ListReviews(ctx context.Context, bookID, limit, offset int) ([]models.Review, models.Pagination, error)
+	AddFavorite(ctx context.Context, userID, bookID int) error
+	RemoveFavorite(ctx context.Context, userID, bookID int) error
+	ListFavorites(ctx context.Context, userID int) ([]models.Book, error)
+	ListChangedSince(ctx context.Context, since time.Time) ([]models.Book, models.SyncMeta, error)
+	ListPopular(ctx context.Context, limit, offset int) ([]models.PopularBook, models.Pagination, error)
+	CompareBooks(ctx context.Context, aID, bID int) (*models.BookComparison, error)
+	TotalPages(ctx context.Context, userID int) (int, error)
+	CheckOwnership(ctx context.Context, userID int, ids []int) (map[int]bool, error)
+}
+
+/* ERRORS */
+/* Re-exported so that the handlers/ package can map it to 403 without talking to the repositories/ package */
+var ErrNotOwner = repositories.ErrNotOwner
+var ErrPreconditionFailed = repositories.ErrPreconditionFailed
+var ErrBookNotFound = repositories.ErrBookNotFound
+var ErrAlreadyExists = repositories.ErrAlreadyExists /* unique violations (books and users) -> 409 */
+type AlreadyExistsError = repositories.AlreadyExistsError
+type TransferBatchError = repositories.TransferBatchError /* failed transfer of a batch -> 409 with its index */
+
+var ErrInvalidRating = errors.New("Rating must be between 1 and 5")
+var ErrCommentTooLong = errors.New("Comment must be at most 2000 characters")
+
+/* Max number of transfers of one POST /books/transfer/batch (they all run within one transaction) */
+const maxTransferBatch = 100
+
+/* Max number of IDs of one POST /books/ownership */
+const maxOwnershipIDs = 100
+
+/* Validation Error - every failed check of one input, keyed by JSON field name (e.g. "to_id") */
+type ValidationError map[string]string
+
+/* Implement the error interface: "field: message" pairs sorted by field, so that the text is deterministic */
+func (v ValidationError) Error() string {
+	fields := make([]string, 0, len(v))
+	for field := range v {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for i, field := range fields {
+		fields[i] = field + ": " + v[field]
+	}
+	return strings.Join(fields, "; ")
 }
 
 /* STRUCT */
 /* Such struct is part of the service layer, which connects business logic with the repository (database) layer. */
 type bookService struct {
-	Repo repositories.BookRepository
+	Repo             repositories.BookRepository
+	MaxTransferPages int                /* Max pages of one transfer (MAX_TRANSFER_PAGES), bigger ones are rejected */
+	reads            singleflight.Group /* Coalesces the concurrent GetBookByID calls, keyed by id (see IMPORTANT NOTES 6.) */
+	sanitizer        *bluemonday.Policy /* HTML sanitizer of titles and authors, nil if disabled (see IMPORTANT NOTES 7.) */
 }
 
-/* STRUCT BUILDER */
-func NewBookService(repo repositories.BookRepository) BookService {
-	return &bookService{Repo: repo}
+/* Deadline of the DB query shared by the concurrent GetBookByID calls (see IMPORTANT NOTES 6.) */
+const sharedReadTimeout = 10 * time.Second
+
+/* STRUCT BUILDER - maxTransferPages <= 0 falls back to the max pages of one book (models.MaxPages) */
+func NewBookService(repo repositories.BookRepository, maxTransferPages int, sanitizeInput bool) BookService {
+	if maxTransferPages <= 0 {
+		maxTransferPages = models.MaxPages
+	}
+	service := &bookService{Repo: repo, MaxTransferPages: maxTransferPages}
+	if sanitizeInput {
+		service.sanitizer = bluemonday.StrictPolicy()
+	}
+	return service
 }
 
 // 3. BUSINESS LOGIC METHODS **************************************************************************************
 
 /* GET AllBooks -------------------------------------------------------------------------------------------------*/
 /* Method Mirroring STATIC HTTP Handler for GET /books */
-func (s *bookService) ListBooks() ([]models.Book, error) {
-	/* 1. Call the Repo Method and return the list of books from the Database */
-	return s.Repo.FindAll()
+func (s *bookService) ListBooks(ctx context.Context, filter models.BookFilter) ([]models.Book, error) {
+	/* 1. Call the Repo Method and return the (filtered) list of books from the Database */
+	return s.Repo.FindAll(ctx, filter)
+}
+
+/* SEARCH Books -------------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /books?q= - books whose title or author contains the query */
+func (s *bookService) SearchBooks(ctx context.Context, query string) ([]models.Book, error) {
+	return s.Repo.Search(ctx, query)
+}
+
+/* COUNT Books --------------------------------------------------------------------------------------------------*/
+/* Total number of books matching the filter, used to paginate GET /books */
+func (s *bookService) CountBooks(ctx context.Context, filter models.BookFilter) (int, error) {
+	return s.Repo.Count(ctx, filter)
+}
+
+/* QUERY Books --------------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for POST /books/query: validates the query object, then returns one page
+   of the matching books together with the pagination meta (total of the matching books) */
+func (s *bookService) QueryBooks(ctx context.Context, q models.BookQuery) ([]models.Book, models.Pagination, error) {
+	/* 1. Validate the query object (see validateBookQuery) and apply the default page size */
+	if err := s.validateBookQuery(q); err != nil {
+		return nil, models.Pagination{}, err
+	}
+	if q.Limit == 0 {
+		q.Limit = 20
+	}
+	/* 2. Get the page of books and the total of the matching ones via Repo Methods */
+	books, err := s.Repo.FindByQuery(ctx, q)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	total, err := s.Repo.CountByQuery(ctx, q)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	return books, models.Pagination{Total: total, Limit: q.Limit, Offset: q.Offset}, nil
 }
 
 /* GET Book -----------------------------------------------------------------------------------------------------*/
 /* Method Mirroring DYNAMIC HTTP Handler for GET /books/{id} */
-func (s *bookService) GetBookByID(id int) (*models.Book, error) {
-	/* 1. Call the Repo Method and get the book item + error object returned */
-	book, err := s.Repo.FindByID(id)
-	/* 2. Error Handling on both book and err obejcts */
-	if err != nil {
-		return nil, err
+func (s *bookService) GetBookByID(ctx context.Context, id int) (*models.Book, error) {
+	/* 1. Call the Repo Method (once for all the concurrent callers asking for this id) and get the book item +
+	   error object returned */
+	result := s.reads.DoChan(strconv.Itoa(id), func() (any, error) {
+		queryCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedReadTimeout)
+		defer cancel()
+		return s.Repo.FindByID(queryCtx, id)
+	})
+	/* 2. Wait for the shared result, unless this caller goes away first */
+	var shared singleflight.Result
+	select {
+	case shared = <-result:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	/* 3. Error Handling on both book and err obejcts */
+	if shared.Err != nil {
+		return nil, shared.Err
 	}
-	if book == nil {
+	found, _ := shared.Val.(*models.Book)
+	if found == nil {
 		return nil, errors.New("Book not found.")
 	}
-	/* 3. Return the found book object and null error */
-	return book, nil
+	/* 4. Return a copy of the found book object (the shared one may be in use by other callers) and null error */
+	book := *found
+	return &book, nil
+}
+
+/* GET Books by IDs ---------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /books?ids=1,2,3 */
+func (s *bookService) GetBooksByIDs(ctx context.Context, ids []int) ([]models.Book, error) {
+	/* 1. Call the Repo Method and return the found books (missing IDs are not an error) */
+	return s.Repo.FindByIDs(ctx, ids)
 }
 
 /* CREATE Book ---------------------------------------------------------------------------------------------------*/
 /* Method Mirroring STATIC HTTP Handler for POST /books */
-func (s *bookService) CreateBook(book models.Book) (models.Book, error) {
-	/* 1. Check JSON Fields' values are not empty/not acceptable + Error Handling */
+func (s *bookService) CreateBook(ctx context.Context, book models.Book) (models.Book, error) {
+	/* 1. Check JSON Fields' values are not empty/not acceptable (once sanitized) + Error Handling */
+	book = s.sanitizeBook(book)
 	err := s.validateBook(book)
 	if err != nil {
 		return models.Book{}, err
 	}
 	/* 2. Call the Repo Method and return the created book from the database + any error */
-	return s.Repo.Create(book)
+	return s.Repo.Create(ctx, book)
+}
+
+/* VALIDATE Book (dry run) ---------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for POST /books/validate: the same sanitization and rules as CreateBook
+   (see bookFailures) with nothing stored, reporting EVERY failed check as a ValidationError (nil if valid) */
+func (s *bookService) ValidateBook(ctx context.Context, book models.Book) error {
+	if failures := bookFailures(s.sanitizeBook(book)); len(failures) > 0 {
+		return failures
+	}
+	return nil
 }
 
 /* TRANSFER pages ------------------------------------------------------------------------------------------------*/
 /* Method Mirroring STATIC HTTP Handler for POST /transfer */
-func (s *bookService) TransferPages(req models.TransferRequest) error {
+func (s *bookService) TransferPages(ctx context.Context, req models.TransferRequest) error {
 	/* 1. Check JSON Fields' values are not empty/not acceptable + Error Handling */
 	err := s.validateTransferRequest(req)
 	if err != nil {
 		return err
 	}
 	/* 2. Call the Repo Method and return the created book from the database + any error */
-	err = s.Repo.TransferPages(req)
+	err = s.Repo.TransferPages(ctx, req)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+/* TRANSFER pages in BATCH --------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for POST /books/transfer/batch */
+func (s *bookService) TransferPagesBatch(ctx context.Context, transfers []models.TransferRequest) error {
+	/* 1. Check JSON Fields' values of every transfer (keyed by "[index].field") + Error Handling */
+	err := s.validateTransferBatch(transfers)
+	if err != nil {
+		return err
+	}
+	/* 2. Call the Repo Method running all the transfers in one transaction + any error */
+	return s.Repo.TransferPagesBatch(ctx, transfers)
+}
+
+/* MERGE Books --------------------------------------------------------------------------------------------------*/
+/* Method Mirroring DYNAMIC HTTP Handler for POST /books/{id}/merge (ownerID=0 -> admin, no ownership check) */
+func (s *bookService) MergeBooks(ctx context.Context, intoID, fromID, ownerID int) (*models.Book, error) {
+	/* 1. Check the ID of the duplicate + Error Handling */
+	if fromID <= 0 {
+		return nil, ValidationError{"merge_from_id": "Merged Book ID is invalid"}
+	}
+	if fromID == intoID {
+		return nil, ValidationError{"merge_from_id": "A book cannot be merged into itself"}
+	}
+	/* 2. Call the Repo Method and return the merged book from the database + any error */
+	return s.Repo.Merge(ctx, intoID, fromID, ownerID)
+}
+
 /* UPDATE Book --------------------------------------------------------------------------------------------------*/
 /* Method Mirroring DYNAMIC HTTP Handler for PUT /books/{id} */
-func (s *bookService) UpdateBook(id int, updated models.Book) (*models.Book, error) {
-	/* 1. Check JSON Fields' values are not empty/not acceptable + Error Handling */
+func (s *bookService) UpdateBook(ctx context.Context, id int, updated models.Book) (*models.Book, error) {
+	/* 1. Check JSON Fields' values are not empty/not acceptable (once sanitized) + Error Handling */
+	updated = s.sanitizeBook(updated)
 	err := s.validateBook(updated)
 	if err != nil {
 		return nil, err
 	}
 	/* 2. Call the Repo Method and return the updated book from the database + any error */
-	return s.Repo.Update(id, updated)
+	return s.Repo.Update(ctx, id, updated)
+}
+
+/* PATCH Book ---------------------------------------------------------------------------------------------------*/
+/* Method Mirroring DYNAMIC HTTP Handler for PATCH /books/{id}. Returns the updated book together with the JSON
+   names of the fields whose value has actually changed (compared to the book read before the update). */
+func (s *bookService) PatchBook(ctx context.Context, id int, patch models.BookPatch) (*models.Book, []string, error) {
+	/* 1. Read the current book (one extra query, needed to fill in the missing fields and to diff) */
+	before, err := s.Repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	/* 2. Apply the fields present in the patch (once sanitized) to a copy of the current book */
+	patched := *before
+	if patch.Title != nil {
+		patched.Title = s.sanitizeText(*patch.Title)
+	}
+	if patch.Author != nil {
+		patched.Author = s.sanitizeText(*patch.Author)
+	}
+	if patch.Pages != nil {
+		patched.Pages = *patch.Pages
+	}
+	if patch.Year != nil {
+		patched.Year = *patch.Year
+	}
+	/* 3. Check the resulting book like a full update + Error Handling */
+	if err := s.validateBook(patched); err != nil {
+		return nil, nil, err
+	}
+	/* 4. Call the Repo Method, then diff the book before and after the update */
+	after, err := s.Repo.Update(ctx, id, patched)
+	if err != nil {
+		return nil, nil, err
+	}
+	after.AvgRating = before.AvgRating /* not touched by the update (not read back either) */
+	return after, changedFields(*before, *after), nil
+}
+
+/* CONDITIONAL UPDATE Book -------------------------------------------------------------------------------------*/
+/* Method Mirroring DYNAMIC HTTP Handler for PUT /books/{id} with the If-Unmodified-Since Header */
+func (s *bookService) UpdateBookIfUnmodifiedSince(ctx context.Context, id int, updated models.Book, since time.Time) (*models.Book, error) {
+	/* 1. Check JSON Fields' values are not empty/not acceptable (once sanitized) + Error Handling */
+	updated = s.sanitizeBook(updated)
+	err := s.validateBook(updated)
+	if err != nil {
+		return nil, err
+	}
+	/* 2. Call the Repo Method and return the updated book + any error (ErrPreconditionFailed if modified since) */
+	return s.Repo.UpdateIfUnmodifiedSince(ctx, id, updated, since)
+}
+
+/* REPLACE OR CREATE Book --------------------------------------------------------------------------------------*/
+/* Method Mirroring DYNAMIC HTTP Handler for PUT /books/{id} in Create-or-Replace mode */
+func (s *bookService) ReplaceBook(ctx context.Context, id int, book models.Book) (*models.Book, bool, error) {
+	/* 1. Check the id (it may become a new book's id, see models.MaxUpsertBookID) and the JSON Fields' values
+	   (once sanitized) + Error Handling */
+	if id < 1 || id > models.MaxUpsertBookID {
+		return nil, false, ValidationError{"id": fmt.Sprintf("must be between 1 and %d", models.MaxUpsertBookID)}
+	}
+	book = s.sanitizeBook(book)
+	err := s.validateBook(book)
+	if err != nil {
+		return nil, false, err
+	}
+	/* 2. Call the Repo Method + Error Handling */
+	book.ID = id
+	result, inserted, err := s.Repo.Upsert(ctx, book)
+	if err != nil {
+		return nil, false, err
+	}
+	/* 3. Return the book + whether it has been created (inserted rather than replaced by the upsert) */
+	return &result, inserted, nil
 }
 
 /* DELETE Book --------------------------------------------------------------------------------------------------*/
 /* Method Mirroring DYNAMIC HTTP Handler for DELETE /books/{id} */
-func (s *bookService) DeleteBook(id int) error {
+func (s *bookService) DeleteBook(ctx context.Context, id int) error {
 	/* 1. Call the Repo Method and return any error */
-	return s.Repo.Delete(id)
+	return s.Repo.Delete(ctx, id)
 }
 
 /* GET OwnerID --------------------------------------------------------------------------------------------------*/
 /* Method Encapsulating Utility method for getting ID of book's owner */
-func (s *bookService) GetOwnerID(bookID int) (int, error) {
+func (s *bookService) GetOwnerID(ctx context.Context, bookID int) (int, error) {
 	/* 1. Call the Repo Method and get the owner id + error object returned */
-	ownerID, err := s.Repo.GetOwnerID(bookID)
+	ownerID, err := s.Repo.GetOwnerID(ctx, bookID)
 	/* 2. Error Handling on both owner id and error objects */
 	if err != nil {
 		return 0, err
@@ -163,35 +422,400 @@ func (s *bookService) GetOwnerID(bookID int) (int, error) {
 	return ownerID, nil
 }
 
+/* GET Authors --------------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /books/authors */
+func (s *bookService) ListAuthors(ctx context.Context, prefix string, ownerID int) ([]string, error) {
+	/* 1. Call the Repo Method and return the list of distinct authors from the Database */
+	return s.Repo.FindAuthors(ctx, strings.TrimSpace(prefix), ownerID)
+}
+
+/* BULK UPDATE Pages --------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for PUT /books/pages */
+func (s *bookService) UpdatePages(ctx context.Context, updates []models.PagesUpdate, ownerID int) (models.BulkUpdateResult, error) {
+	/* 1. Check JSON Fields' values are not empty/not acceptable + Error Handling */
+	err := s.validatePagesUpdates(updates)
+	if err != nil {
+		return models.BulkUpdateResult{}, err
+	}
+	/* 2. Call the Repo Method and return the result of the transaction + any error */
+	return s.Repo.UpdatePages(ctx, updates, ownerID)
+}
+
+/* REVIEW Book -------------------------------------------------------------------------------------------------*/
+/* Method Mirroring DYNAMIC HTTP Handler for POST /books/{id}/reviews
+   Returns the stored review + whether it has been created (true) or has replaced a previous one (false) */
+func (s *bookService) ReviewBook(ctx context.Context, review models.Review) (models.Review, bool, error) {
+	/* 1. Check JSON Fields' values are not empty/not acceptable + Error Handling */
+	if err := s.validateReview(review); err != nil {
+		return models.Review{}, false, err
+	}
+	/* 2. Call the Repo Method + Error Handling */
+	stored, inserted, err := s.Repo.UpsertReview(ctx, review)
+	if err != nil {
+		return models.Review{}, false, err
+	}
+	/* 3. Return the review + whether it has been created (inserted rather than replaced by the upsert) */
+	return stored, inserted, nil
+}
+
+/* LIST Reviews -------------------------------------------------------------------------------------------------*/
+/* Method Mirroring DYNAMIC HTTP Handler for GET /books/{id}/reviews - one page of reviews with the pagination meta */
+func (s *bookService) ListReviews(ctx context.Context, bookID, limit, offset int) ([]models.Review, models.Pagination, error) {
+	reviews, total, err := s.Repo.FindReviews(ctx, bookID, limit, offset)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	return reviews, models.Pagination{Total: total, Limit: limit, Offset: offset}, nil
+}
+
+/* ADD / REMOVE Favorite ---------------------------------------------------------------------------------------*/
+/* Methods Mirroring DYNAMIC HTTP Handlers for POST and DELETE /books/{id}/favorite */
+func (s *bookService) AddFavorite(ctx context.Context, userID, bookID int) error {
+	return s.Repo.AddFavorite(ctx, userID, bookID)
+}
+
+func (s *bookService) RemoveFavorite(ctx context.Context, userID, bookID int) error {
+	return s.Repo.RemoveFavorite(ctx, userID, bookID)
+}
+
+/* LIST Favorites -----------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /me/favorites */
+func (s *bookService) ListFavorites(ctx context.Context, userID int) ([]models.Book, error) {
+	return s.Repo.FindFavorites(ctx, userID)
+}
+
+/* LIST Changed Since -------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /books/changes - the books created or updated at or after since, with
+   the synced_at to pass as the since of the next call. synced_at comes from the Database clock, goes back to the
+   oldest transaction still running (see FindChangedSince) and is rounded down to the second (the precision of the
+   API timestamps): recent changes are sent again next time rather than missed, hence the clients must treat the
+   books as upserts keyed by ID. */
+func (s *bookService) ListChangedSince(ctx context.Context, since time.Time) ([]models.Book, models.SyncMeta, error) {
+	books, syncedAt, err := s.Repo.FindChangedSince(ctx, since)
+	if err != nil {
+		return nil, models.SyncMeta{}, err
+	}
+	syncedAt = syncedAt.UTC().Truncate(time.Second)
+	return books, models.SyncMeta{Since: models.NewAPITime(since), SyncedAt: models.NewAPITime(syncedAt)}, nil
+}
+
+/* LIST Popular -------------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /books/popular - one page of the most favorited books with the meta */
+func (s *bookService) ListPopular(ctx context.Context, limit, offset int) ([]models.PopularBook, models.Pagination, error) {
+	books, total, err := s.Repo.FindPopular(ctx, limit, offset)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+	return books, models.Pagination{Total: total, Limit: limit, Offset: offset}, nil
+}
+
+/* COMPARE Books ------------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /books/compare - ErrBookNotFound if either book is missing */
+func (s *bookService) CompareBooks(ctx context.Context, aID, bID int) (*models.BookComparison, error) {
+	/* 1. Read both books + Error Handling */
+	a, err := s.Repo.FindByID(ctx, aID)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.Repo.FindByID(ctx, bID)
+	if err != nil {
+		return nil, err
+	}
+	/* 2. Put them side by side with their differences */
+	comparison := compareBooks(*a, *b)
+	return &comparison, nil
+}
+
+/* TOTAL Pages --------------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /me/pages/total (0 when the user has no books) */
+func (s *bookService) TotalPages(ctx context.Context, userID int) (int, error) {
+	return s.Repo.SumPagesByOwner(ctx, userID)
+}
+
+/* CHECK Ownership ----------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for POST /books/ownership: every input ID mapped to whether the user owns
+   the book (false for missing books too) */
+func (s *bookService) CheckOwnership(ctx context.Context, userID int, ids []int) (map[int]bool, error) {
+	/* 1. Check the list of IDs + Error Handling */
+	failures := ValidationError{}
+	if len(ids) == 0 || len(ids) > maxOwnershipIDs {
+		failures["ids"] = fmt.Sprintf("Between 1 and %d book IDs are required", maxOwnershipIDs)
+	}
+	for _, id := range ids {
+		if id <= 0 {
+			failures["ids"] = "Book IDs must be greater than 0"
+		}
+	}
+	if len(failures) > 0 {
+		return nil, failures
+	}
+	/* 2. Get the owned IDs via Repo Method (one query) + Error Handling */
+	owned, err := s.Repo.FindOwnedIDs(ctx, ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	/* 3. Answer for every input ID: not owned unless found */
+	result := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		result[id] = false
+	}
+	for _, id := range owned {
+		result[id] = true
+	}
+	return result, nil
+}
+
+/* BOOK JSON Schema ---------------------------------------------------------------------------------------------*/
+/* Returns the JSON Schema document describing the Book input accepted by POST /books and PUT /books/{id}.
+   IMPORTANT!! Hand-authored: it MUST mirror the rules checked by validateBook(..) right below. */
+func BookSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "Book",
+		"type":                 "object",
+		"required":             []string{"title", "author", "pages"},
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"id":             map[string]interface{}{"type": "integer", "readOnly": true},
+			"title":          map[string]interface{}{"type": "string", "minLength": 1},
+			"author":         map[string]interface{}{"type": "string", "minLength": 1},
+			"pages":          map[string]interface{}{"type": "integer", "minimum": 1},
+			"year":           map[string]interface{}{"type": "integer", "description": "Publication year (negative = BC)"},
+			"average_rating": map[string]interface{}{"type": []string{"number", "null"}, "readOnly": true},
+			"review_count":   map[string]interface{}{"type": "integer", "readOnly": true},
+			"created_at":     map[string]interface{}{"type": "string", "format": "date-time", "readOnly": true},
+			"updated_at":     map[string]interface{}{"type": "string", "format": "date-time", "readOnly": true},
+		},
+	}
+}
+
+/* BOOK JSON Example --------------------------------------------------------------------------------------------*/
+/* Fields of models.Book set by the server, hence left out of the example input */
+var bookReadOnlyFields = map[string]bool{"id": true, "average_rating": true, "review_count": true, "created_at": true,
+	"updated_at": true}
+
+/* Valid POST /books Body built from the `example` tags of models.Book (the Swagger ones), so it can never drift */
+func BookExample() map[string]any {
+	example := map[string]any{}
+	bookType := reflect.TypeOf(models.Book{})
+	for i := 0; i < bookType.NumField(); i++ {
+		/* 1. Get the JSON name of the field, skipping hidden, read-only and example-less fields */
+		field := bookType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		value, ok := field.Tag.Lookup("example")
+		if name == "" || name == "-" || bookReadOnlyFields[name] || !ok {
+			continue
+		}
+		/* 2. Store the example with the JSON type of the field */
+		if field.Type.Kind() == reflect.Int {
+			if n, err := strconv.Atoi(value); err == nil {
+				example[name] = n
+				continue
+			}
+		}
+		example[name] = value
+	}
+	return example
+}
+
+/* Utility Methods sanitizeBook and sanitizeText ---------------------------------------------------------------*/
+/* Strip the HTML tags from the text written by the clients, if enabled (see IMPORTANT NOTES 7.) */
+func (s *bookService) sanitizeText(text string) string {
+	if s.sanitizer == nil {
+		return text
+	}
+	return strings.TrimSpace(s.sanitizer.Sanitize(text))
+}
+
+func (s *bookService) sanitizeBook(book models.Book) models.Book {
+	book.Title = s.sanitizeText(book.Title)
+	book.Author = s.sanitizeText(book.Author)
+	return book
+}
+
 /* Utility Method validateBook ----------------------------------------------------------------------------------*/
 /* Method keeping the checks on the Body JSON Field's values out of the handlers and database code */
 func (s *bookService) validateBook(book models.Book) error {
-	/* If Book objects has empty title/author or negative pages, return an error...*/
+	/* If the Book object fails any check, return the first failure (in the order of bookFields)...*/
+	failures := bookFailures(book)
+	for _, field := range bookFields {
+		if message, failed := failures[field]; failed {
+			return ValidationError{field: message}
+		}
+	}
+	/*...otherwise return null */
+	return nil
+}
+
+/* JSON fields checked by bookFailures, in the order validateBook reports them */
+var bookFields = []string{"title", "author", "pages", "year"}
+
+/* Utility Function bookFailures --------------------------------------------------------------------------------*/
+/* Every failed check on the client-writable fields of a book, keyed by JSON field name. The ONLY place defining the
+   book rules: both the real writes (validateBook) and the dry run (ValidateBook) go through it. */
+func bookFailures(book models.Book) ValidationError {
+	failures := ValidationError{}
 	if book.Title == "" {
-		return errors.New("Title is required")
+		failures["title"] = "Title is required"
 	}
 	if book.Author == "" {
-		return errors.New("Author is required")
+		failures["author"] = "Author is required"
 	}
 	if book.Pages <= 0 {
-		return errors.New("Pages must be greater than 0")
+		failures["pages"] = "Pages must be greater than 0"
+	} else if book.Pages > models.MaxPages {
+		failures["pages"] = fmt.Sprintf("Pages must be at most %d", models.MaxPages)
+	}
+	if book.Year > time.Now().Year() {
+		failures["year"] = "Year cannot be in the future"
+	}
+	return failures
+}
+
+/* Utility Method changedFields --------------------------------------------------------------------------------*/
+/* JSON names of the client-writable fields having different values in the two books (never null) */
+func changedFields(before, after models.Book) []string {
+	changed := []string{}
+	if before.Title != after.Title {
+		changed = append(changed, "title")
+	}
+	if before.Author != after.Author {
+		changed = append(changed, "author")
+	}
+	if before.Pages != after.Pages {
+		changed = append(changed, "pages")
+	}
+	if before.Year != after.Year {
+		changed = append(changed, "year")
+	}
+	return changed
+}
+
+/* Utility Method compareBooks ---------------------------------------------------------------------------------*/
+/* Both books side by side with the differences of b compared to a (no I/O, so that it can be tested on its own) */
+func compareBooks(a, b models.Book) models.BookComparison {
+	diff := models.BookDifferences{
+		PagesDelta:      b.Pages - a.Pages,
+		SameAuthor:      strings.EqualFold(strings.TrimSpace(a.Author), strings.TrimSpace(b.Author)),
+		DifferentFields: changedFields(a, b),
+	}
+	if a.Year != 0 && b.Year != 0 { /* 0 = unknown year */
+		delta := b.Year - a.Year
+		diff.YearDelta = &delta
+	}
+	return models.BookComparison{A: a, B: b, Differences: diff}
+}
+
+/* Utility Method validateReview -------------------------------------------------------------------------------*/
+/* Method keeping the checks on the Body JSON Field's values out of the handlers and database code */
+func (s *bookService) validateReview(review models.Review) error {
+	if review.Rating < 1 || review.Rating > 5 {
+		return ErrInvalidRating
+	}
+	if len(review.Comment) > 2000 {
+		return ErrCommentTooLong
 	}
-	/*...otherwise return null */
 	return nil
 }
 
 /* Utility Method transferRequest ------------------------------------------------------------------------------*/
 /* Method keeping the checks on the Body JSON Field's values out of the handlers and database code */
 func (s *bookService) validateTransferRequest(req models.TransferRequest) error {
-	/* If Book objects has empty title/author or negative pages, return an error...*/
+	/* Run ALL the checks, collecting every failure keyed by JSON field name...*/
+	failures := ValidationError{}
 	if req.FromID <= 0 {
-		return errors.New("Sender Book ID is invalid")
+		failures["from_id"] = "Sender Book ID is invalid"
 	}
 	if req.ToID <= 0 {
-		return errors.New("Receiver Book ID is invalid")
+		failures["to_id"] = "Receiver Book ID is invalid"
+	}
+	if req.Pages <= 0 {
+		failures["pages"] = "Pages must be greater than 0"
+	}
+	if req.Pages > s.MaxTransferPages {
+		failures["pages"] = fmt.Sprintf("Pages must not be greater than %d", s.MaxTransferPages)
+	}
+	/*...and return them together as one error (or null if all checks passed) */
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
+
+/* Utility Method validateTransferBatch -------------------------------------------------------------------------*/
+/* Method keeping the checks on the Body JSON Field's values out of the handlers and database code */
+func (s *bookService) validateTransferBatch(transfers []models.TransferRequest) error {
+	/* Run ALL the checks, collecting every failure keyed by "[index].field" (e.g. "[2].pages")...*/
+	failures := ValidationError{}
+	if len(transfers) == 0 {
+		failures["transfers"] = "At least one transfer is required"
+	}
+	if len(transfers) > maxTransferBatch {
+		failures["transfers"] = fmt.Sprintf("At most %d transfers are allowed", maxTransferBatch)
+	}
+	for i, t := range transfers {
+		var itemFailures ValidationError
+		if !errors.As(s.validateTransferRequest(t), &itemFailures) {
+			continue
+		}
+		for field, msg := range itemFailures {
+			failures[fmt.Sprintf("[%d].%s", i, field)] = msg
+		}
+	}
+	/*...and return them together as one error (or null if all checks passed) */
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
+
+/* Utility Method validateBookQuery ----------------------------------------------------------------------------*/
+/* Method keeping the checks on the Body JSON Field's values out of the handlers and database code */
+func (s *bookService) validateBookQuery(q models.BookQuery) error {
+	/* Run ALL the checks, collecting every failure keyed by JSON field name...*/
+	failures := ValidationError{}
+	checkRange := func(minField, maxField string, min, max *int, lower, upper int) {
+		for field, value := range map[string]*int{minField: min, maxField: max} {
+			if value != nil && (*value < lower || *value > upper) {
+				failures[field] = fmt.Sprintf("Must be between %d and %d", lower, upper)
+			}
+		}
+		if min != nil && max != nil && *min > *max {
+			failures[minField] = "Must not be greater than " + maxField
+		}
+	}
+	checkRange("pages_min", "pages_max", q.PagesMin, q.PagesMax, 0, models.MaxPages)
+	checkRange("year_min", "year_max", q.YearMin, q.YearMax, -9999, 9999)
+	if !repositories.IsBookQuerySortable(q.Sort) {
+		failures["sort"] = "Must be one of id, title, author, pages, year, created_at (\"-\" prefix = descending)"
+	}
+	if q.Limit < 0 || q.Limit > 100 {
+		failures["limit"] = "Must be between 1 and 100"
+	}
+	if q.Offset < 0 {
+		failures["offset"] = "Must not be negative"
+	}
+	/*...and return them together as one error (or null if all checks passed) */
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
+
+/* Utility Method validatePagesUpdates ------------------------------------------------------------------------*/
+/* Method keeping the checks on the Body JSON Field's values out of the handlers and database code */
+func (s *bookService) validatePagesUpdates(updates []models.PagesUpdate) error {
+	/* If the list is empty or any item has an invalid id/pages value, return an error...*/
+	if len(updates) == 0 {
+		return errors.New("At least one update is required")
 	}
-	if req.Pages < 0 {
-		return errors.New("Pages must be greater or equal to 0")
+	for _, u := range updates {
+		if u.ID <= 0 {
+			return errors.New("Book ID is invalid")
+		}
+		if u.Pages <= 0 {
+			return errors.New("Pages must be greater than 0")
+		}
 	}
 	/*...otherwise return null */
 	return nil
@@ -0,0 +1,226 @@
+package services
+
+// services/ PACKAGE TESTS ****************************************************************************************
+/* Tests of the business rules of the service layer that don't need the HTTP layer: the repository is replaced by
+   a stub implementing only the methods under test (calling any other one panics). */
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"bookapi/internal/models"
+	"bookapi/internal/repositories"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 2. STUB REPOSITORY *********************************************************************************************
+
+/* Embeds the interface so that only the methods under test need to be written */
+type stubBookRepository struct {
+	repositories.BookRepository
+	transfers int           /* Number of TransferPages calls that reached the "Database" */
+	finds     atomic.Int32  /* Number of FindByID calls that reached the "Database" */
+	release   chan struct{} /* FindByID answers once it gets closed */
+}
+
+func (r *stubBookRepository) Create(ctx context.Context, book models.Book) (models.Book, error) {
+	book.ID = 1
+	return book, nil
+}
+
+func (r *stubBookRepository) FindByID(ctx context.Context, id int) (*models.Book, error) {
+	r.finds.Add(1)
+	select {
+	case <-r.release:
+		return &models.Book{ID: id, Title: "Dune", Author: "Frank Herbert", Pages: 412}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *stubBookRepository) TransferPages(ctx context.Context, req models.TransferRequest) error {
+	r.transfers++
+	return nil
+}
+
+/* Book 1 doesn't exist yet (inserted), any other id gets replaced - with the timestamps of a freshly inserted row */
+func (r *stubBookRepository) Upsert(ctx context.Context, book models.Book) (models.Book, bool, error) {
+	now := models.NewAPITime(time.Now())
+	book.CreatedAt, book.UpdatedAt = now, now
+	return book, book.ID == 1, nil
+}
+
+// 3. TESTS *******************************************************************************************************
+
+/* TESTER for the computed differences of GET /books/compare ---------------------------------------------------*/
+func TestCompareBooks(t *testing.T) {
+	a := models.Book{ID: 1, Title: "De Officiis", Author: "Marcus Tullius Cicero", Pages: 479, Year: 1913}
+	b := models.Book{ID: 2, Title: "De Legibus", Author: " marcus tullius cicero", Pages: 241}
+
+	/* 1. Page delta of b compared to a, author compared ignoring case and spaces, no year delta (b's is unknown) */
+	diff := compareBooks(a, b).Differences
+	if diff.PagesDelta != -238 || !diff.SameAuthor || diff.YearDelta != nil {
+		t.Errorf("Unexpected differences: %+v", diff)
+	}
+	if strings.Join(diff.DifferentFields, ",") != "title,author,pages,year" {
+		t.Errorf("Unexpected different fields: %v", diff.DifferentFields)
+	}
+	/* 2. With both years known, the year delta is set */
+	b.Year = 1928
+	if diff := compareBooks(a, b).Differences; diff.YearDelta == nil || *diff.YearDelta != 15 {
+		t.Errorf("Expected a year delta of 15, got %v", diff.YearDelta)
+	}
+}
+
+/* TESTER for the opt-in HTML sanitization of titles and authors (SANITIZE_INPUT) ------------------------------*/
+func TestCreateBook_SanitizeInput(t *testing.T) {
+	book := models.Book{Title: `<script>alert("x")</script>Dune <b>Messiah</b>`, Author: "Frank Herbert", Pages: 256}
+
+	/* 1. Enabled: the tags get stripped before storing */
+	created, err := NewBookService(&stubBookRepository{}, 0, true).CreateBook(context.Background(), book)
+	if err != nil || created.Title != "Dune Messiah" || created.Author != "Frank Herbert" {
+		t.Errorf("Expected the sanitized title %q, got %q (err=%v)", "Dune Messiah", created.Title, err)
+	}
+	/* 2. A title made only of tags is empty once sanitized, so it's rejected */
+	book.Title = "<img src=x onerror=alert(1)>"
+	if _, err := NewBookService(&stubBookRepository{}, 0, true).CreateBook(context.Background(), book); err == nil {
+		t.Errorf("Expected a title made only of tags to be rejected")
+	}
+	/* 3. Disabled (default): the raw values are stored as they are */
+	created, err = NewBookService(&stubBookRepository{}, 0, false).CreateBook(context.Background(), book)
+	if err != nil || created.Title != book.Title {
+		t.Errorf("Expected the raw title %q, got %q (err=%v)", book.Title, created.Title, err)
+	}
+}
+
+/* TESTER for the upper bound of the pages of one transfer ----------------------------------------------------*/
+func TestTransferPages_MaxPagesBoundary(t *testing.T) {
+	repo := &stubBookRepository{}
+	service := NewBookService(repo, 500, false)
+
+	/* 1. Exactly the max is accepted and reaches the repository */
+	if err := service.TransferPages(context.Background(), models.TransferRequest{FromID: 1, ToID: 2, Pages: 500}); err != nil {
+		t.Fatalf("Expected 500 pages to be accepted, got %v", err)
+	}
+	/* 2. One more is rejected on the pages field, before reaching the repository */
+	err := service.TransferPages(context.Background(), models.TransferRequest{FromID: 1, ToID: 2, Pages: 501})
+	var invalid ValidationError
+	if !errors.As(err, &invalid) || invalid["pages"] == "" {
+		t.Fatalf("Expected a pages validation error for 501 pages, got %v", err)
+	}
+	if repo.transfers != 1 {
+		t.Errorf("Expected 1 transfer to reach the repository, got %d", repo.transfers)
+	}
+	/* 3. No configured max falls back to the max pages of one book */
+	err = NewBookService(repo, 0, false).TransferPages(context.Background(),
+		models.TransferRequest{FromID: 1, ToID: 2, Pages: models.MaxPages + 1})
+	if !errors.As(err, &invalid) {
+		t.Errorf("Expected the default max to be models.MaxPages, got %v", err)
+	}
+}
+
+/* TESTER for the recently viewed books of GET /me/recent -------------------------------------------------------*/
+func TestRecentViews(t *testing.T) {
+	/* 1. Keep the last 3 books: viewing 1, 2, 3, 2, 4 leaves 4, 2, 3 (2 moved to the front once, 1 dropped) */
+	views := NewRecentViews(3)
+	for _, id := range []int{1, 2, 3, 2, 4} {
+		views.Add(7, id)
+	}
+	if got := views.List(7); !slices.Equal(got, []int{4, 2, 3}) {
+		t.Errorf("Expected [4 2 3], got %v", got)
+	}
+	/* 2. Other users have their own list */
+	if got := views.List(8); len(got) != 0 {
+		t.Errorf("Expected an empty list, got %v", got)
+	}
+	/* 3. A limit of 0 disables the tracking without breaking the callers */
+	disabled := NewRecentViews(0)
+	disabled.Add(7, 1)
+	if got := disabled.List(7); len(got) != 0 {
+		t.Errorf("Expected an empty list, got %v", got)
+	}
+}
+
+/* TESTER for the created flag of PUT /books/{id} in Create-or-Replace mode: the Database tells, not the timestamps */
+func TestReplaceBook_Created(t *testing.T) {
+	service := NewBookService(&stubBookRepository{}, 0, false)
+	book := models.Book{Title: "Dune", Author: "Frank Herbert", Pages: 412}
+	for id, expected := range map[int]bool{1: true, 2: false} {
+		_, created, err := service.ReplaceBook(context.Background(), id, book)
+		if err != nil || created != expected {
+			t.Errorf("book %d: Expected created=%v, got %v (err=%v)", id, expected, created, err)
+		}
+	}
+}
+
+/* TESTER for the coalescing of the concurrent GET /books/{id} calls --------------------------------------------*/
+func TestGetBookByID_Coalescing(t *testing.T) {
+	repo := &stubBookRepository{release: make(chan struct{})}
+	service := NewBookService(repo, 0, false)
+
+	/* 1. N concurrent callers for the same id, plus one that goes away while the query is running */
+	const callers = 50
+	var wg sync.WaitGroup
+	books := make([]*models.Book, callers)
+	errs := make([]error, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			books[i], errs[i] = service.GetBookByID(context.Background(), 7)
+		}()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	gone := make(chan error, 1)
+	go func() {
+		_, err := service.GetBookByID(ctx, 7)
+		gone <- err
+	}()
+
+	/* 2. The caller that goes away returns straight away, without failing the others */
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-gone:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled for the caller that went away, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("The caller that went away is still waiting for the shared query")
+	}
+
+	/* 3. One single query served all the callers, each with its own copy of the book */
+	close(repo.release)
+	wg.Wait()
+	if finds := repo.finds.Load(); finds != 1 {
+		t.Errorf("Expected 1 repository query for %d concurrent calls, got %d", callers+1, finds)
+	}
+	for i := range callers {
+		if errs[i] != nil || books[i] == nil || books[i].ID != 7 {
+			t.Fatalf("caller %d: Expected book 7, got %v (err=%v)", i, books[i], errs[i])
+		}
+		if i > 0 && books[i] == books[0] {
+			t.Errorf("caller %d: Expected its own copy of the book, got the shared one", i)
+		}
+	}
+}
+
+/* TESTER for the ids of PUT /books/{id} in Create-or-Replace mode: only 1..MaxUpsertBookID ---------------------*/
+func TestReplaceBook_IDRange(t *testing.T) {
+	service := NewBookService(&stubBookRepository{}, 0, false)
+	book := models.Book{Title: "Dune", Author: "Frank Herbert", Pages: 412}
+	for _, id := range []int{0, -1, models.MaxUpsertBookID + 1, 2147483647} {
+		var invalid ValidationError
+		if _, _, err := service.ReplaceBook(context.Background(), id, book); !errors.As(err, &invalid) {
+			t.Errorf("id %d: Expected a ValidationError, got %v", id, err)
+		}
+	}
+	if _, _, err := service.ReplaceBook(context.Background(), models.MaxUpsertBookID, book); err != nil {
+		t.Errorf("id %d: Expected no error, got %v", models.MaxUpsertBookID, err)
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+// services/ PACKAGE **********************************************************************************************
+/* The services/ package stores all the Business Logic, hence the methods that carry out operations and
+   modifications to data/data structures while being completely decoupled from HTTP Requests and Methods. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Data Portability (GDPR)
+	- GET /me/export hands a user a complete machine-readable copy of their data in one document: profile, owned
+	  books, favorites, reviews and audit trail (the entries of GET /me/activity, all of them). Whenever a new kind of personal data gets stored, add it to models.UserExport.
+   2. Consistency
+	- The parts are read one after the other (not within one transaction): a change made while the export is being
+	  assembled may show up in some parts only.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	/* INTERNAL Packages */
+	"bookapi/internal/models"
+	"bookapi/internal/repositories"
+
+	/* EXTERNAL Packages */
+	"context"
+	"time"
+)
+
+// 2. GO STRUCTS and UTILITY VARIABLES ****************************************************************************
+
+/* STRUCT */
+/* Spans the users, the books and the audit log: it only reads through their repositories */
+type ExportService struct {
+	Users *repositories.UserRepository
+	Books repositories.BookRepository
+	Audit *repositories.AuditRepository
+}
+
+/* STRUCT BUILDER */
+func NewExportService(users *repositories.UserRepository, books repositories.BookRepository,
+	audit *repositories.AuditRepository) *ExportService {
+	return &ExportService{Users: users, Books: books, Audit: audit}
+}
+
+// 3. BUSINESS LOGIC METHODS **************************************************************************************
+
+/* EXPORT User Data ---------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /me/export - every piece of data of the user, or ErrUserNotFound */
+func (s *ExportService) ExportUser(ctx context.Context, userID int) (models.UserExport, error) {
+	/* 1. Read the profile first: a deleted user (with a still valid token) has nothing to export */
+	export := models.UserExport{ExportedAt: models.NewAPITime(time.Now())}
+	profile, err := s.Users.FindByID(ctx, userID)
+	if err != nil {
+		return models.UserExport{}, err
+	}
+	export.Profile = *profile
+	/* 2. Read the books, the favorites, the reviews and the audit trail of the user + Error Handling */
+	if export.Books, err = s.Books.FindByOwner(ctx, userID); err != nil {
+		return models.UserExport{}, err
+	}
+	if export.Favorites, err = s.Books.FindFavorites(ctx, userID); err != nil {
+		return models.UserExport{}, err
+	}
+	if export.Reviews, err = s.Books.FindReviewsByUser(ctx, userID); err != nil {
+		return models.UserExport{}, err
+	}
+	if export.Activity, err = s.Audit.FindByUser(ctx, userID, 0, 0); err != nil {
+		return models.UserExport{}, err
+	}
+	return export, nil
+}
@@ -0,0 +1,71 @@
+package services
+
+// services/ PACKAGE **********************************************************************************************
+/* The services/ package stores all the Business Logic, hence the methods that carry out operations and
+   modifications to data/data structures while being completely decoupled from HTTP Requests and Methods. */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. Recently Viewed Books
+	- Every authenticated GET /books/{id} moves the book to the front of the list of its user, which keeps at most
+	  RECENT_VIEWS_LIMIT books (the oldest ones fall off). GET /me/recent reads it back, latest first.
+   2. In-Memory Lists
+	- The lists live in the memory of this instance (like the daily quota counters): with several instances each
+	  of them only knows the views it served, and a restart empties them. Good enough for a "continue browsing"
+	  list, which is a convenience and not a record.
+   3. Nil RecentViews
+	- A limit of 0 disables the tracking (nil *RecentViews): Add is then a no-op and List always empty.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"slices"
+	"sync"
+)
+
+// 2. GO STRUCTS and UTILITY VARIABLES ****************************************************************************
+
+/* STRUCT */
+/* Last books viewed by every user, latest first (see IMPORTANT NOTES) */
+type RecentViews struct {
+	limit int
+	mu    sync.Mutex
+	views map[int][]int /* User ID -> Book IDs */
+}
+
+/* STRUCT BUILDER */
+/* A limit of 0 or less disables the tracking (nil RecentViews) */
+func NewRecentViews(limit int) *RecentViews {
+	if limit <= 0 {
+		return nil
+	}
+	return &RecentViews{limit: limit, views: make(map[int][]int)}
+}
+
+// 3. BUSINESS LOGIC METHODS **************************************************************************************
+
+/* ADD View -----------------------------------------------------------------------------------------------------*/
+/* Move the book to the front of the user's list (once only), dropping the oldest book beyond the limit */
+func (v *RecentViews) Add(userID, bookID int) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	list := slices.DeleteFunc(v.views[userID], func(id int) bool { return id == bookID })
+	list = slices.Insert(list, 0, bookID)
+	if len(list) > v.limit {
+		list = list[:v.limit]
+	}
+	v.views[userID] = list
+}
+
+/* LIST Views ---------------------------------------------------------------------------------------------------*/
+/* Copy of the IDs of the books viewed by the user, latest first (empty if none) */
+func (v *RecentViews) List(userID int) []int {
+	if v == nil {
+		return []int{}
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return append([]int{}, v.views[userID]...)
+}
@@ -12,6 +12,28 @@ package services
    2. service/ and repository/ methods <-- IMPORTANT!!
    	- IMPORTANT!! The service/ package must contain all methods mirroring each single method that is defined in The
       / package! */
+/* 3. Invite Codes
+- POST /register requires a single-use invite code generated by an admin (POST /admin/invites). The user gets
+  created and the code consumed in the SAME transaction: an invalid or already used code rolls the new user back.
+- The bulk import (POST /admin/users/import) is run by an admin and needs no invite codes.
+   4. Repeated Registrations (double-clicks)
+- A POST /register for an email that has been registered within REGISTER_REPLAY_WINDOW (i.e. whose invite code has
+  been used that recently) WITH THE SAME PASSWORD is treated as a replay of the first one: Register returns the
+  existing user with created = false (200) instead of ErrEmailTaken (409).
+- Any other duplicate (older user, or a different password) still gets ErrEmailTaken, so the check isn't weakened.
+   5. Suspended Accounts
+- An admin can suspend a user (active = false) without deleting them: POST /login answers 403 ErrAccountSuspended
+  and the tokens they already have get rejected by the middleware.RejectSuspended on every protected route.
+- There's no token versioning: the flag is read from the Database on each protected request (one primary key
+  lookup), so that the suspension applies to the tokens issued before it too.
+   6. Impersonation
+- Admins can get a short-lived token to act as a user (POST /admin/users/{id}/impersonate), but never as another
+  admin (no way to borrow somebody else's admin rights) nor as a suspended user (the token would be rejected
+  anyway).
+   7. Email Policy
+- Every new email (registration AND bulk import) must be a plain address (no display name) of at most 254 characters
+  (RFC 5321). On top of that, POST /register only accepts the domains of ALLOWED_EMAIL_DOMAINS when it's set (exact
+  match: "mycompany.com" doesn't allow "eu.mycompany.com"); the admins' bulk import is not restricted. */
 
 // 1. IMPORT PACKAGES *********************************************************************************************
 
@@ -23,49 +45,191 @@ import (
 	"bookapi/internal/security"
 
 	/* EXTERNAL Packages */
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
 	"strings"
+	"time"
 )
 
 // 2. GO STRUCTS and UTILITY VARIABLES ****************************************************************************
 
+/* Errors returned by Register, so that callers can tell them apart */
+var ErrMissingCredentials = errors.New("Email and password are required")
+var ErrEmailTaken = errors.New("Email is already registered")
+var ErrMissingInvite = errors.New("An invite code is required")
+var ErrInvalidInvite = repositories.ErrInvalidInvite
+
+/* Errors of the email policy (see IMPORTANT NOTES 7.) */
+var ErrInvalidEmail = errors.New("Email is not a valid address")
+var ErrEmailTooLong = fmt.Errorf("Email must be at most %d characters", maxEmailLength)
+var ErrEmailDomainNotAllowed = errors.New("Email domain is not allowed to register")
+
+/* Errors of the account suspension (see IMPORTANT NOTES 5.) */
+var ErrUserNotFound = repositories.ErrUserNotFound
+var ErrAccountSuspended = errors.New("account suspended")
+
+/* Error of the impersonation of an admin (see IMPORTANT NOTES 6.) */
+var ErrImpersonateAdmin = errors.New("admins cannot be impersonated")
+
+/* Max invite codes generated by one POST /admin/invites */
+const maxInvites = 100
+
+/* Max length of an email address (RFC 5321) */
+const maxEmailLength = 254
+
+/* Max users updated by one POST /admin/users/roles */
+const maxRoleAssignments = 100
+
+/* Roles that can be assigned to the users */
+var knownRoles = map[string]struct{}{models.RoleUser: {}, models.RoleAdmin: {}}
+
+/* Normalized email (trimmed, lower case) on registration AND login: " User@x.com " and "user@x.com" are one account */
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 /* STRUCT */
 type UserService struct {
-	Repo *repositories.UserRepository
+	Repo         *repositories.UserRepository
+	Pepper       string        /* Application-wide secret appended to the passwords before hashing (PASSWORD_PEPPER) */
+	BcryptCost   int           /* Cost factor of the new password hashes (BCRYPT_COST) */
+	ReplayWindow time.Duration /* How long a repeated registration is a replay (see IMPORTANT NOTES 4.) */
+	/* Domains allowed by POST /register, lower case (empty = any domain, see IMPORTANT NOTES 7.) */
+	AllowedEmailDomains []string
 }
 
 /* STRUCT BUILDER */
-func NewUserService(repo *repositories.UserRepository) *UserService {
-	return &UserService{Repo: repo}
+/* allowedEmailDomains is the comma-separated ALLOWED_EMAIL_DOMAINS (a leading "@" is accepted, e.g. "@x.com") */
+func NewUserService(repo *repositories.UserRepository, pepper string, bcryptCost int,
+	replayWindow time.Duration, allowedEmailDomains string) *UserService {
+	/* Normalize the domains once: lower case, no blanks, no "@", no empty entries */
+	var domains []string
+	for _, d := range strings.Split(allowedEmailDomains, ",") {
+		if d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "@"); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return &UserService{Repo: repo, Pepper: pepper, BcryptCost: bcryptCost, ReplayWindow: replayWindow,
+		AllowedEmailDomains: domains}
+}
+
+/* Check the format and the length of a (normalized) email, see IMPORTANT NOTES 7. */
+func validateEmail(email string) error {
+	if len(email) > maxEmailLength {
+		return ErrEmailTooLong
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+/* Whether the domain of a (valid, normalized) email may register, i.e. any domain when no allowlist is set */
+func (s *UserService) emailDomainAllowed(email string) bool {
+	if len(s.AllowedEmailDomains) == 0 {
+		return true
+	}
+	return slices.Contains(s.AllowedEmailDomains, email[strings.LastIndex(email, "@")+1:])
 }
 
 // 3. BUSINESS LOGIC METHODS **************************************************************************************
 
 /* REGISTER User ------------------------------------------------------------------------------------------------*/
-/* Method Mirroring STATIC HTTP Handler for POST /register */
-func (s *UserService) Register(req models.RegisterRequest) (models.User, error) {
-	/* 1. Extract email and textual password from the input RegisterRequest Go Struct */
-	req.Email = strings.TrimSpace(req.Email)
+/* Method Mirroring STATIC HTTP Handler for POST /register
+   Creates the user and consumes the invite code in ONE transaction (see IMPORTANT NOTES 3.) */
+func (s *UserService) Register(ctx context.Context, req models.RegisterRequest) (models.User, bool, error) {
+	/* 1. The invite code is required */
+	req.InviteCode = strings.TrimSpace(req.InviteCode)
+	if req.InviteCode == "" {
+		return models.User{}, false, ErrMissingInvite
+	}
+	/* 1b. The email must be valid and, when an allowlist is set, of one of its domains (see IMPORTANT NOTES 7.) */
+	if email := NormalizeEmail(req.Email); email != "" {
+		if err := validateEmail(email); err != nil {
+			return models.User{}, false, err
+		}
+		if !s.emailDomainAllowed(email) {
+			return models.User{}, false, ErrEmailDomainNotAllowed
+		}
+	}
+	/* 2. Create the user and consume the code with a transaction-bound copy of the service */
+	var user models.User
+	err := s.Repo.WithinTx(ctx, func(txRepo *repositories.UserRepository) error {
+		txService := &UserService{Repo: txRepo, Pepper: s.Pepper, BcryptCost: s.BcryptCost}
+		created, err := txService.createUser(ctx, req)
+		if err != nil {
+			return err
+		}
+		if err := txRepo.ConsumeInvite(ctx, req.InviteCode, created.ID); err != nil {
+			return err
+		}
+		user = created
+		return nil
+	})
+	/* 3. A taken email might be a replay of a registration that has just succeeded (see IMPORTANT NOTES 4.) */
+	if errors.Is(err, ErrEmailTaken) {
+		replayed, replayErr := s.findReplayed(ctx, req)
+		if replayErr != nil {
+			return models.User{}, false, replayErr
+		}
+		if replayed != nil {
+			return *replayed, false, nil
+		}
+	}
+	/* 4. Return the new user, or no user at all if the transaction has been rolled back */
+	if err != nil {
+		return models.User{}, false, err
+	}
+	return user, true, nil
+}
+
+/* User registered within the replay window with the same password as the input request, nil if none */
+func (s *UserService) findReplayed(ctx context.Context, req models.RegisterRequest) (*models.User, error) {
+	if s.ReplayWindow <= 0 {
+		return nil, nil
+	}
+	user, err := s.Repo.FindRegisteredWithin(ctx, NormalizeEmail(req.Email), s.ReplayWindow)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	if !security.CheckPasswordHash(strings.TrimSpace(req.Password), user.Password, s.Pepper) {
+		return nil, nil
+	}
+	return user, nil
+}
+
+/* Check the credentials, hash the password and store the new user (shared by Register and ImportUsers) */
+func (s *UserService) createUser(ctx context.Context, req models.RegisterRequest) (models.User, error) {
+	/* 1. Extract (normalized) email and textual password from the input RegisterRequest Go Struct */
+	req.Email = NormalizeEmail(req.Email)
 	req.Password = strings.TrimSpace(req.Password)
 
 	/* 2. Check values - if empty return Empty user struct + error object */
 	if req.Email == "" || req.Password == "" {
-		return models.User{}, errors.New("Email and password are required")
+		return models.User{}, ErrMissingCredentials
+	}
+	if err := validateEmail(req.Email); err != nil {
+		return models.User{}, err
 	}
 	/* 3. Get User matching email from DB Table + Error Handling */
-	existing, err := s.Repo.FindByEmail(req.Email)
+	existing, err := s.Repo.FindByEmail(ctx, req.Email)
 	/*...if error occured, return it with null user object */
 	if err != nil {
 		return models.User{}, err
 	}
 	/*...if mathing User exists, return error warning the client that email is already registered */
 	if existing != nil {
-		return models.User{}, errors.New("Email is already registered")
+		return models.User{}, ErrEmailTaken
 	}
 	/*...in case the input email doesn't exist in the DB Table yet...*/
 
 	/* 4. Generate Hash from Password + Error Handling */
-	hashed, err := security.HashPassword(req.Password)
+	hashed, err := security.HashPassword(req.Password, s.Pepper, s.BcryptCost)
 	if err != nil {
 		return models.User{}, errors.New("Could not hash password")
 	}
@@ -77,14 +241,101 @@ func (s *UserService) Register(req models.RegisterRequest) (models.User, error)
 	}
 
 	/* 6. Add the built user to the DB Table */
-	return s.Repo.Create(user)
+	return s.Repo.Create(ctx, user)
+}
+
+/* IMPORT Users -------------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for POST /admin/users/import
+   Registers every input user (with no invite code) inside ONE transaction. Duplicates (already registered or repeated
+   in the batch) and invalid rows are skipped and reported; any other error aborts the whole batch. */
+func (s *UserService) ImportUsers(ctx context.Context, reqs []models.RegisterRequest) ([]models.UserImportResult, error) {
+	/* 1. Create the list of per-row results (encoded as [] and not null) */
+	results := make([]models.UserImportResult, 0, len(reqs))
+	/* 2. Run all the registrations with a transaction-bound copy of the service */
+	err := s.Repo.WithinTx(ctx, func(txRepo *repositories.UserRepository) error {
+		txService := &UserService{Repo: txRepo, Pepper: s.Pepper, BcryptCost: s.BcryptCost}
+		for i, req := range reqs {
+			result := models.UserImportResult{Row: i + 1, Email: NormalizeEmail(req.Email)}
+			user, err := txService.createUser(ctx, req)
+			switch {
+			case err == nil:
+				result.Status, result.ID = models.ImportCreated, user.ID
+			case errors.Is(err, ErrEmailTaken):
+				result.Status, result.Reason = models.ImportSkipped, err.Error()
+			case errors.Is(err, ErrMissingCredentials), errors.Is(err, ErrInvalidEmail), errors.Is(err, ErrEmailTooLong):
+				result.Status, result.Reason = models.ImportFailed, err.Error()
+			default:
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	/* 3. Return the per-row results, or no results at all if the transaction has been rolled back */
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+/* ASSIGN ROLE ------------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for POST /admin/users/roles - returns the number of updated users */
+func (s *UserService) AssignRole(ctx context.Context, ids []int, role string) (int, error) {
+	/* 1. Validate the role and the list of users */
+	invalid := ValidationError{}
+	if _, ok := knownRoles[role]; !ok {
+		invalid["role"] = "must be one of: " + models.RoleUser + ", " + models.RoleAdmin
+	}
+	if len(ids) == 0 || len(ids) > maxRoleAssignments {
+		invalid["ids"] = fmt.Sprintf("must contain between 1 and %d user IDs", maxRoleAssignments)
+	}
+	for _, id := range ids {
+		if id < 1 {
+			invalid["ids"] = "must contain positive user IDs only"
+			break
+		}
+	}
+	if len(invalid) > 0 {
+		return 0, invalid
+	}
+	/* 2. Update all the users at once */
+	return s.Repo.UpdateRoles(ctx, ids, role)
+}
+
+/* CREATE INVITES ---------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for POST /admin/invites */
+func (s *UserService) CreateInvites(ctx context.Context, createdBy, count int) ([]models.Invite, error) {
+	/* 1. Validate the number of codes */
+	if count < 1 || count > maxInvites {
+		return nil, ValidationError{"count": fmt.Sprintf("must be between 1 and %d", maxInvites)}
+	}
+	/* 2. Generate random, hard to guess codes + Error Handling */
+	codes := make([]string, count)
+	for i := range codes {
+		code, err := newInviteCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+	/* 3. Store them in the Database */
+	return s.Repo.CreateInvites(ctx, codes, createdBy)
+}
+
+/* 128 random bits, hex encoded */
+func newInviteCode() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
 /* FIND USER BY EMAIL -----------------------------------------------------------------------------------------*/
 /* Method Mirroring STATIC HTTP Handler for GET /register */
-func (s *UserService) FindByEmail(email string) (*models.User, error) {
+func (s *UserService) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	/* 1. Call the Repo Method and get the user item + error object returned */
-	user, err := s.Repo.FindByEmail(email)
+	user, err := s.Repo.FindByEmail(ctx, email)
 	/* 2. Error Handling on both user and err obejcts */
 	if err != nil {
 		return nil, err
@@ -97,9 +348,82 @@ func (s *UserService) FindByEmail(email string) (*models.User, error) {
 
 }
 
+/* UPGRADE PASSWORD HASH -------------------------------------------------------------------------------------*/
+/* Called by POST /login after a SUCCESSFUL authentication: re-hashes the password at the configured cost and
+   stores it if the current hash has a lower one (see security/bcrypt.go, IMPORTANT NOTES 3.) */
+func (s *UserService) UpgradePasswordHash(ctx context.Context, user *models.User, password string) error {
+	/* 1. Re-hash only if the stored hash is weaker than required + Error Handling */
+	hash, upgraded, err := security.RehashIfWeaker(password, user.Password, s.Pepper, s.BcryptCost)
+	if err != nil || !upgraded {
+		return err
+	}
+	/* 2. Store the new hash */
+	if err := s.Repo.UpdatePassword(ctx, user.ID, hash); err != nil {
+		return err
+	}
+	user.Password = hash
+	return nil
+}
+
+/* PERMISSIONS of a User ---------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /me/permissions
+   Capabilities of the input role, derived from the same role lists the routes are restricted with (no DB call) */
+func (s *UserService) Permissions(userID int, role string) models.Permissions {
+	return models.Permissions{
+		UserID:         userID,
+		Role:           role,
+		CanTransfer:    slices.Contains(models.TransferRoles, role),
+		CanDeleteBooks: slices.Contains(models.DeleteBookRoles, role),
+		CanAdmin:       slices.Contains(models.AdminRoles, role),
+	}
+}
+
+/* SUSPEND/UNSUSPEND User --------------------------------------------------------------------------------------*/
+/* Method Mirroring DYNAMIC HTTP Handlers for POST /admin/users/{id}/suspend and /unsuspend */
+func (s *UserService) SetActive(ctx context.Context, id int, active bool) error {
+	return s.Repo.SetActive(ctx, id, active)
+}
+
+/* Whether the user is NOT suspended (a user that doesn't exist anymore isn't active either) */
+func (s *UserService) IsActive(ctx context.Context, id int) (bool, error) {
+	active, err := s.Repo.IsActive(ctx, id)
+	if errors.Is(err, ErrUserNotFound) {
+		return false, nil
+	}
+	return active, err
+}
+
+/* IMPERSONATION Target ---------------------------------------------------------------------------------------*/
+/* Method Mirroring DYNAMIC HTTP Handler for POST /admin/users/{id}/impersonate: the user an admin is about to act
+   as, or ErrUserNotFound, ErrImpersonateAdmin or ErrAccountSuspended (see IMPORTANT NOTES 6.) */
+func (s *UserService) ImpersonationTarget(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.Repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(models.AdminRoles, user.Role) {
+		return nil, ErrImpersonateAdmin
+	}
+	if !user.Active {
+		return nil, ErrAccountSuspended
+	}
+	return user, nil
+}
+
 /* FIND ALL USERS --------------------------------------------------------------------------------------------*/
 /* Method Mirroring STATIC HTTP Handler for GET /admin/users */
-func (s *UserService) FindAll() ([]models.User, error) {
+func (s *UserService) FindAll(ctx context.Context) ([]models.User, error) {
 	/* 1. Call the Repo Method and return the list of users from the Database */
-	return s.Repo.FindAll()
+	return s.Repo.FindAll(ctx)
+}
+
+/* BOOKS PER USER -------------------------------------------------------------------------------------------*/
+/* Method Mirroring STATIC HTTP Handler for GET /admin/stats/books-per-user */
+func (s *UserService) BooksPerUser(ctx context.Context, limit int) ([]models.UserBookCount, error) {
+	/* 1. Reject negative limits (0 means no limit) */
+	if limit < 0 {
+		return nil, errors.New("limit must be a non-negative integer")
+	}
+	/* 2. Call the Repo Method and return the counts from the Database */
+	return s.Repo.BooksPerUser(ctx, limit)
 }
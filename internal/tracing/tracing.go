@@ -0,0 +1,58 @@
+package tracing
+
+// tracing/ PACKAGE ***********************************************************************************************
+/* The tracing/ package sets up the OpenTelemetry DISTRIBUTED TRACING of the application: the spans created by the
+   Tracing middleware (one per HTTP Request) and by the instrumented DB driver (one per SQL call) get exported via
+   OTLP/HTTP to the collector set by OTEL_EXPORTER_OTLP_ENDPOINT (e.g. http://localhost:4318). */
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. No-Op by Default
+	- Without OTEL_EXPORTER_OTLP_ENDPOINT nothing gets exported: the global TracerProvider stays the OpenTelemetry
+	  default one, whose spans are no-ops (no allocation, no export), so the instrumentation costs ~nothing.
+   2. Context Propagation
+	- The W3C Trace Context propagator is ALWAYS installed: the traceparent header of the incoming requests gets
+	  honoured (i.e. our spans become children of the caller's span) even when this service doesn't export.
+   3. Endpoint
+	- Like the OpenTelemetry SDKs do for OTEL_EXPORTER_OTLP_ENDPOINT, the traces get sent to <endpoint>/v1/traces.
+*/
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// 2. SETUP *******************************************************************************************************
+
+/* Name of this service in the exported traces */
+const ServiceName = "bookapi"
+
+/* Install the propagator (+ the exporter if endpoint is set) + Error Handling: returns its shutdown (no-op if unset) */
+func Setup(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	/* 1. Always honour the incoming traceparent/baggage headers (see IMPORTANT NOTES 2.) */
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	/* 2. No endpoint: keep the default no-op TracerProvider (see IMPORTANT NOTES 1.) */
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	/* 3. Create the OTLP/HTTP exporter (see IMPORTANT NOTES 3.) */
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"))
+	if err != nil {
+		return nil, fmt.Errorf("Could not create the OTLP trace exporter: %w", err)
+	}
+	/* 4. Export the spans in batches (never blocking the requests) tagged with the service name */
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
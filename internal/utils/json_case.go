@@ -0,0 +1,132 @@
+package utils
+
+/* IMPORTANT NOTES ----------------------------------------------------------------------------------------------*/
+/* 1. camelCase Responses
+	- The struct tags are (and stay) snake_case. Clients preferring camelCase keys (e.g. TypeScript frontends) get
+	  them by sending "X-JSON-Case: camel", or by default if JSON_CASE=camel ("X-JSON-Case: snake" switches back).
+	  The JSONCase middleware wraps the http.ResponseWriter and WriteJSON converts the keys when it finds the
+	  wrapper, exactly like ProblemWriter does for the error helpers.
+	- EVERY object key gets converted, including the keys of maps (e.g. GET /admin/config). Only the Responses
+	  change: the Bodies of the HTTP Requests are still decoded with the snake_case tags.
+   2. Key Order
+	- The JSON gets rewritten token by token (not decoded into maps), so that the keys keep the order of the
+	  struct fields.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 1. RESPONSE WRITER WRAPPER *************************************************************************************
+
+/* Supported key cases (JSON_CASE config value and X-JSON-Case header) */
+const (
+	JSONCaseSnake = "snake"
+	JSONCaseCamel = "camel"
+)
+
+/* Response Writer Wrapper - Go Struct */
+/* Set by the JSONCase middleware when the response keys have to be camelCase (see IMPORTANT NOTES 1.) */
+type CamelCaseWriter struct {
+	http.ResponseWriter
+}
+
+/* Give access to the wrapped http.ResponseWriter */
+func (w *CamelCaseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+/* Look for a CamelCaseWriter through the chain of wrapped http.ResponseWriters */
+func camelCaseRequested(w http.ResponseWriter) bool {
+	for w != nil {
+		if _, ok := w.(*CamelCaseWriter); ok {
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+	return false
+}
+
+// 2. KEY CONVERSION **********************************************************************************************
+
+/* "from_id" -> "fromId" (keys without underscores are returned as they are) */
+func SnakeToCamel(key string) string {
+	parts := strings.Split(key, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+/* Rewrite a JSON document with camelCase object keys, leaving the values and the key order untouched */
+func camelizeJSON(raw []byte) ([]byte, error) {
+	/* 1. Read the document token by token (numbers kept as they are written) */
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var out bytes.Buffer
+	/* Open objects/arrays: tokens counts the tokens already written in each of them (in objects, even = key) */
+	type container struct {
+		object bool
+		tokens int
+	}
+	var stack []*container
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		/* 2. Closing delimiters end the innermost container */
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			out.WriteByte(byte(delim))
+			continue
+		}
+		/* 3. Write the separator before the token, telling keys from values */
+		isKey := false
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			if parent.object && parent.tokens%2 == 1 {
+				out.WriteByte(':')
+			} else if parent.tokens > 0 {
+				out.WriteByte(',')
+			}
+			isKey = parent.object && parent.tokens%2 == 0
+			parent.tokens++
+		}
+		/* 4. Write the token, converting the keys */
+		switch value := token.(type) {
+		case json.Delim:
+			out.WriteByte(byte(value))
+			stack = append(stack, &container{object: value == '{'})
+		case string:
+			if isKey {
+				value = SnakeToCamel(value)
+			}
+			encoded, _ := json.Marshal(value)
+			out.Write(encoded)
+		case json.Number:
+			out.WriteString(value.String())
+		case bool:
+			out.WriteString(strconv.FormatBool(value))
+		case nil:
+			out.WriteString("null")
+		}
+	}
+	/* 5. Same trailing newline as json.Encoder */
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
@@ -4,11 +4,68 @@ import (
 	/* INTERNAL Packages */
 	"bookapi/internal/models"
 	/* EXTERNAL Packages */
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
-// 1. RESPONSE HELPER FUNCTIONS  **********************************************************************************
+// 1. PROBLEM DETAILS (RFC 7807) **********************************************************************************
+
+/* Response Writer Wrapper - Go Struct */
+/* Set by the ProblemJSON middleware when the client sends "Accept: application/problem+json": the error helpers
+   below find it (also through other wrappers exposing Unwrap()) and switch to the problem+json format. */
+type ProblemWriter struct {
+	http.ResponseWriter
+	Instance string /* Path of the HTTP Request, used as "instance" member */
+}
+
+/* Give access to the wrapped http.ResponseWriter */
+func (w *ProblemWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+/* Look for a ProblemWriter through the chain of wrapped http.ResponseWriters */
+func problemInstance(w http.ResponseWriter) (string, bool) {
+	for w != nil {
+		if pw, ok := w.(*ProblemWriter); ok {
+			return pw.Instance, true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return "", false
+		}
+		w = u.Unwrap()
+	}
+	return "", false
+}
+
+/* Problem Details Response -------------------------------------------------------------------------------------*/
+
+func WriteProblem(w http.ResponseWriter, statusCode int, detail string, instance string) {
+	writeProblem(w, models.ProblemDetails{Detail: detail, Instance: instance}, statusCode)
+}
+
+/* Fill in the members depending on the status code and send the Problem Details Response */
+func writeProblem(w http.ResponseWriter, response models.ProblemDetails, statusCode int) {
+	/* 1. Complete the Go Struct instance to be turned into JSON */
+	response.Type = "about:blank"
+	response.Title = http.StatusText(statusCode)
+	response.Status = statusCode
+	/* 2. Set up the Content-Type of the Body of the HTTP Response */
+	w.Header().Set("Content-Type", "application/problem+json")
+	/* 3. Set the HTTP Status Code of the HTTP Response. */
+	w.WriteHeader(statusCode)
+	/* 4. Convert the Go Struct into JSON, write it to the Body of the HTTP Response and send it to the Client */
+	json.NewEncoder(w).Encode(response)
+}
+
+// 2. RESPONSE HELPER FUNCTIONS  **********************************************************************************
 
 /* Success Response ---------------------------------------------------------------------------------------------*/
 
@@ -20,15 +77,30 @@ func WriteJSON(w http.ResponseWriter, statusCode int, data interface{}, meta int
 	}
 	/* 2. Set the Content-Type of the Body of the HTTP Response. */
 	w.Header().Set("Content-Type", "application/json")
-	/* 3. Set the Status Code of the HTTP Response. */
+	/* 3. camelCase keys requested (see json_case.go): convert the JSON before sending it */
+	if camelCaseRequested(w) {
+		var buf bytes.Buffer
+		json.NewEncoder(&buf).Encode(response)
+		if camelized, err := camelizeJSON(buf.Bytes()); err == nil {
+			w.WriteHeader(statusCode)
+			w.Write(camelized)
+			return
+		}
+	}
+	/* 4. Set the Status Code of the HTTP Response. */
 	w.WriteHeader(statusCode)
-	/* 4. Convert the Go Struct into JSON, write it to the Body of the HTTP Response and send it to the Client */
+	/* 5. Convert the Go Struct into JSON, write it to the Body of the HTTP Response and send it to the Client */
 	json.NewEncoder(w).Encode(response)
 }
 
 /* Error Response -----------------------------------------------------------------------------------------------*/
 
 func WriteError(w http.ResponseWriter, statusCode int, err error, message string) {
+	/* 0. Switch to the problem+json format if requested by the client */
+	if instance, ok := problemInstance(w); ok {
+		WriteProblem(w, statusCode, message, instance)
+		return
+	}
 	/* 1. Build up the Go Struct instance to be turned into JSON */
 	response := models.ErrorResponse{
 		Error:   err.Error(),
@@ -42,9 +114,36 @@ func WriteError(w http.ResponseWriter, statusCode int, err error, message string
 	json.NewEncoder(w).Encode(response)
 }
 
+/* Validation Error Response ------------------------------------------------------------------------------------*/
+/* Error Safe Response also listing every failed check, keyed by JSON field name */
+func WriteValidationError(w http.ResponseWriter, statusCode int, message string, fields map[string]string) {
+	/* 0. Switch to the problem+json format if requested by the client (fields as extension member) */
+	if instance, ok := problemInstance(w); ok {
+		writeProblem(w, models.ProblemDetails{Detail: message, Instance: instance, Fields: fields}, statusCode)
+		return
+	}
+	/* 1. Build up the Go Struct that gets turned into JSON */
+	response := models.ErrorResponse{
+		Error:   http.StatusText(statusCode),
+		Message: message,
+		Fields:  fields,
+	}
+	/* 2. Set the Content-Type of the Body of the HTTP Response */
+	w.Header().Set("Content-Type", "application/json")
+	/* 3. Set the HTTP Status Code of the HTTP Response */
+	w.WriteHeader(statusCode)
+	/* 4. Convert the Go Struct into JSON, write it to the Body of the HTTP Response and send it to the Client */
+	json.NewEncoder(w).Encode(response)
+}
+
 /* Error Safe Response ------------------------------------------------------------------------------------------*/
 
 func WriteSafeError(w http.ResponseWriter, statusCode int, message string) {
+	/* 0. Switch to the problem+json format if requested by the client */
+	if instance, ok := problemInstance(w); ok {
+		WriteProblem(w, statusCode, message, instance)
+		return
+	}
 	/* 1. Build up the Go Struct that gets turned into JSON */
 	response := models.ErrorResponse{
 		Error:   http.StatusText(statusCode),
@@ -57,3 +156,193 @@ func WriteSafeError(w http.ResponseWriter, statusCode int, message string) {
 	/* 4. Convert the Go Struct into JSON, write it to the Body of the HTTP Response and send it to the Client */
 	json.NewEncoder(w).Encode(response)
 }
+
+// 3. PAGINATION HELPERS ******************************************************************************************
+
+/* Page size of the list endpoints when no limit is given, and max page size a client can ask for */
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+/* Name of the HTTP Request header choosing how an empty list gets answered, and its values */
+const (
+	EmptyListHeader    = "X-Empty-List"
+	EmptyListArray     = "array"      /* 200 with "data": [] */
+	EmptyListNoContent = "no-content" /* 204 with no Body */
+)
+
+/* Empty Lists --------------------------------------------------------------------------------------------------*/
+/* Whether an empty list should be answered with 204 No Content: the X-Empty-List header of the request if valid
+   ("no-content" or "array"), the configured default otherwise */
+func EmptyListAsNoContent(r *http.Request, defaultNoContent bool) bool {
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get(EmptyListHeader))) {
+	case EmptyListNoContent:
+		return true
+	case EmptyListArray:
+		return false
+	}
+	return defaultNoContent
+}
+
+/* Query Parameters ---------------------------------------------------------------------------------------------*/
+/* Parse the optional limit (default defaultLimit, between 1 and maxLimit) and offset (default 0) query parameters
+   shared by all the list endpoints, so that they all accept the same values and report the same errors. */
+func ParsePagination(r *http.Request, defaultLimit, maxLimit int) (limit, offset int, err error) {
+	/* 1. Parse the limit, if any + Error Handling */
+	limit = defaultLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return 0, 0, fmt.Errorf("limit must be an integer between 1 and %d", maxLimit)
+		}
+	}
+	/* 2. Parse the offset, if any + Error Handling */
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		offset, err = strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+/* Link Header (RFC 5988) ---------------------------------------------------------------------------------------*/
+/* Set the Link header of a paginated list response: rel="next" is omitted on the last page and rel="prev" on the
+   first one. The URLs keep all the query parameters of the HTTP Request, only the offset changes. */
+func SetPaginationLinks(w http.ResponseWriter, r *http.Request, page models.Pagination) {
+	/* 1. Build the URL of the page starting at the input offset */
+	pageURL := func(offset int) string {
+		query := r.URL.Query()
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("limit", strconv.Itoa(page.Limit))
+		return r.URL.Path + "?" + query.Encode()
+	}
+	/* 2. Collect the links of the next and previous pages, if any */
+	var links []string
+	if page.Offset+page.Limit < page.Total {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(page.Offset+page.Limit)))
+	}
+	if page.Offset > 0 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(max(page.Offset-page.Limit, 0))))
+	}
+	/* 3. Set the header (only if there's at least one link) */
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// 4. JSON DECODING HELPERS ***************************************************************************************
+
+/* Returned by DecodeJSON when a "pages" field is not an integer between -MaxPages and MaxPages */
+var ErrPagesOutOfRange = fmt.Errorf("pages out of range (must be an integer up to %d)", models.MaxPages)
+
+/* Returned by the reads of a Body that doesn't match its Content-Length (see middleware.ContentLengthCheck) */
+var ErrMalformedBody = errors.New("malformed request body")
+
+/* JSON Body Decoder -------------------------------------------------------------------------------------------*/
+/* Decode the JSON Body into dst (rejecting unknown fields if strict). Every "pages" field (also in nested objects
+   and arrays) is first read as json.Number (UseNumber) and checked explicitly, so that a huge value gives
+   ErrPagesOutOfRange instead of a cryptic "cannot unmarshal number" error or an overflow on 32-bit platforms. */
+func DecodeJSON(body io.Reader, dst any, strict bool) error {
+	/* 1. Read the whole Body, since it gets decoded twice */
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	/* 2. First pass: generic decoding keeping the numbers as text + range check of the page counts */
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var generic any
+	if err := decoder.Decode(&generic); err != nil {
+		return err
+	}
+	if err := checkPages(generic); err != nil {
+		return err
+	}
+	/* 3. Second pass: decoding into the Go Struct */
+	decoder = json.NewDecoder(bytes.NewReader(raw))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(dst)
+}
+
+/* Walk the decoded JSON looking for out of range "pages" fields (negatives are left to the services/ validation) */
+func checkPages(value any) error {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if number, ok := field.(json.Number); ok && key == "pages" {
+				pages, err := number.Int64()
+				if err != nil || pages > models.MaxPages || pages < -models.MaxPages {
+					return ErrPagesOutOfRange
+				}
+			}
+			if err := checkPages(field); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := checkPages(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/*
+Write the 400 Response of a DecodeJSON error: the dedicated messages for out of range pages and malformed bodies,
+otherwise the decoding error together with the input message
+*/
+func WriteDecodeError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, ErrPagesOutOfRange) {
+		WriteSafeError(w, http.StatusBadRequest, ErrPagesOutOfRange.Error())
+		return
+	}
+	if errors.Is(err, ErrMalformedBody) {
+		WriteSafeError(w, http.StatusBadRequest, ErrMalformedBody.Error())
+		return
+	}
+	WriteError(w, http.StatusBadRequest, err, message)
+}
+
+/* JSON Body Shape ---------------------------------------------------------------------------------------------*/
+
+/* Returned by ExpectJSONShape when the Body has the wrong shape */
+var (
+	ErrExpectedArray  = errors.New("expected a JSON array")
+	ErrExpectedObject = errors.New("expected a JSON object")
+)
+
+/* Check that the first non-blank byte of the Body (peeked, not consumed) is '[' if array, '{' otherwise (empty = ok) */
+func ExpectJSONShape(r *http.Request, array bool) error {
+	/* 1. Buffer the Body, so that the peeked bytes are still there for the decoder */
+	reader := bufio.NewReader(r.Body)
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{reader, r.Body}
+	/* 2. Skip the leading whitespace */
+	for {
+		next, err := reader.Peek(1)
+		if err != nil {
+			return nil
+		}
+		switch next[0] {
+		case ' ', '\t', '\r', '\n':
+			reader.Discard(1)
+			continue
+		}
+		/* 3. Compare the first significant byte with the expected shape */
+		if array && next[0] != '[' {
+			return ErrExpectedArray
+		}
+		if !array && next[0] != '{' {
+			return ErrExpectedObject
+		}
+		return nil
+	}
+}
@@ -0,0 +1,42 @@
+package utils
+
+// utils/ PACKAGE TESTS *******************************************************************************************
+
+// 1. IMPORT PACKAGES *********************************************************************************************
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// 2. TESTS *******************************************************************************************************
+
+/* TESTER for the limit/offset query parameters shared by the list endpoints ----------------------------------*/
+func TestParsePagination(t *testing.T) {
+	cases := []struct {
+		query         string
+		limit, offset int
+		err           string
+	}{
+		{"", 20, 0, ""},
+		{"?limit=5&offset=10", 5, 10, ""},
+		{"?limit=50", 50, 0, ""},
+		{"?limit=0", 0, 0, "limit must be an integer between 1 and 50"},
+		{"?limit=51", 0, 0, "limit must be an integer between 1 and 50"},
+		{"?limit=abc", 0, 0, "limit must be an integer between 1 and 50"},
+		{"?offset=-1", 0, 0, "offset must be a non-negative integer"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/books"+c.query, nil)
+		limit, offset, err := ParsePagination(r, 20, 50)
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("%q: Expected error %q, got %v", c.query, c.err, err)
+			}
+			continue
+		}
+		if err != nil || limit != c.limit || offset != c.offset {
+			t.Errorf("%q: Expected %d/%d, got %d/%d (err=%v)", c.query, c.limit, c.offset, limit, offset, err)
+		}
+	}
+}